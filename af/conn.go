@@ -2,6 +2,7 @@ package af
 
 import "C"
 import (
+	"context"
 	"database/sql/driver"
 	"time"
 	"unsafe"
@@ -10,7 +11,11 @@ import (
 	"github.com/taosdata/driver-go/v3/af/insertstmt"
 	"github.com/taosdata/driver-go/v3/af/locker"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/param"
+	schemalessCommon "github.com/taosdata/driver-go/v3/common/schemaless"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 	"github.com/taosdata/driver-go/v3/wrapper/handler"
@@ -19,6 +24,7 @@ import (
 type Connector struct {
 	taos     unsafe.Pointer
 	timezone *time.Location
+	logger   log.Logger
 }
 
 // NewConnector New connector with TDengine connection
@@ -26,7 +32,7 @@ func NewConnector(taos unsafe.Pointer) (*Connector, error) {
 	if taos == nil {
 		return nil, errors.ErrTscInvalidConnection
 	}
-	return &Connector{taos: taos}, nil
+	return &Connector{taos: taos, logger: log.GetLogger()}, nil
 }
 
 // Open New connector with TDengine connection information
@@ -37,13 +43,21 @@ func Open(host, user, pass, db string, port int) (*Connector, error) {
 	if len(pass) == 0 {
 		pass = common.DefaultPassword
 	}
+	logger := log.GetLogger()
 	locker.Lock()
 	tc, err := wrapper.TaosConnect(host, user, pass, db, port)
 	locker.Unlock()
 	if err != nil {
+		logger.Warn("connect failed", "host", host, "error", err)
 		return nil, err
 	}
-	return &Connector{taos: tc}, nil
+	logger.Info("connection opened", "host", host)
+	return &Connector{taos: tc, logger: logger}, nil
+}
+
+// SetLogger sets the logger of the connector, the package-level logger of common/log is used by default.
+func (conn *Connector) SetLogger(logger log.Logger) {
+	conn.logger = log.Or(logger)
 }
 
 func (conn *Connector) SetTimezone(timezone string) error {
@@ -67,6 +81,7 @@ func (conn *Connector) Close() error {
 	wrapper.TaosClose(conn.taos)
 	locker.Unlock()
 	conn.taos = nil
+	conn.logger.Info("connection closed")
 	return nil
 }
 
@@ -227,14 +242,30 @@ func (conn *Connector) processQueryResult(result *handler.AsyncResult, h *handle
 }
 
 func (conn *Connector) taosQuery(sqlStr string, handler *handler.Handler, reqID int64) *handler.AsyncResult {
-	locker.Lock()
+	start := time.Now()
+	ctx, span := trace.Start(context.Background(), trace.SpanQuery, trace.SQL(sqlStr))
 	if reqID == 0 {
-		wrapper.TaosQueryA(conn.taos, sqlStr, handler.Handler)
-	} else {
-		wrapper.TaosQueryAWithReqID(conn.taos, sqlStr, handler.Handler, reqID)
+		reqID = trace.NewReqID(ctx)
 	}
+	span.SetAttributes(trace.ReqID(reqID))
+	conn.logger.Debug("query", "req_id", uint64(reqID))
+	locker.Lock()
+	wrapper.TaosQueryAWithReqID(conn.taos, sqlStr, handler.Handler, reqID)
 	locker.Unlock()
 	r := <-handler.Caller.QueryResult
+	log.Slow(conn.logger, "query", uint64(reqID), start)
+	if code := wrapper.TaosError(r.Res); code != int(errors.SUCCESS) {
+		conn.logger.Debug("query failed", "req_id", uint64(reqID), "code", code)
+		err := errors.NewError(code, wrapper.TaosErrorStr(r.Res))
+		metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), err)
+		trace.End(span, err)
+		return r
+	}
+	if wrapper.TaosIsUpdateQuery(r.Res) {
+		span.SetAttributes(trace.Rows(wrapper.TaosAffectedRows(r.Res)))
+	}
+	metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), nil)
+	trace.End(span, nil)
 	return r
 }
 
@@ -344,44 +375,30 @@ func (conn *Connector) GetTableVGroupID(db, table string) (vgID int, err error)
 
 // InfluxDBInsertLinesWithReqID Insert data using influxdb line format
 func (conn *Connector) InfluxDBInsertLinesWithReqID(lines string, precision string, reqID int64, ttl int, tbNameKey string) error {
-	locker.Lock()
-	_, result := wrapper.TaosSchemalessInsertRawTTLWithReqIDTBNameKey(conn.taos, lines, wrapper.InfluxDBLineProtocol, precision, ttl, reqID, tbNameKey)
-	locker.Unlock()
-	defer func() {
-		locker.Lock()
-		wrapper.TaosFreeResult(result)
-		locker.Unlock()
-	}()
-	code := wrapper.TaosError(result)
-	if code != 0 {
-		errStr := wrapper.TaosErrorStr(result)
-		return errors.NewError(code, errStr)
-	}
-	return nil
+	return conn.schemalessInsert(lines, wrapper.InfluxDBLineProtocol, precision, reqID, ttl, tbNameKey)
 }
 
 // OpenTSDBInsertTelnetLinesWithReqID Insert data using opentsdb telnet format
 func (conn *Connector) OpenTSDBInsertTelnetLinesWithReqID(lines string, reqID int64, ttl int, tbNameKey string) error {
-	locker.Lock()
-	_, result := wrapper.TaosSchemalessInsertRawTTLWithReqIDTBNameKey(conn.taos, lines, wrapper.OpenTSDBTelnetLineProtocol, "", ttl, reqID, tbNameKey)
-	locker.Unlock()
-	defer func() {
-		locker.Lock()
-		wrapper.TaosFreeResult(result)
-		locker.Unlock()
-	}()
-	code := wrapper.TaosError(result)
-	if code != 0 {
-		errStr := wrapper.TaosErrorStr(result)
-		return errors.NewError(code, errStr)
-	}
-	return nil
+	return conn.schemalessInsert(lines, wrapper.OpenTSDBTelnetLineProtocol, "", reqID, ttl, tbNameKey)
 }
 
 // OpenTSDBInsertJsonPayloadWithReqID Insert data using opentsdb json format
 func (conn *Connector) OpenTSDBInsertJsonPayloadWithReqID(payload string, reqID int64, ttl int, tbNameKey string) error {
+	return conn.schemalessInsert(payload, wrapper.OpenTSDBJsonFormatProtocol, "", reqID, ttl, tbNameKey)
+}
+
+func (conn *Connector) schemalessInsert(lines string, protocol int, precision string, reqID int64, ttl int, tbNameKey string) (err error) {
+	ctx, span := trace.Start(context.Background(), trace.SpanSchemalessInsert, trace.Bytes(len(lines)))
+	defer func() {
+		trace.End(span, err)
+	}()
+	if reqID == 0 {
+		reqID = trace.NewReqID(ctx)
+	}
+	span.SetAttributes(trace.ReqID(reqID))
 	locker.Lock()
-	_, result := wrapper.TaosSchemalessInsertRawTTLWithReqIDTBNameKey(conn.taos, payload, wrapper.OpenTSDBJsonFormatProtocol, "", ttl, reqID, tbNameKey)
+	rows, result := wrapper.TaosSchemalessInsertRawTTLWithReqIDTBNameKey(conn.taos, lines, protocol, precision, ttl, reqID, tbNameKey)
 	locker.Unlock()
 	defer func() {
 		locker.Lock()
@@ -393,5 +410,48 @@ func (conn *Connector) OpenTSDBInsertJsonPayloadWithReqID(payload string, reqID
 		errStr := wrapper.TaosErrorStr(result)
 		return errors.NewError(code, errStr)
 	}
+	span.SetAttributes(trace.Rows(int(rows)))
 	return nil
 }
+
+// InsertPoints serializes points with protocol and inserts them, precision is only used by the influxdb line protocol.
+func (conn *Connector) InsertPoints(points []*schemalessCommon.Point, protocol int, precision string, reqID int64, ttl int, tbNameKey string) error {
+	lines, err := schemalessCommon.MarshalPoints(points, protocol, precision)
+	if err != nil {
+		return err
+	}
+	switch protocol {
+	case schemalessCommon.InfluxDBLineProtocol:
+		return conn.InfluxDBInsertLinesWithReqID(lines, precision, reqID, ttl, tbNameKey)
+	case schemalessCommon.OpenTSDBTelnetLineProtocol:
+		return conn.OpenTSDBInsertTelnetLinesWithReqID(lines, reqID, ttl, tbNameKey)
+	default:
+		return conn.OpenTSDBInsertJsonPayloadWithReqID(lines, reqID, ttl, tbNameKey)
+	}
+}
+
+// NewSchemalessAsyncWriter creates an AsyncWriter which batches schemaless lines of protocol
+// and inserts them with InfluxDBInsertLinesWithReqID, OpenTSDBInsertTelnetLinesWithReqID or OpenTSDBInsertJsonPayloadWithReqID.
+// precision is only used by the influxdb line protocol, it is also the precision of the points written with WritePoint.
+func (conn *Connector) NewSchemalessAsyncWriter(protocol int, precision string, ttl int, tbNameKey string, opts ...func(*schemalessCommon.AsyncConfig)) (*schemalessCommon.AsyncWriter, error) {
+	var insert schemalessCommon.InsertFunc
+	switch protocol {
+	case schemalessCommon.InfluxDBLineProtocol:
+		insert = func(lines string, reqID int64) error {
+			return conn.InfluxDBInsertLinesWithReqID(lines, precision, reqID, ttl, tbNameKey)
+		}
+	case schemalessCommon.OpenTSDBTelnetLineProtocol:
+		insert = func(lines string, reqID int64) error {
+			return conn.OpenTSDBInsertTelnetLinesWithReqID(lines, reqID, ttl, tbNameKey)
+		}
+	case schemalessCommon.OpenTSDBJsonFormatProtocol:
+		insert = func(lines string, reqID int64) error {
+			return conn.OpenTSDBInsertJsonPayloadWithReqID(lines, reqID, ttl, tbNameKey)
+		}
+	default:
+		return nil, &errors.TaosError{Code: 0xffff, ErrStr: "unknown schemaless protocol"}
+	}
+	// the precision of the inserts overrides the one of opts
+	opts = append(opts[:len(opts):len(opts)], schemalessCommon.SetPrecision(precision))
+	return schemalessCommon.NewAsyncWriter(insert, protocol, opts...), nil
+}
@@ -1,6 +1,7 @@
 package af
 
 import (
+	"context"
 	"database/sql/driver"
 	"io"
 	"reflect"
@@ -10,7 +11,9 @@ import (
 	"github.com/taosdata/driver-go/v3/af/async"
 	"github.com/taosdata/driver-go/v3/af/locker"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 	"github.com/taosdata/driver-go/v3/wrapper/handler"
@@ -105,11 +108,16 @@ func (rs *rows) FormatTime(ts int64, precision int) driver.Value {
 	return common.TimestampConvertToTimeWithLocation(ts, precision, rs.timezone)
 }
 
-func (rs *rows) taosFetchBlock() error {
+func (rs *rows) taosFetchBlock() (err error) {
+	_, span := trace.Start(context.Background(), trace.SpanFetch)
+	defer func() {
+		trace.End(span, err)
+	}()
 	result := rs.asyncFetchRows()
 	if result.N == 0 {
 		rs.blockSize = 0
 		rs.done = true
+		span.SetAttributes(trace.Rows(0))
 		return nil
 	}
 	if result.N < 0 {
@@ -120,6 +128,9 @@ func (rs *rows) taosFetchBlock() error {
 	rs.blockSize = result.N
 	rs.block = wrapper.TaosGetRawBlock(result.Res)
 	rs.blockOffset = 0
+	bytes := int(parser.RawBlockGetLength(rs.block))
+	span.SetAttributes(trace.Rows(result.N), trace.Bytes(bytes))
+	metrics.GetMetrics().AddFetched(result.N, bytes)
 	return nil
 }
 
@@ -2,6 +2,7 @@ package af
 
 import "C"
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/taosdata/driver-go/v3/af/async"
 	"github.com/taosdata/driver-go/v3/af/locker"
 	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosError "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 )
@@ -82,7 +84,11 @@ func (s *Stmt) SetTableName(tableName string) error {
 	return nil
 }
 
-func (s *Stmt) BindRow(row *param.Param) error {
+func (s *Stmt) BindRow(row *param.Param) (err error) {
+	_, span := trace.Start(context.Background(), trace.SpanStmtBind)
+	defer func() {
+		trace.End(span, err)
+	}()
 	if s.isInsert {
 		if s.paramCount == 0 || s.paramCount != len(row.GetValues()) {
 			paramCount, err := s.NumParams()
@@ -125,7 +131,11 @@ func (s *Stmt) AddBatch() error {
 	return nil
 }
 
-func (s *Stmt) Execute() error {
+func (s *Stmt) Execute() (err error) {
+	_, span := trace.Start(context.Background(), trace.SpanStmtExec)
+	defer func() {
+		trace.End(span, err)
+	}()
 	locker.Lock()
 	code := wrapper.TaosStmtExecute(s.stmt)
 	locker.Unlock()
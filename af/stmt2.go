@@ -1,6 +1,7 @@
 package af
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"github.com/taosdata/driver-go/v3/af/async"
 	"github.com/taosdata/driver-go/v3/af/locker"
 	"github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosError "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 	"github.com/taosdata/driver-go/v3/wrapper/cgo"
@@ -146,16 +148,25 @@ func (s *Stmt2) Bind(params []*stmt.TaosStmt2BindData) error {
 	if s.isInsert == nil {
 		return errors.New("stmt2 is not prepared")
 	}
+	_, span := trace.Start(context.Background(), trace.SpanStmtBind)
 	locker.Lock()
 	defer locker.Unlock()
 	err := wrapper.TaosStmt2BindParam(s.stmt2, *s.isInsert, params, s.fields, -1)
+	trace.End(span, err)
 	return err
 }
 
-func (s *Stmt2) Execute() error {
+func (s *Stmt2) Execute() (err error) {
 	if s.isInsert == nil {
 		return errors.New("stmt2 is not prepared")
 	}
+	_, span := trace.Start(context.Background(), trace.SpanStmtExec)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(trace.Rows(s.affectedRows))
+		}
+		trace.End(span, err)
+	}()
 	locker.Lock()
 	code := wrapper.TaosStmt2Exec(s.stmt2)
 	locker.Unlock()
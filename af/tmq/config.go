@@ -4,6 +4,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/taosdata/driver-go/v3/common/log"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 )
@@ -11,6 +12,8 @@ import (
 type config struct {
 	cConfig  unsafe.Pointer
 	timezone *time.Location
+	groupID  string
+	logger   log.Logger
 }
 
 func newConfig() *config {
@@ -1,6 +1,7 @@
 package tmq
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"time"
@@ -8,16 +9,34 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/parser"
 	"github.com/taosdata/driver-go/v3/common/tmq"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosError "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
+	"github.com/taosdata/driver-go/v3/wrapper/cgo"
 )
 
 type Consumer struct {
-	cConsumer  unsafe.Pointer
-	dataParser *parser.TMQRawDataParser
-	timezone   *time.Location
+	cConsumer       unsafe.Pointer
+	logger          log.Logger
+	dataParser      *parser.TMQRawDataParser
+	timezone        *time.Location
+	groupID         string
+	offsetStore     tmq.OffsetStore
+	offsetStoreMode tmq.OffsetStoreMode
+	// vgroups whose offset has been restored from offsetStore
+	restored           map[vgroupKey]struct{}
+	rebalanceCb        RebalanceCb
+	lastAssignment     []tmq.TopicPartition
+	nextRebalanceCheck time.Time
+}
+
+type vgroupKey struct {
+	topic    string
+	vgroupID int32
 }
 
 // NewConsumer Create new TMQ consumer with TMQ config
@@ -29,19 +48,30 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 	defer confStruct.destroy()
 	cConsumer, err := wrapper.TMQConsumerNew(confStruct.cConfig)
 	if err != nil {
+		confStruct.logger.Warn("create consumer failed", "group_id", confStruct.groupID, "error", err)
 		return nil, err
 	}
 	consumer := &Consumer{
 		cConsumer:  cConsumer,
+		logger:     confStruct.logger,
 		dataParser: parser.NewTMQRawDataParser(),
 		timezone:   confStruct.timezone,
+		groupID:    confStruct.groupID,
 	}
+	consumer.logger.Info("consumer opened", "group_id", confStruct.groupID)
 	return consumer, nil
 }
 
 func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	c := newConfig()
 	confCopy := m.Clone()
+	logger, err := confCopy.GetLogger()
+	if err != nil {
+		c.destroy()
+		return nil, err
+	}
+	c.logger = logger
+	delete(confCopy, tmq.LoggerKey)
 	for k, v := range confCopy {
 		vv, ok := v.(string)
 		if !ok {
@@ -57,6 +87,9 @@ func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 			c.timezone = tz
 			continue
 		}
+		if k == "group.id" {
+			c.groupID = vv
+		}
 		err := c.setConfig(k, vv)
 		if err != nil {
 			c.destroy()
@@ -66,8 +99,12 @@ func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	return c, nil
 }
 
+// RebalanceCb is called with tmq.RevokedPartitions and tmq.AssignedPartitions when the vgroups assigned to the consumer change.
+// The assignment is compared by Poll at most once per second, a returned error is returned by Poll as a tmq.Error.
 type RebalanceCb func(*Consumer, tmq.Event) error
 
+const rebalanceCheckInterval = time.Second
+
 func (c *Consumer) Subscribe(topic string, rebalanceCb RebalanceCb) error {
 	return c.SubscribeTopics([]string{topic}, rebalanceCb)
 }
@@ -85,22 +122,132 @@ func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) err
 	if errCode != 0 {
 		return tmqError(errCode)
 	}
+	c.rebalanceCb = rebalanceCb
+	c.lastAssignment = nil
+	c.nextRebalanceCheck = time.Time{}
+	if c.offsetStore != nil {
+		return c.restoreAssignment()
+	}
+	return nil
+}
+
+// SetOffsetStore saves the offsets committed by Commit and CommitOffsets in store, in addition to the server
+// with tmq.StoreAndCommit or instead of it with tmq.StoreOnly. The offsets of the vgroups assigned to the consumer
+// are restored from store on subscribe and when a vgroup is assigned later.
+// It must be called before Subscribe, and enable.auto.commit should be false with tmq.StoreOnly.
+func (c *Consumer) SetOffsetStore(store tmq.OffsetStore, mode tmq.OffsetStoreMode) {
+	c.offsetStore = store
+	c.offsetStoreMode = mode
+	c.restored = make(map[vgroupKey]struct{})
+}
+
+func (c *Consumer) restoreAssignment() error {
+	partitions, err := c.Assignment()
+	if err != nil {
+		return err
+	}
+	_, err = tmq.RestoreOffsets(c, c.offsetStore, c.groupID, partitions)
+	if err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}] = struct{}{}
+	}
+	return nil
+}
+
+// restoreVgroup restores the offset of a vgroup polled for the first time, it reports whether the consumer
+// has been moved to another offset and the polled message must be dropped.
+func (c *Consumer) restoreVgroup(topic string, vgID int32, offset tmq.Offset) (bool, error) {
+	key := vgroupKey{topic: topic, vgroupID: vgID}
+	if _, exist := c.restored[key]; exist {
+		return false, nil
+	}
+	restored, err := tmq.RestoreOffsets(c, c.offsetStore, c.groupID, []tmq.TopicPartition{{Topic: &topic, Partition: vgID}})
+	if err != nil {
+		return false, err
+	}
+	c.restored[key] = struct{}{}
+	return len(restored) == 1 && restored[0].Offset != offset, nil
+}
+
+// checkRebalance compares the assignment with the last one and calls the rebalance callback with the changes.
+func (c *Consumer) checkRebalance() error {
+	if c.rebalanceCb == nil || time.Now().Before(c.nextRebalanceCheck) {
+		return nil
+	}
+	c.nextRebalanceCheck = time.Now().Add(rebalanceCheckInterval)
+	current, err := c.Assignment()
+	if err != nil {
+		return err
+	}
+	assigned, revoked := tmq.DiffAssignment(c.lastAssignment, current)
+	c.lastAssignment = current
+	if len(revoked) != 0 {
+		if c.offsetStore != nil {
+			for _, partition := range revoked {
+				delete(c.restored, vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition})
+			}
+		}
+		if err = c.rebalanceCb(c, tmq.RevokedPartitions{Partitions: revoked}); err != nil {
+			return err
+		}
+	}
+	if len(assigned) != 0 {
+		if c.offsetStore != nil {
+			var unrestored []tmq.TopicPartition
+			for _, partition := range assigned {
+				if _, exist := c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}]; !exist {
+					unrestored = append(unrestored, partition)
+				}
+			}
+			if _, err = tmq.RestoreOffsets(c, c.offsetStore, c.groupID, unrestored); err != nil {
+				return err
+			}
+			for _, partition := range unrestored {
+				c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}] = struct{}{}
+			}
+		}
+		if err = c.rebalanceCb(c, tmq.AssignedPartitions{Partitions: assigned}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// revokeAssignment calls the rebalance callback with the whole assignment after unsubscribe.
+func (c *Consumer) revokeAssignment() error {
+	revoked := c.lastAssignment
+	c.lastAssignment = nil
+	if c.offsetStore != nil {
+		c.restored = make(map[vgroupKey]struct{})
+	}
+	if c.rebalanceCb == nil || len(revoked) == 0 {
+		return nil
+	}
+	return c.rebalanceCb(c, tmq.RevokedPartitions{Partitions: revoked})
+}
+
 // Unsubscribe TMQ unsubscribe
 func (c *Consumer) Unsubscribe() error {
 	errCode := wrapper.TMQUnsubscribe(c.cConsumer)
 	if errCode != taosError.SUCCESS {
 		return tmqError(errCode)
 	}
-	return nil
+	return c.revokeAssignment()
 }
 
 // Poll consumer poll message with timeout
 func (c *Consumer) Poll(timeoutMs int) tmq.Event {
+	if err := c.checkRebalance(); err != nil {
+		return tmq.NewTMQErrorWithErr(err)
+	}
+	start := time.Now()
+	_, span := trace.Start(context.Background(), trace.SpanTMQPoll)
 	message := wrapper.TMQConsumerPoll(c.cConsumer, int64(timeoutMs))
+	metrics.GetMetrics().ObserveRequest(metrics.KindTMQPoll, time.Since(start), nil)
 	if message == nil {
+		trace.End(span, nil)
 		return nil
 	}
 	defer func() {
@@ -111,6 +258,20 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 	resultType := wrapper.TMQGetResType(message)
 	offset := tmq.Offset(wrapper.TMQGetVgroupOffset(message))
 	vgID := wrapper.TMQGetVgroupID(message)
+	span.SetAttributes(
+		trace.Attribute{Key: trace.AttrTopic, Value: topic},
+		trace.Attribute{Key: trace.AttrVgroupID, Value: vgID},
+	)
+	trace.End(span, nil)
+	if c.offsetStore != nil {
+		moved, err := c.restoreVgroup(topic, vgID, offset)
+		if err != nil {
+			return tmq.NewTMQErrorWithErr(err)
+		}
+		if moved {
+			return nil
+		}
+	}
 	switch resultType {
 	case common.TMQ_RES_DATA:
 		result := &tmq.DataMessage{}
@@ -172,6 +333,31 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 	}
 }
 
+// contextPollTimeout is the timeout of each native poll of PollContext, a native poll can not be interrupted
+// so PollContext returns at most contextPollTimeout after ctx is done.
+const contextPollTimeout = 100 * time.Millisecond
+
+// PollContext polls until a message or an error is received, it returns nil when ctx is done.
+func (c *Consumer) PollContext(ctx context.Context) tmq.Event {
+	for ctx.Err() == nil {
+		timeout := contextPollTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining < time.Millisecond {
+				// a poll of 0 ms waits without limit
+				return nil
+			}
+			if remaining < timeout {
+				timeout = remaining
+			}
+		}
+		if event := c.Poll(int(timeout / time.Millisecond)); event != nil {
+			return event
+		}
+	}
+	return nil
+}
+
 func (c *Consumer) getMeta(message unsafe.Pointer) (*tmq.Meta, error) {
 	var meta tmq.Meta
 	p := wrapper.TMQGetJsonMeta(message)
@@ -194,12 +380,13 @@ func (c *Consumer) getData(message unsafe.Pointer) ([]*tmq.Data, error) {
 		err := taosError.NewError(int(errCode), errStr)
 		return nil, err
 	}
-	_, _, rawPtr := wrapper.ParseRawMeta(raw)
+	length, _, rawPtr := wrapper.ParseRawMeta(raw)
 	blockInfos, err := c.dataParser.Parse(rawPtr)
 	if err != nil {
 		return nil, err
 	}
 	var tmqData []*tmq.Data
+	rows := 0
 	for i := 0; i < len(blockInfos); i++ {
 		var data [][]driver.Value
 		if c.timezone == nil {
@@ -210,11 +397,14 @@ func (c *Consumer) getData(message unsafe.Pointer) ([]*tmq.Data, error) {
 		if err != nil {
 			return nil, err
 		}
+		rows += len(data)
 		tmqData = append(tmqData, &tmq.Data{
 			TableName: blockInfos[i].TableName,
 			Data:      data,
+			Fields:    parser.ParseTMQFields(blockInfos[i]),
 		})
 	}
+	metrics.GetMetrics().AddFetched(rows, int(length))
 	return tmqData, nil
 }
 
@@ -223,9 +413,56 @@ func (c *Consumer) FormatTime(ts int64, precision int) driver.Value {
 }
 
 func (c *Consumer) Commit() ([]tmq.TopicPartition, error) {
+	if c.offsetStore != nil {
+		partitions, err := c.Assignment()
+		if err != nil {
+			return nil, err
+		}
+		positions, err := c.Position(partitions)
+		if err != nil {
+			return nil, err
+		}
+		return c.CommitOffsets(positions)
+	}
+	start := time.Now()
+	_, span := trace.Start(context.Background(), trace.SpanTMQCommit)
 	errCode := wrapper.TMQCommitSync(c.cConsumer, nil)
 	if errCode != taosError.SUCCESS {
-		return nil, tmqError(errCode)
+		c.logger.Warn("commit failed", "group_id", c.groupID, "code", errCode)
+		err := tmqError(errCode)
+		metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), err)
+		trace.End(span, err)
+		return nil, err
+	}
+	metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), nil)
+	trace.End(span, nil)
+	log.Slow(c.logger, "commit", 0, start)
+	partitions, err := c.Assignment()
+	if err != nil {
+		return nil, err
+	}
+	return c.Committed(partitions, 0)
+}
+
+// CommitContext is Commit that returns ctx.Err() when ctx is done before the commit completes,
+// the commit in progress is still completed by the client library.
+func (c *Consumer) CommitContext(ctx context.Context) ([]tmq.TopicPartition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.offsetStore != nil {
+		partitions, err := c.Assignment()
+		if err != nil {
+			return nil, err
+		}
+		positions, err := c.Position(partitions)
+		if err != nil {
+			return nil, err
+		}
+		return c.commitOffsets(ctx, positions)
+	}
+	if err := c.commitAsync(ctx); err != nil {
+		return nil, err
 	}
 	partitions, err := c.Assignment()
 	if err != nil {
@@ -234,6 +471,37 @@ func (c *Consumer) Commit() ([]tmq.TopicPartition, error) {
 	return c.Committed(partitions, 0)
 }
 
+func (c *Consumer) commitAsync(ctx context.Context) (err error) {
+	start := time.Now()
+	_, span := trace.Start(ctx, trace.SpanTMQCommit)
+	defer func() {
+		metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), err)
+		trace.End(span, err)
+	}()
+	result := make(chan *wrapper.TMQCommitCallbackResult, 1)
+	h := cgo.NewHandle(result)
+	wrapper.TMQCommitAsync(c.cConsumer, nil, h)
+	return awaitCommit(ctx, result, h)
+}
+
+// awaitCommit waits for the callback of an async commit, it returns ctx.Err() when ctx is done first.
+func awaitCommit(ctx context.Context, result chan *wrapper.TMQCommitCallbackResult, h cgo.Handle) error {
+	select {
+	case r := <-result:
+		h.Delete()
+		err := r.GetError()
+		wrapper.PutTMQCommitCallbackResult(r)
+		return err
+	case <-ctx.Done():
+		// the handle must be kept until the callback is called
+		go func() {
+			wrapper.PutTMQCommitCallbackResult(<-result)
+			h.Delete()
+		}()
+		return ctx.Err()
+	}
+}
+
 func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
 	errCode, list := wrapper.TMQSubscription(c.cConsumer)
 	if errCode != taosError.SUCCESS {
@@ -267,7 +535,18 @@ func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) erro
 	return nil
 }
 
+// SeekContext is Seek that returns ctx.Err() without seeking when ctx is done, the native seek is not interruptible.
+func (c *Consumer) SeekContext(ctx context.Context, partition tmq.TopicPartition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Seek(partition, 0)
+}
+
 func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (offsets []tmq.TopicPartition, err error) {
+	if c.offsetStore != nil && c.offsetStoreMode == tmq.StoreOnly {
+		return c.offsetStore.Load(c.groupID, partitions)
+	}
 	offsets = make([]tmq.TopicPartition, len(partitions))
 	for i := 0; i < len(partitions); i++ {
 		cOffset := wrapper.TMQCommitted(c.cConsumer, *partitions[i].Topic, partitions[i].Partition)
@@ -285,12 +564,38 @@ func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (of
 }
 
 func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicPartition, error) {
+	return c.commitOffsets(context.Background(), offsets)
+}
+
+// commitOffsets commits offsets to the offset store and the server, it returns ctx.Err() when ctx is done
+// before the commits complete, the commit in progress is still completed by the client library.
+func (c *Consumer) commitOffsets(ctx context.Context, offsets []tmq.TopicPartition) ([]tmq.TopicPartition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.offsetStore != nil {
+		err := c.offsetStore.Store(c.groupID, offsets)
+		if err != nil {
+			return nil, err
+		}
+		if c.offsetStoreMode == tmq.StoreOnly {
+			return c.Committed(offsets, 0)
+		}
+	}
+	start := time.Now()
+	_, span := trace.Start(ctx, trace.SpanTMQCommit)
 	for i := 0; i < len(offsets); i++ {
-		errCode := wrapper.TMQCommitOffsetSync(c.cConsumer, *offsets[i].Topic, offsets[i].Partition, int64(offsets[i].Offset))
-		if errCode != taosError.SUCCESS {
-			return nil, tmqError(errCode)
+		result := make(chan *wrapper.TMQCommitCallbackResult, 1)
+		h := cgo.NewHandle(result)
+		wrapper.TMQCommitOffsetAsync(c.cConsumer, *offsets[i].Topic, offsets[i].Partition, int64(offsets[i].Offset), h)
+		if err := awaitCommit(ctx, result, h); err != nil {
+			metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), err)
+			trace.End(span, err)
+			return nil, err
 		}
 	}
+	metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), nil)
+	trace.End(span, nil)
 	return c.Committed(offsets, 0)
 }
 
@@ -314,8 +619,10 @@ func (c *Consumer) Position(partitions []tmq.TopicPartition) (offsets []tmq.Topi
 func (c *Consumer) Close() error {
 	errCode := wrapper.TMQConsumerClose(c.cConsumer)
 	if errCode != 0 {
+		c.logger.Warn("close consumer failed", "group_id", c.groupID, "code", errCode)
 		return tmqError(errCode)
 	}
+	c.logger.Info("consumer closed", "group_id", c.groupID)
 	return nil
 }
 
@@ -0,0 +1,322 @@
+package tmq
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	taosError "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/wrapper"
+)
+
+// ErrSkipMessage is returned by the meta handler of a Replicator to skip writing a meta message.
+var ErrSkipMessage = errors.New("skip message")
+
+type ReplicatorConfig struct {
+	pollTimeoutMs      int
+	checkpointInterval time.Duration
+	metaHandler        func(partition tmq.TopicPartition, meta *tmq.Meta) error
+	writeErrorHandler  func(partition tmq.TopicPartition, err error) error
+}
+
+// SetPollTimeout sets the timeout of each poll, default 100ms.
+func SetPollTimeout(timeoutMs int) func(*ReplicatorConfig) {
+	return func(c *ReplicatorConfig) {
+		c.pollTimeoutMs = timeoutMs
+	}
+}
+
+// SetCheckpointInterval sets how often the offsets of the written messages are committed, default 1s.
+// With 0 the offset is committed after every message.
+func SetCheckpointInterval(interval time.Duration) func(*ReplicatorConfig) {
+	return func(c *ReplicatorConfig) {
+		c.checkpointInterval = interval
+	}
+}
+
+// SetMetaHandler sets the handler called with the parsed meta (create, alter or drop of tables)
+// before a meta message is written. The message is skipped if the handler returns ErrSkipMessage
+// and the replication stops with any other error.
+func SetMetaHandler(metaHandler func(partition tmq.TopicPartition, meta *tmq.Meta) error) func(*ReplicatorConfig) {
+	return func(c *ReplicatorConfig) {
+		c.metaHandler = metaHandler
+	}
+}
+
+// SetWriteErrorHandler sets the handler of errors writing a message to the target. The message is skipped
+// if the handler returns nil, for example for a table that already exists after a restart,
+// and the replication stops with the returned error otherwise. Without a handler the replication stops with the first error.
+func SetWriteErrorHandler(writeErrorHandler func(partition tmq.TopicPartition, err error) error) func(*ReplicatorConfig) {
+	return func(c *ReplicatorConfig) {
+		c.writeErrorHandler = writeErrorHandler
+	}
+}
+
+// VgroupStats is the replication progress of a vgroup.
+type VgroupStats struct {
+	Topic    string
+	VgroupID int32
+	// Committed is the offset of the last checkpoint
+	Committed tmq.Offset
+	// Lag is the number of offsets between the last checkpoint and the end of the vgroup
+	Lag int64
+}
+
+// ReplicatorStats counts the messages written by a Replicator.
+type ReplicatorStats struct {
+	Messages     uint64
+	MetaMessages uint64
+	Rows         uint64
+	Skipped      uint64
+	Vgroups      []VgroupStats
+}
+
+// Replicator copies the messages of the subscribed topics to another cluster without parsing them.
+//
+// Each message is taken with tmq_get_raw and written to the target connection with tmq_write_raw in poll order,
+// so the tables created or altered by meta messages exist before their data is written. The offset of a vgroup
+// is committed only after its messages have been written, a failed message and the following ones are consumed
+// again after a restart.
+type Replicator struct {
+	consumer    *Consumer
+	target      unsafe.Pointer
+	config      ReplicatorConfig
+	vgroups     map[vgroupKey]*vgroupCheckpoint
+	statsLock   sync.Mutex
+	stats       ReplicatorStats
+	vgroupStats map[vgroupKey]*VgroupStats
+}
+
+type vgroupCheckpoint struct {
+	// offset to commit, the position after the last written message
+	offset tmq.Offset
+	dirty  bool
+}
+
+// NewReplicator creates a replicator of a subscribed consumer. The target is a connection opened
+// by wrapper.TaosConnect with the target database selected.
+func NewReplicator(consumer *Consumer, target unsafe.Pointer, opts ...func(*ReplicatorConfig)) (*Replicator, error) {
+	if target == nil {
+		return nil, taosError.ErrTscInvalidConnection
+	}
+	config := ReplicatorConfig{
+		pollTimeoutMs:      100,
+		checkpointInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.pollTimeoutMs <= 0 {
+		config.pollTimeoutMs = 100
+	}
+	return &Replicator{
+		consumer:    consumer,
+		target:      target,
+		config:      config,
+		vgroups:     make(map[vgroupKey]*vgroupCheckpoint),
+		vgroupStats: make(map[vgroupKey]*VgroupStats),
+	}, nil
+}
+
+// Run replicates messages until ctx is cancelled or a message can not be written,
+// then the offsets of the written messages are committed.
+// Run returns nil when ctx is cancelled and the first error otherwise.
+func (r *Replicator) Run(ctx context.Context) error {
+	nextCheckpoint := time.Now().Add(r.config.checkpointInterval)
+	var runErr error
+	for ctx.Err() == nil {
+		message := wrapper.TMQConsumerPoll(r.consumer.cConsumer, int64(r.config.pollTimeoutMs))
+		if message != nil {
+			runErr = r.replicate(message)
+			wrapper.TaosFreeResult(message)
+			if runErr != nil {
+				break
+			}
+		}
+		if !time.Now().Before(nextCheckpoint) {
+			if runErr = r.checkpoint(); runErr != nil {
+				break
+			}
+			nextCheckpoint = time.Now().Add(r.config.checkpointInterval)
+		}
+	}
+	if err := r.checkpoint(); err != nil && runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+func (r *Replicator) replicate(message unsafe.Pointer) error {
+	topic := wrapper.TMQGetTopicName(message)
+	vgID := wrapper.TMQGetVgroupID(message)
+	partition := tmq.TopicPartition{
+		Topic:     &topic,
+		Partition: vgID,
+		Offset:    tmq.Offset(wrapper.TMQGetVgroupOffset(message)),
+	}
+	if r.consumer.offsetStore != nil {
+		moved, err := r.consumer.restoreVgroup(topic, vgID, partition.Offset)
+		if err != nil || moved {
+			return err
+		}
+	}
+	resultType := wrapper.TMQGetResType(message)
+	isMeta := resultType == common.TMQ_RES_TABLE_META || resultType == common.TMQ_RES_METADATA
+	skip := false
+	if isMeta && r.config.metaHandler != nil {
+		meta, err := r.consumer.getMeta(message)
+		if err != nil {
+			return err
+		}
+		err = r.config.metaHandler(partition, meta)
+		if err == ErrSkipMessage {
+			skip = true
+		} else if err != nil {
+			return err
+		}
+	}
+	var rows int
+	if !skip {
+		errCode, raw := wrapper.TMQGetRaw(message)
+		if errCode != taosError.SUCCESS {
+			errStr := wrapper.TaosErrorStr(message)
+			return taosError.NewError(int(errCode), errStr)
+		}
+		if resultType != common.TMQ_RES_TABLE_META {
+			var err error
+			rows, err = r.countRows(raw)
+			if err != nil {
+				wrapper.TMQFreeRaw(raw)
+				return err
+			}
+		}
+		errCode = wrapper.TMQWriteRaw(r.target, raw)
+		wrapper.TMQFreeRaw(raw)
+		if errCode != taosError.SUCCESS {
+			err := tmqError(errCode)
+			if r.config.writeErrorHandler == nil {
+				return err
+			}
+			if err = r.config.writeErrorHandler(partition, err); err != nil {
+				return err
+			}
+			skip = true
+		}
+	}
+	position := wrapper.TMQPosition(r.consumer.cConsumer, topic, vgID)
+	if position < 0 {
+		return tmqError(int32(position))
+	}
+	key := vgroupKey{topic: topic, vgroupID: vgID}
+	checkpoint, exist := r.vgroups[key]
+	if !exist {
+		checkpoint = &vgroupCheckpoint{}
+		r.vgroups[key] = checkpoint
+	}
+	checkpoint.offset = tmq.Offset(position)
+	checkpoint.dirty = true
+
+	r.statsLock.Lock()
+	if skip {
+		r.stats.Skipped += 1
+	} else {
+		r.stats.Messages += 1
+		if isMeta {
+			r.stats.MetaMessages += 1
+		}
+		r.stats.Rows += uint64(rows)
+	}
+	r.statsLock.Unlock()
+	if r.config.checkpointInterval == 0 {
+		return r.checkpoint()
+	}
+	return nil
+}
+
+func (r *Replicator) countRows(raw unsafe.Pointer) (int, error) {
+	_, _, rawPtr := wrapper.ParseRawMeta(raw)
+	blockInfos, err := r.consumer.dataParser.Parse(rawPtr)
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	for _, blockInfo := range blockInfos {
+		rows += int(parser.RawBlockGetNumOfRows(blockInfo.RawBlock))
+	}
+	return rows, nil
+}
+
+// checkpoint commits the offsets of the vgroups written since the last checkpoint and updates their lag,
+// the offsets are saved in the offset store of the consumer if it has one.
+func (r *Replicator) checkpoint() error {
+	// end offsets of the vgroups of each topic
+	ends := make(map[string]map[int32]int64)
+	for key, checkpoint := range r.vgroups {
+		if !checkpoint.dirty {
+			continue
+		}
+		if r.consumer.offsetStore != nil {
+			topic := key.topic
+			_, err := r.consumer.CommitOffsets([]tmq.TopicPartition{{Topic: &topic, Partition: key.vgroupID, Offset: checkpoint.offset}})
+			if err != nil {
+				return err
+			}
+		} else {
+			errCode := wrapper.TMQCommitOffsetSync(r.consumer.cConsumer, key.topic, key.vgroupID, int64(checkpoint.offset))
+			if errCode != taosError.SUCCESS {
+				return tmqError(errCode)
+			}
+		}
+		checkpoint.dirty = false
+		topicEnds, exist := ends[key.topic]
+		if !exist {
+			errCode, assignment := wrapper.TMQGetTopicAssignment(r.consumer.cConsumer, key.topic)
+			if errCode != taosError.SUCCESS {
+				return tmqError(errCode)
+			}
+			topicEnds = make(map[int32]int64, len(assignment))
+			for _, vgroup := range assignment {
+				topicEnds[vgroup.VGroupID] = vgroup.End
+			}
+			ends[key.topic] = topicEnds
+		}
+		r.statsLock.Lock()
+		stats, exist := r.vgroupStats[key]
+		if !exist {
+			stats = &VgroupStats{Topic: key.topic, VgroupID: key.vgroupID}
+			r.vgroupStats[key] = stats
+		}
+		stats.Committed = checkpoint.offset
+		stats.Lag = 0
+		if end, exist := topicEnds[key.vgroupID]; exist && end > int64(checkpoint.offset) {
+			stats.Lag = end - int64(checkpoint.offset)
+		}
+		r.statsLock.Unlock()
+	}
+	return nil
+}
+
+// Stats returns the counters of the replication, it can be called while Run is running.
+// The lag of a vgroup is updated at every checkpoint.
+func (r *Replicator) Stats() ReplicatorStats {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+	stats := r.stats
+	stats.Vgroups = make([]VgroupStats, 0, len(r.vgroupStats))
+	for _, vgroup := range r.vgroupStats {
+		stats.Vgroups = append(stats.Vgroups, *vgroup)
+	}
+	sort.Slice(stats.Vgroups, func(i, j int) bool {
+		if stats.Vgroups[i].Topic != stats.Vgroups[j].Topic {
+			return stats.Vgroups[i].Topic < stats.Vgroups[j].Topic
+		}
+		return stats.Vgroups[i].VgroupID < stats.Vgroups[j].VgroupID
+	})
+	return stats
+}
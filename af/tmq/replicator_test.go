@@ -0,0 +1,112 @@
+package tmq
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/af"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	"github.com/taosdata/driver-go/v3/wrapper"
+)
+
+func TestReplicator(t *testing.T) {
+	conn, err := wrapper.TaosConnect("", "root", "taosdata", "", 0)
+	require.NoError(t, err)
+	defer wrapper.TaosClose(conn)
+	sqls := []string{
+		"drop topic if exists af_test_replicator_topic",
+		"drop database if exists af_test_replicator_source",
+		"drop database if exists af_test_replicator_target",
+		"create database af_test_replicator_source vgroups 2 WAL_RETENTION_PERIOD 86400",
+		"create database af_test_replicator_target vgroups 2",
+		"create topic af_test_replicator_topic with meta as database af_test_replicator_source",
+		"create stable af_test_replicator_source.stb (ts timestamp, v int) tags (t1 int)",
+		"insert into af_test_replicator_source.ct0 using af_test_replicator_source.stb tags (1) values (now, 1) (now+1s, 2)",
+		"insert into af_test_replicator_source.ct1 using af_test_replicator_source.stb tags (2) values (now, 3)",
+	}
+	for _, sql := range sqls {
+		require.NoError(t, execWithoutResult(conn, sql))
+	}
+	defer func() {
+		assert.NoError(t, doClean(conn, []string{
+			"drop topic if exists af_test_replicator_topic",
+			"drop database if exists af_test_replicator_source",
+			"drop database if exists af_test_replicator_target",
+		}))
+	}()
+	target, err := wrapper.TaosConnect("", "root", "taosdata", "af_test_replicator_target", 0)
+	require.NoError(t, err)
+	defer wrapper.TaosClose(target)
+
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"group.id":            "test_replicator",
+		"auto.offset.reset":   "earliest",
+		"td.connect.user":     "root",
+		"td.connect.pass":     "taosdata",
+		"enable.auto.commit":  "false",
+		"msg.with.table.name": "true",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	require.NoError(t, consumer.Subscribe("af_test_replicator_topic", nil))
+
+	_, err = NewReplicator(consumer, nil)
+	assert.Error(t, err)
+	var metas []*tmq.Meta
+	replicator, err := NewReplicator(consumer, target, SetCheckpointInterval(0), SetMetaHandler(func(_ tmq.TopicPartition, meta *tmq.Meta) error {
+		metas = append(metas, meta)
+		return nil
+	}))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- replicator.Run(ctx)
+	}()
+	deadline := time.Now().Add(time.Second * 30)
+	for replicator.Stats().Rows < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 100)
+	}
+	cancel()
+	require.NoError(t, <-done)
+
+	stats := replicator.Stats()
+	assert.Equal(t, uint64(3), stats.Rows)
+	assert.True(t, stats.MetaMessages > 0)
+	assert.Equal(t, stats.MetaMessages, uint64(len(metas)))
+	assert.Equal(t, uint64(0), stats.Skipped)
+	for _, vgroup := range stats.Vgroups {
+		assert.Equal(t, "af_test_replicator_topic", vgroup.Topic)
+		assert.Equal(t, int64(0), vgroup.Lag)
+	}
+	assignment, err := consumer.Assignment()
+	require.NoError(t, err)
+	committed, err := consumer.Committed(assignment, 0)
+	require.NoError(t, err)
+	for _, vgroup := range stats.Vgroups {
+		for _, partition := range committed {
+			if partition.Partition == vgroup.VgroupID {
+				assert.Equal(t, vgroup.Committed, partition.Offset)
+			}
+		}
+	}
+
+	targetConnector, err := af.NewConnector(target)
+	require.NoError(t, err)
+	rows, err := targetConnector.Query("select count(*) from af_test_replicator_target.stb")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, rows.Close())
+	}()
+	values := make([]driver.Value, 1)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, int64(3), values[0])
+	assert.Equal(t, io.EOF, rows.Next(values))
+}
@@ -0,0 +1,407 @@
+// Package bulk writes slices of go structs to a supertable with parameter binding.
+//
+// Struct fields are mapped to the columns of the table by the `taos` tag, fields without the tag
+// use their name and fields tagged with `taos:"-"` are ignored. Options follow the name separated by commas:
+//
+//	tbname    the field holds the subtable name, rows are grouped into subtables by it
+//	tag       the field is a tag of the supertable
+//	nchar     the string field is a NCHAR column, the default of string fields is VARCHAR
+//	binary    the string or []byte field is a VARCHAR (BINARY) column
+//	varbinary the string or []byte field is a VARBINARY column, the default of []byte fields
+//	json      the string or []byte field is a JSON tag
+//	geometry  the []byte field is a GEOMETRY column in WKB format
+//
+// The column types of the other fields are inferred from the go type: bool, int8 to int64, uint8 to uint64,
+// float32, float64 and time.Time (TIMESTAMP), int and uint are BIGINT and BIGINT UNSIGNED.
+// Nil pointers, nil []byte and the nullable types of the types and database/sql packages without a value are written as NULL.
+package bulk
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+const tagName = "taos"
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// nullableTypes maps the nullable types to the column type of their value.
+var nullableTypes = map[reflect.Type]int{
+	reflect.TypeOf(types.NullBool{}):    common.TSDB_DATA_TYPE_BOOL,
+	reflect.TypeOf(types.NullInt8{}):    common.TSDB_DATA_TYPE_TINYINT,
+	reflect.TypeOf(types.NullInt16{}):   common.TSDB_DATA_TYPE_SMALLINT,
+	reflect.TypeOf(types.NullInt32{}):   common.TSDB_DATA_TYPE_INT,
+	reflect.TypeOf(types.NullInt64{}):   common.TSDB_DATA_TYPE_BIGINT,
+	reflect.TypeOf(types.NullUInt8{}):   common.TSDB_DATA_TYPE_UTINYINT,
+	reflect.TypeOf(types.NullUInt16{}):  common.TSDB_DATA_TYPE_USMALLINT,
+	reflect.TypeOf(types.NullUInt32{}):  common.TSDB_DATA_TYPE_UINT,
+	reflect.TypeOf(types.NullUInt64{}):  common.TSDB_DATA_TYPE_UBIGINT,
+	reflect.TypeOf(types.NullFloat32{}): common.TSDB_DATA_TYPE_FLOAT,
+	reflect.TypeOf(types.NullFloat64{}): common.TSDB_DATA_TYPE_DOUBLE,
+	reflect.TypeOf(types.NullString{}):  common.TSDB_DATA_TYPE_BINARY,
+	reflect.TypeOf(types.NullTime{}):    common.TSDB_DATA_TYPE_TIMESTAMP,
+	reflect.TypeOf(types.NullJson{}):    common.TSDB_DATA_TYPE_JSON,
+	reflect.TypeOf(sql.NullBool{}):      common.TSDB_DATA_TYPE_BOOL,
+	reflect.TypeOf(sql.NullInt32{}):     common.TSDB_DATA_TYPE_INT,
+	reflect.TypeOf(sql.NullInt64{}):     common.TSDB_DATA_TYPE_BIGINT,
+	reflect.TypeOf(sql.NullFloat64{}):   common.TSDB_DATA_TYPE_DOUBLE,
+	reflect.TypeOf(sql.NullString{}):    common.TSDB_DATA_TYPE_BINARY,
+	reflect.TypeOf(sql.NullTime{}):      common.TSDB_DATA_TYPE_TIMESTAMP,
+}
+
+type column struct {
+	name     string
+	index    []int
+	dataType int
+}
+
+// Schema is the table layout inferred from a struct type.
+type Schema struct {
+	structType reflect.Type
+	tableName  *column
+	tags       []*column
+	cols       []*column
+}
+
+var schemaCache sync.Map
+
+// ParseSchema infers the schema from the struct type of sample,
+// sample is a struct, a pointer to a struct or a slice of them.
+func ParseSchema(sample interface{}) (*Schema, error) {
+	t := reflect.TypeOf(sample)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bulk: sample must be a struct or a slice of structs, got %T", sample)
+	}
+	if schema, ok := schemaCache.Load(t); ok {
+		return schema.(*Schema), nil
+	}
+	schema := &Schema{structType: t}
+	err := schema.parseStruct(t, nil, make(map[string]struct{}))
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.cols) == 0 {
+		return nil, fmt.Errorf("bulk: struct %s has no columns", t)
+	}
+	if len(schema.tags) != 0 && schema.tableName == nil {
+		return nil, fmt.Errorf("bulk: struct %s has tags but no tbname field", t)
+	}
+	schemaCache.Store(t, schema)
+	return schema, nil
+}
+
+func (s *Schema) parseStruct(t reflect.Type, parent []int, names map[string]struct{}) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+		if f.Anonymous && !hasTag && f.Type.Kind() == reflect.Struct && nullableType(f.Type) == -1 && f.Type != timeType {
+			err := s.parseStruct(f.Type, index, names)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := f.Name
+		var options []string
+		if hasTag {
+			parts := strings.Split(tag, ",")
+			if len(parts[0]) != 0 {
+				name = parts[0]
+			}
+			options = parts[1:]
+		}
+		col := &column{name: name, index: index}
+		isTableName, isTag := false, false
+		typeOption := ""
+		for _, option := range options {
+			switch option {
+			case "":
+			case "tbname":
+				isTableName = true
+			case "tag":
+				isTag = true
+			case "nchar", "binary", "varbinary", "json", "geometry":
+				if len(typeOption) != 0 {
+					return fmt.Errorf("bulk: field %s has more than one type option", f.Name)
+				}
+				typeOption = option
+			default:
+				return fmt.Errorf("bulk: unknown option %s of field %s", option, f.Name)
+			}
+		}
+		if isTableName {
+			if isTag || len(typeOption) != 0 {
+				return fmt.Errorf("bulk: tbname field %s can not have other options", f.Name)
+			}
+			if s.tableName != nil {
+				return fmt.Errorf("bulk: more than one tbname field")
+			}
+			if fieldKind(f.Type) != reflect.String {
+				return fmt.Errorf("bulk: tbname field %s must be a string", f.Name)
+			}
+			col.dataType = common.TSDB_DATA_TYPE_BINARY
+			s.tableName = col
+			continue
+		}
+		dataType, err := inferType(f.Type, typeOption)
+		if err != nil {
+			return fmt.Errorf("bulk: field %s: %w", f.Name, err)
+		}
+		if dataType == common.TSDB_DATA_TYPE_JSON && !isTag {
+			return fmt.Errorf("bulk: json field %s must be a tag", f.Name)
+		}
+		col.dataType = dataType
+		key := strings.ToLower(name)
+		if _, exist := names[key]; exist {
+			return fmt.Errorf("bulk: duplicate column %s", name)
+		}
+		names[key] = struct{}{}
+		if isTag {
+			s.tags = append(s.tags, col)
+		} else {
+			s.cols = append(s.cols, col)
+		}
+	}
+	return nil
+}
+
+func fieldKind(t reflect.Type) reflect.Kind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind()
+}
+
+func nullableType(t reflect.Type) int {
+	if dataType, ok := nullableTypes[t]; ok {
+		return dataType
+	}
+	return -1
+}
+
+func inferType(t reflect.Type, typeOption string) (int, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	dataType := nullableType(t)
+	if dataType == -1 {
+		dataType = inferGoType(t)
+	}
+	if dataType == -1 {
+		return 0, fmt.Errorf("unsupported type %s", t)
+	}
+	if len(typeOption) == 0 {
+		return dataType, nil
+	}
+	if dataType != common.TSDB_DATA_TYPE_BINARY &&
+		dataType != common.TSDB_DATA_TYPE_VARBINARY &&
+		dataType != common.TSDB_DATA_TYPE_JSON {
+		return 0, fmt.Errorf("option %s requires a string or []byte field", typeOption)
+	}
+	switch typeOption {
+	case "nchar":
+		return common.TSDB_DATA_TYPE_NCHAR, nil
+	case "binary":
+		return common.TSDB_DATA_TYPE_BINARY, nil
+	case "varbinary":
+		return common.TSDB_DATA_TYPE_VARBINARY, nil
+	case "json":
+		return common.TSDB_DATA_TYPE_JSON, nil
+	default:
+		return common.TSDB_DATA_TYPE_GEOMETRY, nil
+	}
+}
+
+func inferGoType(t reflect.Type) int {
+	if t == timeType {
+		return common.TSDB_DATA_TYPE_TIMESTAMP
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return common.TSDB_DATA_TYPE_VARBINARY
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return common.TSDB_DATA_TYPE_BOOL
+	case reflect.Int8:
+		return common.TSDB_DATA_TYPE_TINYINT
+	case reflect.Int16:
+		return common.TSDB_DATA_TYPE_SMALLINT
+	case reflect.Int32:
+		return common.TSDB_DATA_TYPE_INT
+	case reflect.Int64, reflect.Int:
+		return common.TSDB_DATA_TYPE_BIGINT
+	case reflect.Uint8:
+		return common.TSDB_DATA_TYPE_UTINYINT
+	case reflect.Uint16:
+		return common.TSDB_DATA_TYPE_USMALLINT
+	case reflect.Uint32:
+		return common.TSDB_DATA_TYPE_UINT
+	case reflect.Uint64, reflect.Uint:
+		return common.TSDB_DATA_TYPE_UBIGINT
+	case reflect.Float32:
+		return common.TSDB_DATA_TYPE_FLOAT
+	case reflect.Float64:
+		return common.TSDB_DATA_TYPE_DOUBLE
+	case reflect.String:
+		return common.TSDB_DATA_TYPE_BINARY
+	}
+	return -1
+}
+
+// value returns the value of the column in row converted to the go type stmt2 expects for the column type:
+// bool, int8 to int64, uint8 to uint64, float32, float64, time.Time, string for NCHAR and []byte
+// for the other variable length types, or nil.
+func (c *column) value(row reflect.Value) (driver.Value, error) {
+	v := row.FieldByIndex(c.index)
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	if nullableType(v.Type()) != -1 {
+		inner, err := v.Interface().(driver.Valuer).Value()
+		if err != nil {
+			return nil, err
+		}
+		if inner == nil {
+			return nil, nil
+		}
+		v = reflect.ValueOf(inner)
+	}
+	value, err := convertValue(c.dataType, v)
+	if err != nil {
+		return nil, fmt.Errorf("bulk: column %s: %w", c.name, err)
+	}
+	return value, nil
+}
+
+func convertValue(dataType int, v reflect.Value) (driver.Value, error) {
+	switch dataType {
+	case common.TSDB_DATA_TYPE_BOOL:
+		if v.Kind() == reflect.Bool {
+			return v.Bool(), nil
+		}
+	case common.TSDB_DATA_TYPE_TINYINT:
+		i, err := toInt(v, math.MinInt8, math.MaxInt8)
+		return int8(i), err
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		i, err := toInt(v, math.MinInt16, math.MaxInt16)
+		return int16(i), err
+	case common.TSDB_DATA_TYPE_INT:
+		i, err := toInt(v, math.MinInt32, math.MaxInt32)
+		return int32(i), err
+	case common.TSDB_DATA_TYPE_BIGINT:
+		return toInt(v, math.MinInt64, math.MaxInt64)
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		u, err := toUint(v, math.MaxUint8)
+		return uint8(u), err
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		u, err := toUint(v, math.MaxUint16)
+		return uint16(u), err
+	case common.TSDB_DATA_TYPE_UINT:
+		u, err := toUint(v, math.MaxUint32)
+		return uint32(u), err
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		return toUint(v, math.MaxUint64)
+	case common.TSDB_DATA_TYPE_FLOAT:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return float32(v.Float()), nil
+		}
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return v.Float(), nil
+		}
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time), nil
+		}
+	case common.TSDB_DATA_TYPE_NCHAR:
+		switch {
+		case v.Kind() == reflect.String:
+			return v.String(), nil
+		case v.Type().ConvertibleTo(bytesType):
+			return string(v.Bytes()), nil
+		}
+	default:
+		switch {
+		case v.Kind() == reflect.String:
+			return []byte(v.String()), nil
+		case v.Type().ConvertibleTo(bytesType):
+			return v.Convert(bytesType).Interface().([]byte), nil
+		}
+	}
+	return nil, fmt.Errorf("can not convert %s to %s", v.Type(), common.TypeNameArray[dataType])
+}
+
+func toInt(v reflect.Value, min, max int64) (int64, error) {
+	var i int64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d out of range", u)
+		}
+		i = int64(u)
+	default:
+		return 0, fmt.Errorf("can not convert %s to integer", v.Type())
+	}
+	if i < min || i > max {
+		return 0, fmt.Errorf("value %d out of range", i)
+	}
+	return i, nil
+}
+
+func toUint(v reflect.Value, max uint64) (uint64, error) {
+	var u uint64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < 0 {
+			return 0, fmt.Errorf("value %d out of range", i)
+		}
+		u = uint64(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u = v.Uint()
+	default:
+		return 0, fmt.Errorf("can not convert %s to unsigned integer", v.Type())
+	}
+	if u > max {
+		return 0, fmt.Errorf("value %d out of range", u)
+	}
+	return u, nil
+}
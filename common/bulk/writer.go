@@ -0,0 +1,456 @@
+package bulk
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/stmt"
+	taosTypes "github.com/taosdata/driver-go/v3/types"
+)
+
+// NativeStmt is the statement of the native connection, *insertstmt.InsertStmt from af.Connector.InsertStmt.
+type NativeStmt interface {
+	Prepare(sql string) error
+	SetTableName(name string) error
+	SetTableNameWithTags(tableName string, tags *param.Param) error
+	BindParam(params []*param.Param, bindType *param.ColumnType) error
+	AddBatch() error
+	Execute() error
+	GetAffectedRows() int
+}
+
+// WSStmt is the statement of the websocket connection, *stmt.Stmt from the ws/stmt package.
+type WSStmt interface {
+	Prepare(sql string) error
+	SetTableName(name string) error
+	SetTags(tags *param.Param, bindType *param.ColumnType) error
+	BindParam(params []*param.Param, bindType *param.ColumnType) error
+	AddBatch() error
+	Exec() error
+	GetAffectedRows() int
+}
+
+// Stmt2 is the stmt2 statement of either connection, *af.Stmt2 or *stmt.Stmt2 from the ws/stmt package.
+// The statement is executed with Execute or Exec, whichever it has.
+type Stmt2 interface {
+	Prepare(sql string) error
+	Bind(params []*stmt.TaosStmt2BindData) error
+	GetAffectedRows() int
+}
+
+// Writer writes slices of structs of one type to a supertable.
+// A Writer is not safe for concurrent use.
+type Writer struct {
+	schema    *Schema
+	sql       string
+	precision int
+	prepared  interface{}
+}
+
+// NewWriter creates a Writer for the supertable and the struct type of sample,
+// precision is the timestamp precision of the database, it is only used by NativeStmt and WSStmt.
+// If the struct has no tbname field, stable is the name of the table all rows are written to.
+func NewWriter(stable string, sample interface{}, precision int) (*Writer, error) {
+	schema, err := ParseSchema(sample)
+	if err != nil {
+		return nil, err
+	}
+	switch precision {
+	case common.PrecisionMilliSecond, common.PrecisionMicroSecond, common.PrecisionNanoSecond:
+	default:
+		return nil, fmt.Errorf("bulk: invalid precision %d", precision)
+	}
+	return &Writer{
+		schema:    schema,
+		sql:       schema.insertSQL(stable),
+		precision: precision,
+	}, nil
+}
+
+// SQL returns the insert statement prepared by the writer.
+func (w *Writer) SQL() string {
+	return w.sql
+}
+
+func (s *Schema) insertSQL(stable string) string {
+	b := &bytes.Buffer{}
+	b.WriteString("insert into ")
+	if s.tableName == nil {
+		b.WriteString(stable)
+	} else {
+		b.WriteString("?")
+		if len(s.tags) != 0 {
+			b.WriteString(" using ")
+			b.WriteString(stable)
+			writeColumns(b, s.tags)
+			b.WriteString(" tags")
+			writePlaceholders(b, len(s.tags))
+		}
+	}
+	writeColumns(b, s.cols)
+	b.WriteString(" values")
+	writePlaceholders(b, len(s.cols))
+	return b.String()
+}
+
+func writeColumns(b *bytes.Buffer, columns []*column) {
+	b.WriteString(" (")
+	for i, col := range columns {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('`')
+		b.WriteString(col.name)
+		b.WriteByte('`')
+	}
+	b.WriteByte(')')
+}
+
+func writePlaceholders(b *bytes.Buffer, count int) {
+	b.WriteByte('(')
+	for i := 0; i < count; i++ {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('?')
+	}
+	b.WriteByte(')')
+}
+
+// table holds the rows of one subtable in column order.
+type table struct {
+	name string
+	tags []driver.Value
+	cols [][]driver.Value
+}
+
+// group splits rows by the subtable name in the order the subtables first appear,
+// the tags of a subtable are taken from its first row.
+func (s *Schema) group(rows interface{}) ([]*table, error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("bulk: rows must be a slice of %s, got %T", s.structType, rows)
+	}
+	elemType := rv.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType != s.structType {
+		return nil, fmt.Errorf("bulk: rows must be a slice of %s, got %T", s.structType, rows)
+	}
+	var tables []*table
+	tableMap := make(map[string]*table)
+	for i := 0; i < rv.Len(); i++ {
+		row := rv.Index(i)
+		if isPtr {
+			if row.IsNil() {
+				return nil, fmt.Errorf("bulk: row %d is nil", i)
+			}
+			row = row.Elem()
+		}
+		name := ""
+		if s.tableName != nil {
+			v, err := s.tableName.value(row)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil || len(v.([]byte)) == 0 {
+				return nil, fmt.Errorf("bulk: row %d has no table name", i)
+			}
+			name = string(v.([]byte))
+		}
+		t, exist := tableMap[name]
+		if !exist {
+			t = &table{name: name, cols: make([][]driver.Value, len(s.cols))}
+			if len(s.tags) != 0 {
+				t.tags = make([]driver.Value, len(s.tags))
+				for j, tag := range s.tags {
+					v, err := tag.value(row)
+					if err != nil {
+						return nil, err
+					}
+					t.tags[j] = v
+				}
+			}
+			tableMap[name] = t
+			tables = append(tables, t)
+		}
+		for j, col := range s.cols {
+			v, err := col.value(row)
+			if err != nil {
+				return nil, err
+			}
+			t.cols[j] = append(t.cols[j], v)
+		}
+	}
+	return tables, nil
+}
+
+// Write writes rows, a slice of structs or struct pointers, with statement s and returns the affected rows.
+// s is a Stmt2, WSStmt or NativeStmt, it is prepared with the insert statement of the writer
+// unless it is the statement of the previous Write call.
+// All subtables are bound and executed in one batch.
+func (w *Writer) Write(s interface{}, rows interface{}) (int, error) {
+	tables, err := w.schema.group(rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(tables) == 0 {
+		return 0, nil
+	}
+	switch s := s.(type) {
+	case Stmt2:
+		return w.writeStmt2(s, tables)
+	case WSStmt:
+		return w.writeWS(s, tables)
+	case NativeStmt:
+		return w.writeNative(s, tables)
+	default:
+		return 0, fmt.Errorf("bulk: unsupported statement %T", s)
+	}
+}
+
+func (w *Writer) prepare(s interface{ Prepare(sql string) error }) error {
+	if w.prepared == s {
+		return nil
+	}
+	w.prepared = nil
+	err := s.Prepare(w.sql)
+	if err != nil {
+		return err
+	}
+	w.prepared = s
+	return nil
+}
+
+func (w *Writer) writeStmt2(s Stmt2, tables []*table) (int, error) {
+	var execute func() error
+	switch e := s.(type) {
+	case interface{ Execute() error }:
+		execute = e.Execute
+	case interface{ Exec() error }:
+		execute = e.Exec
+	default:
+		return 0, fmt.Errorf("bulk: statement %T has no Execute or Exec method", s)
+	}
+	err := w.prepare(s)
+	if err != nil {
+		return 0, err
+	}
+	bindData := make([]*stmt.TaosStmt2BindData, len(tables))
+	for i, t := range tables {
+		bindData[i] = &stmt.TaosStmt2BindData{
+			TableName: t.name,
+			Tags:      t.tags,
+			Cols:      t.cols,
+		}
+	}
+	err = s.Bind(bindData)
+	if err != nil {
+		return 0, err
+	}
+	err = execute()
+	if err != nil {
+		return 0, err
+	}
+	return s.GetAffectedRows(), nil
+}
+
+func (w *Writer) writeWS(s WSStmt, tables []*table) (int, error) {
+	err := w.prepare(s)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range tables {
+		if w.schema.tableName != nil {
+			err = s.SetTableName(t.name)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if len(t.tags) != 0 {
+			tags, tagTypes := w.tagParams(t)
+			err = s.SetTags(tags, tagTypes)
+			if err != nil {
+				return 0, err
+			}
+		}
+		params, colTypes := w.colParams(t)
+		err = s.BindParam(params, colTypes)
+		if err != nil {
+			return 0, err
+		}
+		err = s.AddBatch()
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = s.Exec()
+	if err != nil {
+		return 0, err
+	}
+	return s.GetAffectedRows(), nil
+}
+
+func (w *Writer) writeNative(s NativeStmt, tables []*table) (int, error) {
+	err := w.prepare(s)
+	if err != nil {
+		return 0, err
+	}
+	for _, t := range tables {
+		if len(t.tags) != 0 {
+			tags, _ := w.tagParams(t)
+			err = s.SetTableNameWithTags(t.name, tags)
+		} else if w.schema.tableName != nil {
+			err = s.SetTableName(t.name)
+		}
+		if err != nil {
+			return 0, err
+		}
+		params, colTypes := w.colParams(t)
+		err = s.BindParam(params, colTypes)
+		if err != nil {
+			return 0, err
+		}
+		err = s.AddBatch()
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = s.Execute()
+	if err != nil {
+		return 0, err
+	}
+	return s.GetAffectedRows(), nil
+}
+
+func (w *Writer) tagParams(t *table) (*param.Param, *param.ColumnType) {
+	tags := param.NewParam(len(t.tags))
+	tagTypes := param.NewColumnType(len(t.tags))
+	for i, tag := range w.schema.tags {
+		v := t.tags[i]
+		tags.AddValue(paramValue(tag.dataType, v, w.precision))
+		addColumnType(tagTypes, tag.dataType, valueLength(v))
+	}
+	return tags, tagTypes
+}
+
+func (w *Writer) colParams(t *table) ([]*param.Param, *param.ColumnType) {
+	params := make([]*param.Param, len(w.schema.cols))
+	colTypes := param.NewColumnType(len(w.schema.cols))
+	for i, col := range w.schema.cols {
+		values := t.cols[i]
+		p := param.NewParam(len(values))
+		maxLength := 0
+		for _, v := range values {
+			p.AddValue(paramValue(col.dataType, v, w.precision))
+			if length := valueLength(v); length > maxLength {
+				maxLength = length
+			}
+		}
+		params[i] = p
+		addColumnType(colTypes, col.dataType, maxLength)
+	}
+	return params, colTypes
+}
+
+func valueLength(v driver.Value) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return 0
+}
+
+// paramValue converts a value returned by column.value to the taos type used by param.Param.
+func paramValue(dataType int, v driver.Value, precision int) driver.Value {
+	if v == nil {
+		return nil
+	}
+	switch dataType {
+	case common.TSDB_DATA_TYPE_BOOL:
+		return taosTypes.TaosBool(v.(bool))
+	case common.TSDB_DATA_TYPE_TINYINT:
+		return taosTypes.TaosTinyint(v.(int8))
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		return taosTypes.TaosSmallint(v.(int16))
+	case common.TSDB_DATA_TYPE_INT:
+		return taosTypes.TaosInt(v.(int32))
+	case common.TSDB_DATA_TYPE_BIGINT:
+		return taosTypes.TaosBigint(v.(int64))
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		return taosTypes.TaosUTinyint(v.(uint8))
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		return taosTypes.TaosUSmallint(v.(uint16))
+	case common.TSDB_DATA_TYPE_UINT:
+		return taosTypes.TaosUInt(v.(uint32))
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		return taosTypes.TaosUBigint(v.(uint64))
+	case common.TSDB_DATA_TYPE_FLOAT:
+		return taosTypes.TaosFloat(v.(float32))
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		return taosTypes.TaosDouble(v.(float64))
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		return taosTypes.TaosTimestamp{T: v.(time.Time), Precision: precision}
+	case common.TSDB_DATA_TYPE_NCHAR:
+		return taosTypes.TaosNchar(v.(string))
+	case common.TSDB_DATA_TYPE_VARBINARY:
+		return taosTypes.TaosVarBinary(v.([]byte))
+	case common.TSDB_DATA_TYPE_JSON:
+		return taosTypes.TaosJson(v.([]byte))
+	case common.TSDB_DATA_TYPE_GEOMETRY:
+		return taosTypes.TaosGeometry(v.([]byte))
+	default:
+		return taosTypes.TaosBinary(v.([]byte))
+	}
+}
+
+func addColumnType(ct *param.ColumnType, dataType int, maxLength int) {
+	if maxLength == 0 {
+		maxLength = 1
+	}
+	switch dataType {
+	case common.TSDB_DATA_TYPE_BOOL:
+		ct.AddBool()
+	case common.TSDB_DATA_TYPE_TINYINT:
+		ct.AddTinyint()
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		ct.AddSmallint()
+	case common.TSDB_DATA_TYPE_INT:
+		ct.AddInt()
+	case common.TSDB_DATA_TYPE_BIGINT:
+		ct.AddBigint()
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		ct.AddUTinyint()
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		ct.AddUSmallint()
+	case common.TSDB_DATA_TYPE_UINT:
+		ct.AddUInt()
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		ct.AddUBigint()
+	case common.TSDB_DATA_TYPE_FLOAT:
+		ct.AddFloat()
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		ct.AddDouble()
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		ct.AddTimestamp()
+	case common.TSDB_DATA_TYPE_NCHAR:
+		ct.AddNchar(maxLength)
+	case common.TSDB_DATA_TYPE_VARBINARY:
+		ct.AddVarBinary(maxLength)
+	case common.TSDB_DATA_TYPE_JSON:
+		ct.AddJson(maxLength)
+	case common.TSDB_DATA_TYPE_GEOMETRY:
+		ct.AddGeometry(maxLength)
+	default:
+		ct.AddBinary(maxLength)
+	}
+}
@@ -0,0 +1,312 @@
+package bulk
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/stmt"
+	taosTypes "github.com/taosdata/driver-go/v3/types"
+)
+
+type Base struct {
+	Ts time.Time `taos:"ts"`
+}
+
+type meter struct {
+	Base
+	Device   string                `taos:"tbname,tbname"`
+	Location string                `taos:"location,tag,nchar"`
+	GroupID  int32                 `taos:"group_id,tag"`
+	Current  float32               `taos:"current"`
+	Voltage  *int32                `taos:"voltage"`
+	Phase    taosTypes.NullFloat64 `taos:"phase"`
+	Note     string                `taos:"-"`
+}
+
+type normal struct {
+	Ts    time.Time
+	Value uint8  `taos:"v"`
+	Data  []byte `taos:"data,binary"`
+}
+
+type fakeStmt2 struct {
+	sql      string
+	prepared int
+	bindData []*stmt.TaosStmt2BindData
+	affected int
+	execErr  error
+}
+
+func (s *fakeStmt2) Prepare(sql string) error {
+	s.sql = sql
+	s.prepared++
+	return nil
+}
+
+func (s *fakeStmt2) Bind(params []*stmt.TaosStmt2BindData) error {
+	s.bindData = params
+	for _, data := range params {
+		s.affected += len(data.Cols[0])
+	}
+	return nil
+}
+
+func (s *fakeStmt2) Execute() error {
+	return s.execErr
+}
+
+func (s *fakeStmt2) GetAffectedRows() int {
+	return s.affected
+}
+
+type fakeBatch struct {
+	tableName string
+	tags      []driver.Value
+	tagTypes  []*taosTypes.ColumnType
+	params    [][]driver.Value
+	colTypes  []*taosTypes.ColumnType
+}
+
+type fakeStmt struct {
+	sql      string
+	current  fakeBatch
+	batches  []fakeBatch
+	affected int
+}
+
+func (s *fakeStmt) Prepare(sql string) error {
+	s.sql = sql
+	return nil
+}
+
+func (s *fakeStmt) SetTableName(name string) error {
+	s.current.tableName = name
+	return nil
+}
+
+func (s *fakeStmt) SetTableNameWithTags(tableName string, tags *param.Param) error {
+	s.current.tableName = tableName
+	s.current.tags = tags.GetValues()
+	return nil
+}
+
+func (s *fakeStmt) SetTags(tags *param.Param, bindType *param.ColumnType) error {
+	s.current.tags = tags.GetValues()
+	var err error
+	s.current.tagTypes, err = bindType.GetValue()
+	return err
+}
+
+func (s *fakeStmt) BindParam(params []*param.Param, bindType *param.ColumnType) error {
+	for _, p := range params {
+		s.current.params = append(s.current.params, p.GetValues())
+	}
+	var err error
+	s.current.colTypes, err = bindType.GetValue()
+	return err
+}
+
+func (s *fakeStmt) AddBatch() error {
+	s.batches = append(s.batches, s.current)
+	s.affected += len(s.current.params[0])
+	s.current = fakeBatch{}
+	return nil
+}
+
+func (s *fakeStmt) GetAffectedRows() int {
+	return s.affected
+}
+
+type fakeNativeStmt struct {
+	fakeStmt
+}
+
+func (s *fakeNativeStmt) Execute() error {
+	return nil
+}
+
+type fakeWSStmt struct {
+	fakeStmt
+}
+
+func (s *fakeWSStmt) Exec() error {
+	return nil
+}
+
+func meterRows(now time.Time) []*meter {
+	voltage := int32(220)
+	return []*meter{
+		{Base: Base{Ts: now}, Device: "d1", Location: "北京", GroupID: 1, Current: 1.5, Voltage: &voltage, Phase: taosTypes.NullFloat64{Inner: 0.3, Valid: true}},
+		{Base: Base{Ts: now}, Device: "d2", Location: "Shanghai", GroupID: 2, Current: 2.5},
+		{Base: Base{Ts: now.Add(time.Second)}, Device: "d1", Location: "ignored", GroupID: 3, Current: 3.5},
+	}
+}
+
+func TestInsertSQL(t *testing.T) {
+	w, err := NewWriter("power.meters", []meter{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	assert.Equal(t, "insert into ? using power.meters (`location`,`group_id`) tags(?,?) (`ts`,`current`,`voltage`,`phase`) values(?,?,?,?)", w.SQL())
+	w, err = NewWriter("power.t", normal{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	assert.Equal(t, "insert into power.t (`Ts`,`v`,`data`) values(?,?,?)", w.SQL())
+}
+
+func TestWriteStmt2(t *testing.T) {
+	now := time.Now()
+	w, err := NewWriter("meters", &meter{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	s := &fakeStmt2{}
+	affected, err := w.Write(s, meterRows(now))
+	require.NoError(t, err)
+	assert.Equal(t, 3, affected)
+	assert.Equal(t, w.SQL(), s.sql)
+	assert.Equal(t, []*stmt.TaosStmt2BindData{
+		{
+			TableName: "d1",
+			Tags:      []driver.Value{"北京", int32(1)},
+			Cols: [][]driver.Value{
+				{now, now.Add(time.Second)},
+				{float32(1.5), float32(3.5)},
+				{int32(220), nil},
+				{0.3, nil},
+			},
+		},
+		{
+			TableName: "d2",
+			Tags:      []driver.Value{"Shanghai", int32(2)},
+			Cols:      [][]driver.Value{{now}, {float32(2.5)}, {nil}, {nil}},
+		},
+	}, s.bindData)
+	// the statement is only prepared once
+	_, err = w.Write(s, meterRows(now))
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.prepared)
+
+	affected, err = w.Write(s, []*meter{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, affected)
+
+	s.execErr = errors.New("exec error")
+	_, err = w.Write(s, meterRows(now))
+	assert.EqualError(t, err, "exec error")
+}
+
+func TestWriteWS(t *testing.T) {
+	now := time.Now()
+	w, err := NewWriter("meters", meter{}, common.PrecisionMicroSecond)
+	require.NoError(t, err)
+	s := &fakeWSStmt{}
+	affected, err := w.Write(s, meterRows(now))
+	require.NoError(t, err)
+	assert.Equal(t, 3, affected)
+	assert.Equal(t, w.SQL(), s.sql)
+	require.Equal(t, 2, len(s.batches))
+	d1 := s.batches[0]
+	assert.Equal(t, "d1", d1.tableName)
+	assert.Equal(t, []driver.Value{taosTypes.TaosNchar("北京"), taosTypes.TaosInt(1)}, d1.tags)
+	assert.Equal(t, []*taosTypes.ColumnType{
+		{Type: taosTypes.TaosNcharType, MaxLen: len("北京")},
+		{Type: taosTypes.TaosIntType},
+	}, d1.tagTypes)
+	assert.Equal(t, [][]driver.Value{
+		{
+			taosTypes.TaosTimestamp{T: now, Precision: common.PrecisionMicroSecond},
+			taosTypes.TaosTimestamp{T: now.Add(time.Second), Precision: common.PrecisionMicroSecond},
+		},
+		{taosTypes.TaosFloat(1.5), taosTypes.TaosFloat(3.5)},
+		{taosTypes.TaosInt(220), nil},
+		{taosTypes.TaosDouble(0.3), nil},
+	}, d1.params)
+	assert.Equal(t, []*taosTypes.ColumnType{
+		{Type: taosTypes.TaosTimestampType},
+		{Type: taosTypes.TaosFloatType},
+		{Type: taosTypes.TaosIntType},
+		{Type: taosTypes.TaosDoubleType},
+	}, d1.colTypes)
+	assert.Equal(t, "d2", s.batches[1].tableName)
+}
+
+func TestWriteNative(t *testing.T) {
+	now := time.Now()
+	w, err := NewWriter("t", normal{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	s := &fakeNativeStmt{}
+	affected, err := w.Write(s, []normal{
+		{Ts: now, Value: 1, Data: []byte("abc")},
+		{Ts: now.Add(time.Second), Value: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, affected)
+	require.Equal(t, 1, len(s.batches))
+	batch := s.batches[0]
+	assert.Equal(t, "", batch.tableName)
+	assert.Nil(t, batch.tags)
+	assert.Equal(t, []driver.Value{taosTypes.TaosUTinyint(1), taosTypes.TaosUTinyint(2)}, batch.params[1])
+	assert.Equal(t, []driver.Value{taosTypes.TaosBinary("abc"), nil}, batch.params[2])
+	assert.Equal(t, &taosTypes.ColumnType{Type: taosTypes.TaosBinaryType, MaxLen: 3}, batch.colTypes[2])
+}
+
+func TestSchemaErrors(t *testing.T) {
+	type noTableName struct {
+		Ts  time.Time
+		Tag int32 `taos:"t,tag"`
+	}
+	type unsupported struct {
+		Ts time.Time
+		M  map[string]int
+	}
+	type badOption struct {
+		Ts time.Time
+		V  int32 `taos:"v,nchar"`
+	}
+	type jsonColumn struct {
+		Ts time.Time
+		V  string `taos:"v,json"`
+	}
+	type duplicate struct {
+		Ts time.Time
+		V  int32 `taos:"v"`
+		W  int32 `taos:"V"`
+	}
+	type onlyTableName struct {
+		Name string `taos:",tbname"`
+	}
+	for _, sample := range []interface{}{noTableName{}, unsupported{}, badOption{}, jsonColumn{}, duplicate{}, onlyTableName{}, 1} {
+		_, err := ParseSchema(sample)
+		assert.Error(t, err, "%T", sample)
+	}
+	_, err := NewWriter("t", normal{}, 3)
+	assert.Error(t, err)
+}
+
+func TestWriteErrors(t *testing.T) {
+	w, err := NewWriter("meters", meter{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	_, err = w.Write(&fakeStmt2{}, []normal{{}})
+	assert.Error(t, err)
+	_, err = w.Write(&fakeStmt2{}, []*meter{nil})
+	assert.Error(t, err)
+	_, err = w.Write(&fakeStmt2{}, []meter{{Current: 1}})
+	assert.Error(t, err)
+	_, err = w.Write(struct{}{}, []meter{{Device: "d1"}})
+	assert.Error(t, err)
+
+	type small struct {
+		Ts time.Time
+		V  int8 `taos:"v"`
+	}
+	w, err = NewWriter("t", small{}, common.PrecisionMilliSecond)
+	require.NoError(t, err)
+	_, err = w.Write(&fakeStmt2{}, []small{{V: 1}})
+	assert.NoError(t, err)
+	v, err := convertValue(common.TSDB_DATA_TYPE_TINYINT, reflect.ValueOf(300))
+	assert.Error(t, err)
+	assert.Equal(t, int8(0), v)
+}
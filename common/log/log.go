@@ -0,0 +1,161 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Logger is a leveled logger with alternating key/value pairs, a *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// Nop is a Logger that discards everything, it is the default logger.
+var Nop Logger = nopLogger{}
+
+type loggerHolder struct {
+	logger Logger
+}
+
+var defaultLogger atomic.Value
+
+var slowThreshold int64
+
+func init() {
+	defaultLogger.Store(loggerHolder{logger: Nop})
+}
+
+// SetLogger sets the package-level logger used by connectors that are not configured with a logger,
+// nil restores Nop.
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = Nop
+	}
+	defaultLogger.Store(loggerHolder{logger: logger})
+}
+
+// GetLogger returns the package-level logger.
+func GetLogger() Logger {
+	return defaultLogger.Load().(loggerHolder).logger
+}
+
+// Or returns logger, or the package-level logger if logger is nil.
+func Or(logger Logger) Logger {
+	if logger == nil {
+		return GetLogger()
+	}
+	return logger
+}
+
+// SetSlowThreshold sets the duration above which requests are logged as slow, 0 disables it.
+func SetSlowThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowThreshold, int64(threshold))
+}
+
+// GetSlowThreshold returns the duration above which requests are logged as slow.
+func GetSlowThreshold() time.Duration {
+	return time.Duration(atomic.LoadInt64(&slowThreshold))
+}
+
+// Slow logs a warning when a request started at start took longer than the slow threshold.
+func Slow(logger Logger, action string, reqID uint64, start time.Time) {
+	threshold := GetSlowThreshold()
+	if threshold <= 0 {
+		return
+	}
+	cost := time.Since(start)
+	if cost >= threshold {
+		logger.Warn("slow request", "action", action, "req_id", reqID, "cost", cost)
+	}
+}
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+type textLogger struct {
+	lock  sync.Mutex
+	out   io.Writer
+	level Level
+	now   func() time.Time
+}
+
+// NewTextLogger returns a Logger that writes the records at or above level to out, one line per record:
+//
+//	2024-03-12T10:35:00.000+08:00 WARN slow request action=query req_id=1 cost=2s
+func NewTextLogger(out io.Writer, level Level) Logger {
+	return &textLogger{out: out, level: level, now: time.Now}
+}
+
+func (l *textLogger) Debug(msg string, args ...interface{}) { l.log(LevelDebug, msg, args) }
+func (l *textLogger) Info(msg string, args ...interface{})  { l.log(LevelInfo, msg, args) }
+func (l *textLogger) Warn(msg string, args ...interface{})  { l.log(LevelWarn, msg, args) }
+func (l *textLogger) Error(msg string, args ...interface{}) { l.log(LevelError, msg, args) }
+
+func (l *textLogger) log(level Level, msg string, args []interface{}) {
+	if level < l.level {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(l.now().Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteByte(' ')
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		b.WriteByte(' ')
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, "!BADKEY=%v", args[i])
+			break
+		}
+		fmt.Fprintf(&b, "%v=%s", args[i], formatValue(args[i+1]))
+	}
+	b.WriteByte('\n')
+	l.lock.Lock()
+	_, _ = io.WriteString(l.out, b.String())
+	l.lock.Unlock()
+}
+
+// formatValue quotes values that would be ambiguous in a key=value line
+func formatValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " =\"\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLogger(t *testing.T) {
+	var b bytes.Buffer
+	logger := NewTextLogger(&b, LevelInfo).(*textLogger)
+	logger.now = func() time.Time {
+		return time.Date(2024, 3, 12, 10, 35, 0, 0, time.UTC)
+	}
+	logger.Debug("dropped")
+	logger.Info("connected", "addr", "127.0.0.1:6041", "req_id", uint64(1))
+	logger.Error("query failed", "error", errors.New("syntax error"), "sql", "")
+	logger.Warn("odd", "key")
+	assert.Equal(t, "2024-03-12T10:35:00.000Z INFO connected addr=127.0.0.1:6041 req_id=1\n"+
+		"2024-03-12T10:35:00.000Z ERROR query failed error=\"syntax error\" sql=\"\"\n"+
+		"2024-03-12T10:35:00.000Z WARN odd !BADKEY=key\n", b.String())
+}
+
+type recordLogger struct {
+	nopLogger
+	warns []string
+}
+
+func (l *recordLogger) Warn(msg string, _ ...interface{}) {
+	l.warns = append(l.warns, msg)
+}
+
+func TestPackageLogger(t *testing.T) {
+	defer SetLogger(nil)
+	assert.Equal(t, Nop, GetLogger())
+	logger := &recordLogger{}
+	SetLogger(logger)
+	assert.Equal(t, logger, GetLogger())
+	assert.Equal(t, logger, Or(nil))
+	assert.Equal(t, Nop, Or(Nop))
+	SetLogger(nil)
+	assert.Equal(t, Nop, GetLogger())
+}
+
+func TestSlow(t *testing.T) {
+	defer SetSlowThreshold(0)
+	logger := &recordLogger{}
+	Slow(logger, "query", 1, time.Now().Add(-time.Hour))
+	assert.Empty(t, logger.warns)
+	SetSlowThreshold(time.Second)
+	assert.Equal(t, time.Second, GetSlowThreshold())
+	Slow(logger, "query", 1, time.Now())
+	assert.Empty(t, logger.warns)
+	Slow(logger, "query", 1, time.Now().Add(-2*time.Second))
+	assert.Equal(t, []string{"slow request"}, logger.warns)
+}
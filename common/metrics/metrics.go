@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// Kinds of the observed requests.
+const (
+	KindQuery     = "query"
+	KindTMQPoll   = "tmq.poll"
+	KindTMQCommit = "tmq.commit"
+)
+
+// Pools of which the waits are observed.
+const (
+	// PoolLocker is the thread locker that limits the concurrent calls into the client library
+	PoolLocker = "locker"
+	// PoolHandler is the pool of the handlers of asynchronous calls
+	PoolHandler = "handler"
+)
+
+// Metrics receives the measurements of all connectors, it must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest records a finished request of kind, err is nil if it succeeded.
+	ObserveRequest(kind string, latency time.Duration, err error)
+	// AddFetched records rows and bytes fetched from the server.
+	AddFetched(rows int, bytes int)
+	// AddReconnect records a successful websocket reconnection.
+	AddReconnect()
+	// AddSendQueue adds delta to the number of messages queued for websocket write pumps.
+	AddSendQueue(delta int)
+	// ObservePoolWait records the time spent waiting for a saturated pool.
+	ObservePoolWait(pool string, wait time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, time.Duration, error) {}
+func (nopMetrics) AddFetched(int, int)                         {}
+func (nopMetrics) AddReconnect()                               {}
+func (nopMetrics) AddSendQueue(int)                            {}
+func (nopMetrics) ObservePoolWait(string, time.Duration)       {}
+
+// Nop is a Metrics that records nothing, it is the default metrics.
+var Nop Metrics = nopMetrics{}
+
+type metricsHolder struct {
+	metrics Metrics
+}
+
+var global atomic.Value
+
+func init() {
+	global.Store(metricsHolder{metrics: Nop})
+}
+
+// SetMetrics sets the metrics of all connectors, nil restores Nop.
+func SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = Nop
+	}
+	global.Store(metricsHolder{metrics: metrics})
+}
+
+// GetMetrics returns the metrics set by SetMetrics.
+func GetMetrics() Metrics {
+	return global.Load().(metricsHolder).metrics
+}
+
+// DefaultBuckets are the upper bounds of the latency buckets of NewMemory.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts latencies in buckets, Counts[i] is the number of latencies in (Bounds[i-1], Bounds[i]]
+// and the last count is the number of latencies above the last bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Sum    time.Duration
+}
+
+// RequestStats are the measurements of a kind of requests.
+type RequestStats struct {
+	Count   uint64
+	Errors  uint64
+	Latency Histogram
+}
+
+// PoolWaitStats are the waits for a pool.
+type PoolWaitStats struct {
+	Count uint64
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Snapshot is a copy of the measurements of a Memory.
+type Snapshot struct {
+	// Requests by kind
+	Requests map[string]RequestStats
+	// ErrorsByCode counts the failed requests by TaosError.Code, other errors are only counted in RequestStats.Errors
+	ErrorsByCode map[int32]uint64
+	Rows         uint64
+	Bytes        uint64
+	Reconnects   uint64
+	// SendQueue is the number of messages queued for websocket write pumps
+	SendQueue int64
+	// SentMessages is the number of messages queued since the Memory is created
+	SentMessages uint64
+	PoolWaits    map[string]PoolWaitStats
+}
+
+// Memory is a Metrics that keeps the measurements in memory.
+type Memory struct {
+	buckets      []time.Duration
+	rows         uint64
+	bytes        uint64
+	reconnects   uint64
+	sendQueue    int64
+	sentMessages uint64
+	lock         sync.Mutex
+	requests     map[string]*RequestStats
+	errorsByCode map[int32]uint64
+	poolWaits    map[string]*PoolWaitStats
+}
+
+// NewMemory returns a Memory with latency buckets of the upper bounds buckets, DefaultBuckets is used if buckets is empty.
+func NewMemory(buckets ...time.Duration) *Memory {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := make([]time.Duration, len(buckets))
+	copy(bounds, buckets)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return &Memory{
+		buckets:      bounds,
+		requests:     map[string]*RequestStats{},
+		errorsByCode: map[int32]uint64{},
+		poolWaits:    map[string]*PoolWaitStats{},
+	}
+}
+
+func (m *Memory) ObserveRequest(kind string, latency time.Duration, err error) {
+	bucket := sort.Search(len(m.buckets), func(i int) bool { return latency <= m.buckets[i] })
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stats := m.requests[kind]
+	if stats == nil {
+		stats = &RequestStats{Latency: Histogram{Bounds: m.buckets, Counts: make([]uint64, len(m.buckets)+1)}}
+		m.requests[kind] = stats
+	}
+	stats.Count++
+	stats.Latency.Counts[bucket]++
+	stats.Latency.Sum += latency
+	if err != nil {
+		stats.Errors++
+		var taosErr *taosErrors.TaosError
+		if errors.As(err, &taosErr) {
+			m.errorsByCode[taosErr.Code]++
+		}
+	}
+}
+
+func (m *Memory) AddFetched(rows int, bytes int) {
+	atomic.AddUint64(&m.rows, uint64(rows))
+	atomic.AddUint64(&m.bytes, uint64(bytes))
+}
+
+func (m *Memory) AddReconnect() {
+	atomic.AddUint64(&m.reconnects, 1)
+}
+
+func (m *Memory) AddSendQueue(delta int) {
+	atomic.AddInt64(&m.sendQueue, int64(delta))
+	if delta > 0 {
+		atomic.AddUint64(&m.sentMessages, uint64(delta))
+	}
+}
+
+func (m *Memory) ObservePoolWait(pool string, wait time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stats := m.poolWaits[pool]
+	if stats == nil {
+		stats = &PoolWaitStats{}
+		m.poolWaits[pool] = stats
+	}
+	stats.Count++
+	stats.Total += wait
+	if wait > stats.Max {
+		stats.Max = wait
+	}
+}
+
+// Snapshot returns a copy of the measurements.
+func (m *Memory) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Rows:         atomic.LoadUint64(&m.rows),
+		Bytes:        atomic.LoadUint64(&m.bytes),
+		Reconnects:   atomic.LoadUint64(&m.reconnects),
+		SendQueue:    atomic.LoadInt64(&m.sendQueue),
+		SentMessages: atomic.LoadUint64(&m.sentMessages),
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	snapshot.Requests = make(map[string]RequestStats, len(m.requests))
+	for kind, stats := range m.requests {
+		counts := make([]uint64, len(stats.Latency.Counts))
+		copy(counts, stats.Latency.Counts)
+		copied := *stats
+		copied.Latency.Counts = counts
+		snapshot.Requests[kind] = copied
+	}
+	snapshot.ErrorsByCode = make(map[int32]uint64, len(m.errorsByCode))
+	for code, count := range m.errorsByCode {
+		snapshot.ErrorsByCode[code] = count
+	}
+	snapshot.PoolWaits = make(map[string]PoolWaitStats, len(m.poolWaits))
+	for pool, stats := range m.poolWaits {
+		snapshot.PoolWaits[pool] = *stats
+	}
+	return snapshot
+}
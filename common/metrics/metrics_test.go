@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(100*time.Millisecond, 10*time.Millisecond)
+	m.ObserveRequest(KindQuery, 5*time.Millisecond, nil)
+	m.ObserveRequest(KindQuery, 10*time.Millisecond, nil)
+	m.ObserveRequest(KindQuery, 50*time.Millisecond, taosErrors.NewError(0x2603, "Table does not exist"))
+	m.ObserveRequest(KindQuery, time.Second, fmt.Errorf("query: %w", taosErrors.NewError(0x2603, "Table does not exist")))
+	m.ObserveRequest(KindTMQPoll, time.Millisecond, errors.New("websocket closed"))
+	m.AddFetched(10, 1024)
+	m.AddFetched(5, 512)
+	m.AddReconnect()
+	m.AddSendQueue(1)
+	m.AddSendQueue(1)
+	m.AddSendQueue(-1)
+	m.ObservePoolWait(PoolLocker, time.Millisecond)
+	m.ObservePoolWait(PoolLocker, 3*time.Millisecond)
+
+	snapshot := m.Snapshot()
+	query := snapshot.Requests[KindQuery]
+	assert.Equal(t, uint64(4), query.Count)
+	assert.Equal(t, uint64(2), query.Errors)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}, query.Latency.Bounds)
+	assert.Equal(t, []uint64{2, 1, 1}, query.Latency.Counts)
+	assert.Equal(t, 1065*time.Millisecond, query.Latency.Sum)
+	poll := snapshot.Requests[KindTMQPoll]
+	assert.Equal(t, uint64(1), poll.Count)
+	assert.Equal(t, uint64(1), poll.Errors)
+	assert.Equal(t, map[int32]uint64{0x2603: 2}, snapshot.ErrorsByCode)
+	assert.Equal(t, uint64(15), snapshot.Rows)
+	assert.Equal(t, uint64(1536), snapshot.Bytes)
+	assert.Equal(t, uint64(1), snapshot.Reconnects)
+	assert.Equal(t, int64(1), snapshot.SendQueue)
+	assert.Equal(t, uint64(2), snapshot.SentMessages)
+	assert.Equal(t, PoolWaitStats{Count: 2, Total: 4 * time.Millisecond, Max: 3 * time.Millisecond}, snapshot.PoolWaits[PoolLocker])
+
+	// the snapshot is a copy
+	m.ObserveRequest(KindQuery, time.Millisecond, nil)
+	assert.Equal(t, []uint64{2, 1, 1}, query.Latency.Counts)
+	assert.Equal(t, uint64(5), m.Snapshot().Requests[KindQuery].Count)
+}
+
+func TestSetMetrics(t *testing.T) {
+	assert.Equal(t, Nop, GetMetrics())
+	m := NewMemory()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+	assert.Equal(t, m, GetMetrics())
+	GetMetrics().ObserveRequest(KindQuery, time.Millisecond, nil)
+	assert.Equal(t, DefaultBuckets, m.Snapshot().Requests[KindQuery].Latency.Bounds)
+	SetMetrics(nil)
+	assert.Equal(t, Nop, GetMetrics())
+}
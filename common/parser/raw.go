@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"unsafe"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/pointer"
+	"github.com/taosdata/driver-go/v3/common/tmq"
 )
 
 type TMQRawDataParser struct {
@@ -182,3 +184,39 @@ func (p *TMQRawDataParser) parseVariableByteInteger() int {
 func zigzagDecode(n int) int {
 	return (n >> 1) ^ (-(n & 1))
 }
+
+// ParseTMQFields reads the column schema of a block parsed by TMQRawDataParser.
+// The names are empty if the block was sent without schema.
+func ParseTMQFields(blockInfo *TMQBlockInfo) []*tmq.Field {
+	colCount := int(RawBlockGetNumOfCols(blockInfo.RawBlock))
+	colInfo := make([]RawBlockColInfo, colCount)
+	RawBlockGetColInfo(blockInfo.RawBlock, colInfo)
+	fields := make([]*tmq.Field, colCount)
+	for i := 0; i < colCount; i++ {
+		field := &tmq.Field{
+			Type:  int(uint8(colInfo[i].ColType)),
+			Bytes: int(colInfo[i].Bytes),
+		}
+		switch field.Type {
+		case common.TSDB_DATA_TYPE_TIMESTAMP:
+			field.Precision = blockInfo.Precision
+		case common.TSDB_DATA_TYPE_DECIMAL, common.TSDB_DATA_TYPE_DECIMAL64:
+			bytes, precision, scale := RawBlockGetDecimalInfo(blockInfo.RawBlock, i)
+			field.Bytes = int(bytes)
+			field.Precision = int(precision)
+			field.Scale = int(scale)
+		}
+		if i < len(blockInfo.Schema) {
+			field.Name = blockInfo.Schema[i].Name
+			if !isDecimal(field.Type) {
+				field.Bytes = int(blockInfo.Schema[i].Bytes)
+			}
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
+func isDecimal(t int) bool {
+	return t == common.TSDB_DATA_TYPE_DECIMAL || t == common.TSDB_DATA_TYPE_DECIMAL64
+}
@@ -8,6 +8,9 @@ import (
 	"unsafe"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/tmq"
 )
 
 func TestParse(t *testing.T) {
@@ -1049,3 +1052,116 @@ func TestVersion100Block(t *testing.T) {
 	}
 	assert.Equal(t, [][]driver.Value{expect}, value)
 }
+
+func TestParseTMQFields(t *testing.T) {
+	data := []byte{
+		0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+
+		0x01,
+		0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+
+		0x01, 0x00, 0x00, 0x00,
+
+		0x01,
+		0x01,
+
+		0xc5, 0x01,
+
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
+
+		0x02,
+
+		0x02, 0x00, 0x00, 0x00,
+		0xb3, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x04, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x80,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x09, 0x08, 0x00, 0x00, 0x00,
+		0x04, 0x04, 0x00, 0x00, 0x00,
+		0x06, 0x04, 0x00, 0x00, 0x00,
+		0x08, 0x82, 0x00, 0x00, 0x00,
+		0x08, 0x00, 0x00, 0x00,
+		0x04, 0x00, 0x00, 0x00,
+		0x04, 0x00, 0x00, 0x00,
+		0x5c, 0x00, 0x00, 0x00,
+
+		0x00,
+		0xc0, 0xed, 0x82, 0x05, 0xc3, 0x1b, 0xab, 0x17,
+
+		0x80,
+		0x00, 0x00, 0x00, 0x00,
+
+		0x80,
+		0x00, 0x00, 0x00, 0x00,
+
+		0x00, 0x00, 0x00, 0x00,
+		0x5a, 0x00,
+		0x61, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34, 0x34,
+		0x34, 0x61,
+
+		0x08,
+		0x00,
+
+		0x09,
+		0x01,
+		0x10,
+		0x02,
+		0x03,
+		0x74, 0x73, 0x00,
+
+		0x04,
+		0x01,
+		0x08,
+		0x04,
+		0x03,
+		0x63, 0x31, 0x00,
+
+		0x06,
+		0x01,
+		0x08,
+		0x06,
+		0x03,
+		0x63, 0x32, 0x00,
+
+		0x08,
+		0x01,
+		0x84, 0x02,
+		0x08,
+		0x03, 0x63, 0x33, 0x00,
+
+		0x05,
+		0x63, 0x74, 0x62, 0x30, 0x00,
+
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	blockInfos, err := NewTMQRawDataParser().Parse(unsafe.Pointer(&data[0]))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(blockInfos))
+	fields := ParseTMQFields(blockInfos[0])
+	assert.Equal(t, []*tmq.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Bytes: 8, Precision: common.PrecisionNanoSecond},
+		{Name: "c1", Type: common.TSDB_DATA_TYPE_INT, Bytes: 4},
+		{Name: "c2", Type: common.TSDB_DATA_TYPE_FLOAT, Bytes: 4},
+		{Name: "c3", Type: common.TSDB_DATA_TYPE_BINARY, Bytes: 130},
+	}, fields)
+	assert.Equal(t, "TIMESTAMP", fields[0].TypeName())
+	assert.Equal(t, "VARCHAR", fields[3].TypeName())
+
+	// without schema only the types are known
+	blockInfos[0].Schema = nil
+	fields = ParseTMQFields(blockInfos[0])
+	assert.Equal(t, "", fields[1].Name)
+	assert.Equal(t, common.TSDB_DATA_TYPE_INT, fields[1].Type)
+}
@@ -36,7 +36,6 @@ func UnmarshalRestfulBodyWithTimezone(body io.Reader, bufferSize int, loc *time.
 	iter := jsonI.BorrowIterator(make([]byte, bufferSize))
 	defer jsonI.ReturnIterator(iter)
 	iter.Reset(body)
-	timeFormat := time.RFC3339Nano
 	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
 		switch s {
 		case "code":
@@ -44,140 +43,12 @@ func UnmarshalRestfulBodyWithTimezone(body io.Reader, bufferSize int, loc *time.
 		case "desc":
 			result.Desc = iter.ReadString()
 		case "column_meta":
-			iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-				index := 0
-				isDecimal := false
-				iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-					switch index {
-					case 0:
-						result.ColNames = append(result.ColNames, iter.ReadString())
-						index = 1
-					case 1:
-						typeStr := iter.ReadString()
-						if strings.HasPrefix(typeStr, "DECIMAL(") {
-							// parse DECIMAL(10,2) to DECIMAL, 10, 2
-							precision, scale, err := parseDecimalType(typeStr)
-							if err != nil {
-								iter.ReportError("parse decimal", err.Error())
-								return false
-							}
-							isDecimal = true
-							result.Precisions = append(result.Precisions, precision)
-							result.Scales = append(result.Scales, scale)
-						} else {
-							t, exist := NameTypeMap[typeStr]
-							if exist {
-								result.ColTypes = append(result.ColTypes, t)
-							} else {
-								iter.ReportError("unsupported type in column_meta", typeStr)
-								return false
-							}
-							result.Precisions = append(result.Precisions, 0)
-							result.Scales = append(result.Scales, 0)
-						}
-						index = 2
-					case 2:
-						colLen := iter.ReadInt64()
-						result.ColLength = append(result.ColLength, colLen)
-						index = 0
-						if isDecimal {
-							switch colLen {
-							case 8:
-								result.ColTypes = append(result.ColTypes, TSDB_DATA_TYPE_DECIMAL64)
-							case 16:
-								result.ColTypes = append(result.ColTypes, TSDB_DATA_TYPE_DECIMAL)
-							default:
-								iter.ReportError("parse decimal", fmt.Sprintf("invalid length %d", colLen))
-								return false
-							}
-						}
-						isDecimal = false
-					}
-					return true
-				})
-				return true
-			})
+			readRestfulColumnMeta(iter, &result)
 		case "data":
 			columnCount := len(result.ColTypes)
-			column := 0
 			iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-				column = 0
 				var row = make([]driver.Value, columnCount)
-				iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-					defer func() {
-						column += 1
-					}()
-					columnType := result.ColTypes[column]
-					if columnType == TSDB_DATA_TYPE_JSON {
-						row[column] = iter.SkipAndReturnBytes()
-						return true
-					}
-					if iter.ReadNil() {
-						row[column] = nil
-						return true
-					}
-					var err error
-					switch columnType {
-					case TSDB_DATA_TYPE_NULL:
-						iter.Skip()
-						row[column] = nil
-					case TSDB_DATA_TYPE_BOOL:
-						row[column] = iter.ReadAny().ToBool()
-					case TSDB_DATA_TYPE_TINYINT:
-						row[column] = iter.ReadInt8()
-					case TSDB_DATA_TYPE_SMALLINT:
-						row[column] = iter.ReadInt16()
-					case TSDB_DATA_TYPE_INT:
-						row[column] = iter.ReadInt32()
-					case TSDB_DATA_TYPE_BIGINT:
-						row[column] = iter.ReadInt64()
-					case TSDB_DATA_TYPE_FLOAT:
-						row[column] = iter.ReadFloat32()
-					case TSDB_DATA_TYPE_DOUBLE:
-						row[column] = iter.ReadFloat64()
-					case TSDB_DATA_TYPE_BINARY:
-						row[column] = iter.ReadString()
-					case TSDB_DATA_TYPE_TIMESTAMP:
-						b := iter.ReadString()
-						var tm time.Time
-						tm, err = time.Parse(timeFormat, b)
-						if err != nil {
-							iter.ReportError("parse time", err.Error())
-							return false
-						}
-						if loc != nil {
-							tm = tm.In(loc)
-						}
-						row[column] = tm
-					case TSDB_DATA_TYPE_NCHAR:
-						row[column] = iter.ReadString()
-					case TSDB_DATA_TYPE_UTINYINT:
-						row[column] = iter.ReadUint8()
-					case TSDB_DATA_TYPE_USMALLINT:
-						row[column] = iter.ReadUint16()
-					case TSDB_DATA_TYPE_UINT:
-						row[column] = iter.ReadUint32()
-					case TSDB_DATA_TYPE_UBIGINT:
-						row[column] = iter.ReadUint64()
-					case TSDB_DATA_TYPE_VARBINARY, TSDB_DATA_TYPE_GEOMETRY, TSDB_DATA_TYPE_BLOB:
-						data := iter.ReadStringAsSlice()
-						if len(data)%2 != 0 {
-							iter.ReportError("read varbinary", fmt.Sprintf("invalid length %s", string(data)))
-							return false
-						}
-						value := make([]byte, len(data)/2)
-						for i := 0; i < len(data); i += 2 {
-							value[i/2] = hexCharToDigit(data[i])<<4 | hexCharToDigit(data[i+1])
-						}
-						row[column] = value
-					case TSDB_DATA_TYPE_DECIMAL, TSDB_DATA_TYPE_DECIMAL64:
-						row[column] = iter.ReadString()
-					default:
-						row[column] = nil
-						iter.Skip()
-					}
-					return iter.Error == nil
-				})
+				readRestfulRow(iter, result.ColTypes, row, loc)
 				if iter.Error != nil {
 					return false
 				}
@@ -197,6 +68,253 @@ func UnmarshalRestfulBodyWithTimezone(body io.Reader, bufferSize int, loc *time.
 	return &result, nil
 }
 
+// RestfulRowReader decodes a restful response row by row while it is being read,
+// the memory used is bounded by the read buffer instead of the result size.
+type RestfulRowReader struct {
+	iter     *jsoniter.Iterator
+	loc      *time.Location
+	result   *TDEngineRestfulResp
+	finished bool
+}
+
+// NewRestfulRowReader reads the response up to the first row, the code, description and
+// column meta are available from Result when it returns.
+func NewRestfulRowReader(body io.Reader, bufferSize int, loc *time.Location) (*RestfulRowReader, error) {
+	iter := jsonI.BorrowIterator(make([]byte, bufferSize))
+	iter.Reset(body)
+	reader := &RestfulRowReader{
+		iter:   iter,
+		loc:    loc,
+		result: &TDEngineRestfulResp{},
+	}
+	if !reader.readFields(true) {
+		reader.finished = true
+	}
+	if err := reader.err(); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// readFields reads the fields of the response object, it stops at the data field and
+// reports whether the data field was reached.
+func (r *RestfulRowReader) readFields(stopAtData bool) bool {
+	iter := r.iter
+	for iter.Error == nil {
+		field := iter.ReadObject()
+		switch field {
+		case "":
+			return false
+		case "code":
+			r.result.Code = iter.ReadInt()
+		case "desc":
+			r.result.Desc = iter.ReadString()
+		case "column_meta":
+			readRestfulColumnMeta(iter, r.result)
+		case "data":
+			if stopAtData {
+				return true
+			}
+			iter.Skip()
+		case "rows":
+			r.result.Rows = iter.ReadInt()
+		default:
+			iter.Skip()
+		}
+	}
+	return false
+}
+
+func (r *RestfulRowReader) err() error {
+	if r.iter.Error != nil && r.iter.Error != io.EOF {
+		return r.iter.Error
+	}
+	return nil
+}
+
+// Result returns the response without data, Rows is set after all rows have been read.
+func (r *RestfulRowReader) Result() *TDEngineRestfulResp {
+	return r.result
+}
+
+// Next decodes the next row into dest, it returns io.EOF after the last row.
+func (r *RestfulRowReader) Next(dest []driver.Value) error {
+	if r.finished {
+		return io.EOF
+	}
+	if !r.iter.ReadArray() {
+		r.finished = true
+		if err := r.err(); err != nil {
+			return err
+		}
+		r.readFields(false)
+		if err := r.err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	readRestfulRow(r.iter, r.result.ColTypes, dest, r.loc)
+	if err := r.err(); err != nil {
+		r.finished = true
+		return err
+	}
+	return nil
+}
+
+// Finished reports whether the whole response has been read.
+func (r *RestfulRowReader) Finished() bool {
+	return r.finished
+}
+
+// Close returns the read buffer, the reader can not be used after Close.
+func (r *RestfulRowReader) Close() {
+	if r.iter != nil {
+		jsonI.ReturnIterator(r.iter)
+		r.iter = nil
+		r.finished = true
+	}
+}
+
+func readRestfulColumnMeta(iter *jsoniter.Iterator, result *TDEngineRestfulResp) {
+	iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+		index := 0
+		isDecimal := false
+		iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+			switch index {
+			case 0:
+				result.ColNames = append(result.ColNames, iter.ReadString())
+				index = 1
+			case 1:
+				typeStr := iter.ReadString()
+				if strings.HasPrefix(typeStr, "DECIMAL(") {
+					// parse DECIMAL(10,2) to DECIMAL, 10, 2
+					precision, scale, err := parseDecimalType(typeStr)
+					if err != nil {
+						iter.ReportError("parse decimal", err.Error())
+						return false
+					}
+					isDecimal = true
+					result.Precisions = append(result.Precisions, precision)
+					result.Scales = append(result.Scales, scale)
+				} else {
+					t, exist := NameTypeMap[typeStr]
+					if exist {
+						result.ColTypes = append(result.ColTypes, t)
+					} else {
+						iter.ReportError("unsupported type in column_meta", typeStr)
+						return false
+					}
+					result.Precisions = append(result.Precisions, 0)
+					result.Scales = append(result.Scales, 0)
+				}
+				index = 2
+			case 2:
+				colLen := iter.ReadInt64()
+				result.ColLength = append(result.ColLength, colLen)
+				index = 0
+				if isDecimal {
+					switch colLen {
+					case 8:
+						result.ColTypes = append(result.ColTypes, TSDB_DATA_TYPE_DECIMAL64)
+					case 16:
+						result.ColTypes = append(result.ColTypes, TSDB_DATA_TYPE_DECIMAL)
+					default:
+						iter.ReportError("parse decimal", fmt.Sprintf("invalid length %d", colLen))
+						return false
+					}
+				}
+				isDecimal = false
+			}
+			return true
+		})
+		return true
+	})
+}
+
+// readRestfulRow reads one row array into row, errors are reported on the iterator.
+func readRestfulRow(iter *jsoniter.Iterator, colTypes []int, row []driver.Value, loc *time.Location) {
+	column := 0
+	iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+		defer func() {
+			column += 1
+		}()
+		if column >= len(colTypes) || column >= len(row) {
+			iter.Skip()
+			return iter.Error == nil
+		}
+		row[column] = readRestfulValue(iter, colTypes[column], loc)
+		return iter.Error == nil
+	})
+}
+
+func readRestfulValue(iter *jsoniter.Iterator, columnType int, loc *time.Location) driver.Value {
+	if columnType == TSDB_DATA_TYPE_JSON {
+		return iter.SkipAndReturnBytes()
+	}
+	if iter.ReadNil() {
+		return nil
+	}
+	switch columnType {
+	case TSDB_DATA_TYPE_NULL:
+		iter.Skip()
+		return nil
+	case TSDB_DATA_TYPE_BOOL:
+		return iter.ReadAny().ToBool()
+	case TSDB_DATA_TYPE_TINYINT:
+		return iter.ReadInt8()
+	case TSDB_DATA_TYPE_SMALLINT:
+		return iter.ReadInt16()
+	case TSDB_DATA_TYPE_INT:
+		return iter.ReadInt32()
+	case TSDB_DATA_TYPE_BIGINT:
+		return iter.ReadInt64()
+	case TSDB_DATA_TYPE_FLOAT:
+		return iter.ReadFloat32()
+	case TSDB_DATA_TYPE_DOUBLE:
+		return iter.ReadFloat64()
+	case TSDB_DATA_TYPE_BINARY:
+		return iter.ReadString()
+	case TSDB_DATA_TYPE_TIMESTAMP:
+		b := iter.ReadString()
+		tm, err := time.Parse(time.RFC3339Nano, b)
+		if err != nil {
+			iter.ReportError("parse time", err.Error())
+			return nil
+		}
+		if loc != nil {
+			tm = tm.In(loc)
+		}
+		return tm
+	case TSDB_DATA_TYPE_NCHAR:
+		return iter.ReadString()
+	case TSDB_DATA_TYPE_UTINYINT:
+		return iter.ReadUint8()
+	case TSDB_DATA_TYPE_USMALLINT:
+		return iter.ReadUint16()
+	case TSDB_DATA_TYPE_UINT:
+		return iter.ReadUint32()
+	case TSDB_DATA_TYPE_UBIGINT:
+		return iter.ReadUint64()
+	case TSDB_DATA_TYPE_VARBINARY, TSDB_DATA_TYPE_GEOMETRY, TSDB_DATA_TYPE_BLOB:
+		data := iter.ReadStringAsSlice()
+		if len(data)%2 != 0 {
+			iter.ReportError("read varbinary", fmt.Sprintf("invalid length %s", string(data)))
+			return nil
+		}
+		value := make([]byte, len(data)/2)
+		for i := 0; i < len(data); i += 2 {
+			value[i/2] = hexCharToDigit(data[i])<<4 | hexCharToDigit(data[i+1])
+		}
+		return value
+	case TSDB_DATA_TYPE_DECIMAL, TSDB_DATA_TYPE_DECIMAL64:
+		return iter.ReadString()
+	default:
+		iter.Skip()
+		return nil
+	}
+}
+
 func hexCharToDigit(char byte) uint8 {
 	switch {
 	case char >= '0' && char <= '9':
@@ -575,3 +575,103 @@ func TestUnmarshalRestfulBodyWithTimezone(t *testing.T) {
 		})
 	}
 }
+
+func TestRestfulRowReader(t *testing.T) {
+	body := `{"code":0,"column_meta":[["ts","TIMESTAMP",8],["c1","BOOL",1],["c2","INT",4],["c3","VARCHAR",20],["c4","VARBINARY",20],["c5","DECIMAL(10,4)",8],["info","JSON",4095]],"data":[["2025-03-20T09:11:11.634Z",true,1,"test_binary","76617262696e617279","1234.5600",{"a":1}],["2025-03-20T09:11:12.634Z",null,null,null,null,null,null]],"rows":2}`
+	want, err := UnmarshalRestfulBody(strings.NewReader(body), 1024)
+	require.NoError(t, err)
+	for _, bufferSize := range []int{8, 16, 1024} {
+		t.Run(fmt.Sprintf("buffer %d", bufferSize), func(t *testing.T) {
+			reader, err := NewRestfulRowReader(strings.NewReader(body), bufferSize, nil)
+			require.NoError(t, err)
+			defer reader.Close()
+			result := reader.Result()
+			assert.Equal(t, want.ColNames, result.ColNames)
+			assert.Equal(t, want.ColTypes, result.ColTypes)
+			assert.Equal(t, want.ColLength, result.ColLength)
+			assert.Equal(t, want.Precisions, result.Precisions)
+			assert.Equal(t, want.Scales, result.Scales)
+			var data [][]driver.Value
+			for {
+				row := make([]driver.Value, len(result.ColNames))
+				err = reader.Next(row)
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				data = append(data, row)
+			}
+			assert.Equal(t, want.Data, data)
+			assert.True(t, reader.Finished())
+			assert.Equal(t, 2, result.Rows)
+			assert.Equal(t, io.EOF, reader.Next(make([]driver.Value, len(result.ColNames))))
+		})
+	}
+}
+
+func TestRestfulRowReaderError(t *testing.T) {
+	reader, err := NewRestfulRowReader(strings.NewReader(`{"code":9750,"desc":"Table does not exist"}`), 1024, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, 9750, reader.Result().Code)
+	assert.Equal(t, "Table does not exist", reader.Result().Desc)
+	assert.True(t, reader.Finished())
+
+	_, err = NewRestfulRowReader(strings.NewReader(`{"code":0,"column_meta":[["ts","UNKNOWN",8]]}`), 1024, nil)
+	assert.Error(t, err)
+
+	reader, err = NewRestfulRowReader(strings.NewReader(`{"code":0,"column_meta":[["ts","TIMESTAMP",8]],"data":[["wrong"]],"rows":1}`), 1024, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	err = reader.Next(make([]driver.Value, 1))
+	assert.Error(t, err)
+	assert.NotEqual(t, io.EOF, err)
+}
+
+type repeatRowsReader struct {
+	rows   int
+	state  int
+	buffer []byte
+}
+
+func (r *repeatRowsReader) Read(p []byte) (int, error) {
+	if len(r.buffer) == 0 {
+		switch {
+		case r.state == 0:
+			r.buffer = []byte(`{"code":0,"column_meta":[["ts","TIMESTAMP",8],["v","VARCHAR",20]],"data":[`)
+		case r.state <= r.rows:
+			r.buffer = []byte(`["2025-03-20T09:11:11.634Z","value"]`)
+			if r.state != r.rows {
+				r.buffer = append(r.buffer, ',')
+			}
+		case r.state == r.rows+1:
+			r.buffer = []byte(fmt.Sprintf(`],"rows":%d}`, r.rows))
+		default:
+			return 0, io.EOF
+		}
+		r.state += 1
+	}
+	n := copy(p, r.buffer)
+	r.buffer = r.buffer[n:]
+	return n, nil
+}
+
+func TestRestfulRowReaderLargeResult(t *testing.T) {
+	rows := 100000
+	reader, err := NewRestfulRowReader(&repeatRowsReader{rows: rows}, 4096, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+	row := make([]driver.Value, 2)
+	count := 0
+	for {
+		err = reader.Next(row)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count += 1
+	}
+	assert.Equal(t, rows, count)
+	assert.Equal(t, rows, reader.Result().Rows)
+	assert.Equal(t, "value", row[1])
+}
@@ -0,0 +1,435 @@
+// Package scan maps query results onto go structs.
+//
+// Struct fields are matched with columns by the `taos` tag, fields without the tag
+// are matched by their name case-insensitively and fields tagged with `taos:"-"` are ignored.
+// Options follow the column name separated by commas:
+//
+//	json    decode a JSON column (or any []byte/string column) with encoding/json
+//	ms/us/ns unit of a timestamp scanned into an integer field, the default is ms
+//
+// Columns without a matching field are skipped, fields without a matching column keep their zero value.
+// Fields can be of the column's go type, a convertible type, a pointer to them, or a sql.Scanner
+// such as the nullable types in the types package.
+package scan
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const tagName = "taos"
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+type field struct {
+	index []int
+	json  bool
+	unit  time.Duration
+}
+
+type structInfo struct {
+	// several fields can read the same column with different options
+	fields map[string][]*field
+}
+
+var structCache sync.Map
+
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if info, ok := structCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+	info := &structInfo{fields: make(map[string][]*field)}
+	err := parseStruct(t, nil, info)
+	if err != nil {
+		return nil, err
+	}
+	structCache.Store(t, info)
+	return info, nil
+}
+
+func parseStruct(t reflect.Type, parent []int, info *structInfo) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+		embedded := f.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if f.Anonymous && !hasTag && embedded.Kind() == reflect.Struct {
+			err := parseStruct(embedded, index, info)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := f.Name
+		options := ""
+		if hasTag {
+			name = tag
+			if comma := strings.IndexByte(tag, ','); comma != -1 {
+				name, options = tag[:comma], tag[comma+1:]
+			}
+			if len(name) == 0 {
+				name = f.Name
+			}
+		}
+		fi := &field{index: index, unit: time.Millisecond}
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case "":
+			case "json":
+				fi.json = true
+			case "ms":
+				fi.unit = time.Millisecond
+			case "us":
+				fi.unit = time.Microsecond
+			case "ns":
+				fi.unit = time.Nanosecond
+			default:
+				return fmt.Errorf("scan: unknown option %s of field %s", option, f.Name)
+			}
+		}
+		key := strings.ToLower(name)
+		exist := info.fields[key]
+		switch {
+		case len(exist) == 0 || len(exist[0].index) == len(index):
+			info.fields[key] = append(exist, fi)
+		case len(exist[0].index) > len(index):
+			// the shallower field wins like go field selectors
+			info.fields[key] = []*field{fi}
+		}
+	}
+	return nil
+}
+
+// Mapper assigns rows with fixed columns to structs of one type.
+type Mapper struct {
+	structType reflect.Type
+	fields     [][]*field
+}
+
+// NewMapper creates a Mapper for the columns and the struct type of dest,
+// dest is a struct, a pointer to a struct or a pointer to a slice of them.
+func NewMapper(columns []string, dest interface{}) (*Mapper, error) {
+	t := reflect.TypeOf(dest)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("scan: dest must be a struct or a slice of structs, got %T", dest)
+	}
+	info, err := getStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mapper{structType: t, fields: make([][]*field, len(columns))}
+	for i, column := range columns {
+		m.fields[i] = info.fields[strings.ToLower(column)]
+	}
+	return m, nil
+}
+
+// Assign sets the fields of the struct that dest points to from one row.
+func (m *Mapper) Assign(values []driver.Value, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Type() != m.structType {
+		return fmt.Errorf("scan: dest must be a non-nil *%s, got %T", m.structType, dest)
+	}
+	return m.assign(values, v.Elem())
+}
+
+func (m *Mapper) assign(values []driver.Value, v reflect.Value) error {
+	if len(values) != len(m.fields) {
+		return fmt.Errorf("scan: expected %d values, got %d", len(m.fields), len(values))
+	}
+	for i, fields := range m.fields {
+		for _, f := range fields {
+			err := assignValue(fieldByIndex(v, f.index), values[i], f)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Row assigns one row to the struct that dest points to.
+func Row(columns []string, values []driver.Value, dest interface{}) error {
+	m, err := NewMapper(columns, dest)
+	if err != nil {
+		return err
+	}
+	return m.Assign(values, dest)
+}
+
+// All reads the remaining rows into dest, a pointer to a slice of structs or struct pointers.
+// rows can be returned by any driver of this module, including af, rows are not closed.
+func All(rows driver.Rows, dest interface{}) error {
+	columns := rows.Columns()
+	values := make([]driver.Value, len(columns))
+	return scanAll(columns, dest, func() error {
+		return rows.Next(values)
+	}, func() []driver.Value {
+		return values
+	})
+}
+
+// SQLRows reads the remaining rows of database/sql rows into dest, a pointer to a slice of structs or struct pointers.
+// rows are not closed.
+func SQLRows(rows *sql.Rows, dest interface{}) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	holders := make([]interface{}, len(columns))
+	scanned := make([]interface{}, len(columns))
+	values := make([]driver.Value, len(columns))
+	for i := range holders {
+		holders[i] = &scanned[i]
+	}
+	return scanAll(columns, dest, func() error {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+		err := rows.Scan(holders...)
+		if err != nil {
+			return err
+		}
+		for i, v := range scanned {
+			values[i] = v
+		}
+		return nil
+	}, func() []driver.Value {
+		return values
+	})
+}
+
+// Values appends rows, values in the order of columns, to dest, a pointer to a slice of structs or struct pointers.
+func Values(columns []string, rows [][]driver.Value, dest interface{}) error {
+	i := -1
+	return scanAll(columns, dest, func() error {
+		i++
+		if i == len(rows) {
+			return io.EOF
+		}
+		return nil
+	}, func() []driver.Value {
+		return rows[i]
+	})
+}
+
+func scanAll(columns []string, dest interface{}, next func() error, values func() []driver.Value) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	m, err := NewMapper(columns, dest)
+	if err != nil {
+		return err
+	}
+	for {
+		err = next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(m.structType)
+		err = m.assign(values(), elem.Elem())
+		if err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex allocating nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func assignValue(dest reflect.Value, value driver.Value, f *field) error {
+	if dest.CanAddr() && dest.Addr().Type().Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+	if value == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Ptr {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		return assignValue(dest.Elem(), value, f)
+	}
+	if f.json {
+		var data []byte
+		switch v := value.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			return fmt.Errorf("scan: can not decode %T as json", value)
+		}
+		return json.Unmarshal(data, dest.Addr().Interface())
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return assignTime(dest, v, f.unit)
+	case []byte:
+		switch {
+		case dest.Kind() == reflect.String:
+			dest.SetString(string(v))
+			return nil
+		case dest.Kind() == reflect.Slice && dest.Type().Elem().Kind() == reflect.Uint8:
+			// the driver may reuse the buffer
+			b := reflect.MakeSlice(dest.Type(), len(v), len(v))
+			reflect.Copy(b, reflect.ValueOf(v))
+			dest.Set(b)
+			return nil
+		}
+	case string:
+		switch dest.Kind() {
+		case reflect.String:
+			dest.SetString(v)
+			return nil
+		case reflect.Slice:
+			if dest.Type().Elem().Kind() == reflect.Uint8 {
+				dest.SetBytes([]byte(v))
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			// decimal
+			f, err := strconv.ParseFloat(v, dest.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("scan: %s", err)
+			}
+			dest.SetFloat(f)
+			return nil
+		}
+	}
+	return assignNumber(dest, reflect.ValueOf(value))
+}
+
+func assignTime(dest reflect.Value, t time.Time, unit time.Duration) error {
+	switch {
+	case dest.Type() == timeType:
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	case dest.Kind() == reflect.Int64 || dest.Kind() == reflect.Int:
+		dest.SetInt(t.UnixNano() / int64(unit))
+		return nil
+	case dest.Kind() == reflect.String:
+		dest.SetString(t.Format(time.RFC3339Nano))
+		return nil
+	case dest.Type().ConvertibleTo(timeType) && timeType.ConvertibleTo(dest.Type()):
+		dest.Set(reflect.ValueOf(t).Convert(dest.Type()))
+		return nil
+	}
+	return fmt.Errorf("scan: can not assign time.Time to %s", dest.Type())
+}
+
+func assignNumber(dest reflect.Value, src reflect.Value) error {
+	switch src.Kind() {
+	case reflect.Bool:
+		if dest.Kind() == reflect.Bool {
+			dest.SetBool(src.Bool())
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := src.Int()
+		switch dest.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if dest.OverflowInt(i) {
+				return overflowError(src, dest)
+			}
+			dest.SetInt(i)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i < 0 || dest.OverflowUint(uint64(i)) {
+				return overflowError(src, dest)
+			}
+			dest.SetUint(uint64(i))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(float64(i))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := src.Uint()
+		switch dest.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if u > 1<<63-1 || dest.OverflowInt(int64(u)) {
+				return overflowError(src, dest)
+			}
+			dest.SetInt(int64(u))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if dest.OverflowUint(u) {
+				return overflowError(src, dest)
+			}
+			dest.SetUint(u)
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(float64(u))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch dest.Kind() {
+		case reflect.Float32, reflect.Float64:
+			dest.SetFloat(src.Float())
+			return nil
+		}
+	}
+	if src.Type().AssignableTo(dest.Type()) {
+		dest.Set(src)
+		return nil
+	}
+	return fmt.Errorf("scan: can not assign %s to %s", src.Type(), dest.Type())
+}
+
+func overflowError(src reflect.Value, dest reflect.Value) error {
+	return fmt.Errorf("scan: value %v overflows %s", src.Interface(), dest.Type())
+}
@@ -0,0 +1,237 @@
+package scan
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+type testRows struct {
+	columns []string
+	data    [][]driver.Value
+	index   int
+}
+
+func (r *testRows) Columns() []string {
+	return r.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.index])
+	r.index += 1
+	return nil
+}
+
+type Base struct {
+	TS       time.Time `taos:"ts"`
+	TSMicro  int64     `taos:"ts,us"`
+	Location string
+}
+
+type meter struct {
+	Base
+	Current  float32           `taos:"current"`
+	Voltage  *int32            `taos:"voltage"`
+	Phase    types.NullFloat64 `taos:"phase"`
+	GroupID  uint8             `taos:"group_id"`
+	Info     map[string]int    `taos:"info,json"`
+	RawInfo  types.RawMessage  `taos:"info"`
+	Geometry []byte            `taos:"geo"`
+	Price    float64           `taos:"price"`
+	PriceStr string            `taos:"price"`
+	Flag     sql.NullBool      `taos:"flag"`
+	Ignored  string            `taos:"-"`
+}
+
+func newMeterRows(now time.Time) *testRows {
+	return &testRows{
+		columns: []string{"ts", "location", "current", "voltage", "phase", "group_id", "info", "geo", "price", "flag", "unknown"},
+		data: [][]driver.Value{
+			{now, "California", float32(10.2), int32(219), float64(0.31), int32(2), []byte(`{"a":1}`), []byte{0x01, 0x02}, "12.3400", true, "x"},
+			{now.Add(time.Second), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+		},
+	}
+}
+
+func TestAll(t *testing.T) {
+	now := time.Now()
+	rows := newMeterRows(now)
+	var result []meter
+	err := All(rows, &result)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(result))
+	voltage := int32(219)
+	assert.Equal(t, meter{
+		Base:     Base{TS: now, TSMicro: now.UnixNano() / 1e3, Location: "California"},
+		Current:  10.2,
+		Voltage:  &voltage,
+		Phase:    types.NullFloat64{Inner: 0.31, Valid: true},
+		GroupID:  2,
+		Info:     map[string]int{"a": 1},
+		RawInfo:  types.RawMessage(`{"a":1}`),
+		Geometry: []byte{0x01, 0x02},
+		Price:    12.34,
+		PriceStr: "12.3400",
+		Flag:     sql.NullBool{Bool: true, Valid: true},
+	}, result[0])
+	assert.Equal(t, meter{
+		Base: Base{TS: now.Add(time.Second), TSMicro: now.Add(time.Second).UnixNano() / 1e3},
+	}, result[1])
+
+	var pointers []*meter
+	err = All(newMeterRows(now), &pointers)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(pointers))
+	assert.Equal(t, result[0], *pointers[0])
+}
+
+func TestRow(t *testing.T) {
+	now := time.Now()
+	var m meter
+	err := Row([]string{"ts", "current"}, []driver.Value{now, float32(1)}, &m)
+	require.NoError(t, err)
+	assert.Equal(t, now, m.TS)
+	assert.Equal(t, now.UnixNano()/1e3, m.TSMicro)
+	assert.Equal(t, float32(1), m.Current)
+
+	mapper, err := NewMapper([]string{"ts"}, m)
+	require.NoError(t, err)
+	assert.Error(t, mapper.Assign([]driver.Value{now}, m))
+	assert.Error(t, mapper.Assign([]driver.Value{now, now}, &m))
+}
+
+func TestValues(t *testing.T) {
+	now := time.Now()
+	var meters []meter
+	err := Values([]string{"current", "ts"}, [][]driver.Value{{float32(1), now}, {float32(2), now}}, &meters)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(meters))
+	assert.Equal(t, now, meters[1].TS)
+	assert.Equal(t, float32(2), meters[1].Current)
+	err = Values([]string{"current"}, [][]driver.Value{{float32(3)}}, &meters)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(meters))
+}
+
+func TestTimestampUnit(t *testing.T) {
+	type ts struct {
+		MS    int64  `taos:"ts"`
+		US    int64  `taos:"ts,us"`
+		NS    int64  `taos:"ts,ns"`
+		Str   string `taos:"ts"`
+		Local *time.Time
+	}
+	now := time.Unix(1700000000, 123456789)
+	var result []ts
+	err := All(&testRows{columns: []string{"ts", "local"}, data: [][]driver.Value{{now, now}}}, &result)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(result))
+	assert.Equal(t, int64(1700000000123), result[0].MS)
+	assert.Equal(t, int64(1700000000123456), result[0].US)
+	assert.Equal(t, int64(1700000000123456789), result[0].NS)
+	assert.Equal(t, now.Format(time.RFC3339Nano), result[0].Str)
+	assert.Equal(t, now, *result[0].Local)
+}
+
+func TestErrors(t *testing.T) {
+	var notSlice meter
+	assert.Error(t, All(newMeterRows(time.Now()), &notSlice))
+	var ints []int
+	assert.Error(t, All(newMeterRows(time.Now()), &ints))
+
+	type overflow struct {
+		V int8
+	}
+	var o []overflow
+	assert.Error(t, All(&testRows{columns: []string{"v"}, data: [][]driver.Value{{int32(1000)}}}, &o))
+	type negative struct {
+		V uint32
+	}
+	var n []negative
+	assert.Error(t, All(&testRows{columns: []string{"v"}, data: [][]driver.Value{{int64(-1)}}}, &n))
+	type mismatch struct {
+		V bool
+	}
+	var m []mismatch
+	assert.Error(t, All(&testRows{columns: []string{"v"}, data: [][]driver.Value{{"abc"}}}, &m))
+	type badOption struct {
+		V int `taos:"v,unknown"`
+	}
+	var b []badOption
+	assert.Error(t, All(&testRows{columns: []string{"v"}}, &b))
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) {
+	return testConn{}, nil
+}
+
+type testConn struct{}
+
+func (testConn) Prepare(string) (driver.Stmt, error) {
+	return testStmt{}, nil
+}
+
+func (testConn) Close() error {
+	return nil
+}
+
+func (testConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type testStmt struct{}
+
+func (testStmt) Close() error {
+	return nil
+}
+
+func (testStmt) NumInput() int {
+	return 0
+}
+
+func (testStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (testStmt) Query([]driver.Value) (driver.Rows, error) {
+	return newMeterRows(time.Unix(1700000000, 0)), nil
+}
+
+func TestSQLRows(t *testing.T) {
+	sql.Register("scan_test", testDriver{})
+	db, err := sql.Open("scan_test", "")
+	require.NoError(t, err)
+	defer func() {
+		err = db.Close()
+		assert.NoError(t, err)
+	}()
+	rows, err := db.Query("select * from meters")
+	require.NoError(t, err)
+	defer func() {
+		err = rows.Close()
+		assert.NoError(t, err)
+	}()
+	var result []meter
+	err = SQLRows(rows, &result)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(result))
+	assert.Equal(t, time.Unix(1700000000, 0), result[0].TS)
+	assert.Equal(t, map[string]int{"a": 1}, result[0].Info)
+	assert.Equal(t, float32(10.2), result[0].Current)
+	assert.Nil(t, result[1].Voltage)
+}
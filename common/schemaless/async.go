@@ -0,0 +1,325 @@
+// Package schemaless contains the schemaless helpers shared by the native and websocket connections.
+package schemaless
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	InfluxDBLineProtocol       = 1
+	OpenTSDBTelnetLineProtocol = 2
+	OpenTSDBJsonFormatProtocol = 3
+)
+
+//revive:disable
+var ErrWriterClosed = errors.New("async writer closed")
+
+//revive:enable
+
+// InsertFunc inserts a batch of lines with the request id, lines are separated by '\n',
+// batches of the OpenTSDB JSON protocol are a JSON array.
+type InsertFunc func(lines string, reqID int64) error
+
+// BatchError is passed to the error handler when a batch failed.
+type BatchError struct {
+	Lines []string
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+type AsyncConfig struct {
+	maxBatchBytes int
+	maxBatchLines int
+	maxLatency    time.Duration
+	concurrency   int
+	queueSize     int
+	errorHandler  func(*BatchError)
+	reqIDFunc     func() int64
+	precision     string
+}
+
+// SetMaxBatchBytes sets the max size of a batch in bytes, default 1MB. A single line larger than it is sent alone.
+func SetMaxBatchBytes(maxBatchBytes int) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.maxBatchBytes = maxBatchBytes
+	}
+}
+
+// SetMaxBatchLines sets the max lines of a batch, default 10000.
+func SetMaxBatchLines(maxBatchLines int) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.maxBatchLines = maxBatchLines
+	}
+}
+
+// SetMaxLatency sets how long a line waits in a batch before the batch is sent, default 1s.
+func SetMaxLatency(maxLatency time.Duration) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.maxLatency = maxLatency
+	}
+}
+
+// SetConcurrency sets the number of batches sent concurrently, default 2.
+func SetConcurrency(concurrency int) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.concurrency = concurrency
+	}
+}
+
+// SetQueueSize sets the number of lines buffered before Write blocks, default 10000.
+func SetQueueSize(queueSize int) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.queueSize = queueSize
+	}
+}
+
+// SetBatchErrorHandler sets the handler called with every failed batch, it is called from the sending goroutines.
+func SetBatchErrorHandler(errorHandler func(*BatchError)) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.errorHandler = errorHandler
+	}
+}
+
+// SetReqIDFunc sets the function generating the request id of each batch, by default the id is 0
+// and the connection generates it.
+func SetReqIDFunc(reqIDFunc func() int64) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.reqIDFunc = reqIDFunc
+	}
+}
+
+// SetPrecision sets the precision of the timestamps of the points written with WritePoint, it is only used by
+// the InfluxDB line protocol. The writers of the connections set it to the precision of their inserts.
+func SetPrecision(precision string) func(*AsyncConfig) {
+	return func(c *AsyncConfig) {
+		c.precision = precision
+	}
+}
+
+// AsyncWriter buffers schemaless lines and inserts them in batches.
+// A batch is sent when it reaches the max bytes or max lines, or when its first line waited for the max latency.
+// Write and WritePoint are safe for concurrent use.
+type AsyncWriter struct {
+	insert    InsertFunc
+	protocol  int
+	config    AsyncConfig
+	lines     chan string
+	batches   chan []string
+	closing   chan struct{}
+	abort     chan struct{}
+	lock      sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	abortOnce sync.Once
+	abortErr  error
+	done      chan struct{}
+	workerWg  sync.WaitGroup
+}
+
+// NewAsyncWriter creates an AsyncWriter inserting the lines of protocol with insert.
+func NewAsyncWriter(insert InsertFunc, protocol int, opts ...func(*AsyncConfig)) *AsyncWriter {
+	config := AsyncConfig{
+		maxBatchBytes: 1 << 20,
+		maxBatchLines: 10000,
+		maxLatency:    time.Second,
+		concurrency:   2,
+		queueSize:     10000,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.maxBatchBytes <= 0 {
+		config.maxBatchBytes = 1 << 20
+	}
+	if config.maxBatchLines <= 0 {
+		config.maxBatchLines = 10000
+	}
+	if config.maxLatency <= 0 {
+		config.maxLatency = time.Second
+	}
+	if config.concurrency <= 0 {
+		config.concurrency = 1
+	}
+	if config.queueSize < 0 {
+		config.queueSize = 0
+	}
+	w := &AsyncWriter{
+		insert:   insert,
+		protocol: protocol,
+		config:   config,
+		lines:    make(chan string, config.queueSize),
+		batches:  make(chan []string),
+		closing:  make(chan struct{}),
+		abort:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.workerWg.Add(config.concurrency)
+	for i := 0; i < config.concurrency; i++ {
+		go w.sendLoop()
+	}
+	go w.batchLoop()
+	go func() {
+		w.workerWg.Wait()
+		close(w.done)
+	}()
+	return w
+}
+
+// Write adds a line to the current batch, it blocks while the queue is full.
+// With the OpenTSDB JSON protocol a line is a JSON object or an array of objects.
+func (w *AsyncWriter) Write(line string) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	select {
+	case w.lines <- line:
+		return nil
+	case <-w.closing:
+		return ErrWriterClosed
+	}
+}
+
+// WritePoint serializes p with the protocol of the writer and adds it to the current batch like Write.
+func (w *AsyncWriter) WritePoint(p *Point) error {
+	line, err := MarshalPoints([]*Point{p}, w.protocol, w.config.precision)
+	if err != nil {
+		return err
+	}
+	return w.Write(line)
+}
+
+// Close stops accepting lines and waits until the buffered lines are sent or ctx is done.
+// When ctx is done the batches not sent yet are passed to the error handler with the ctx error,
+// and Close returns the ctx error without waiting for the batches in flight.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		// unblock the writers waiting for the queue before taking the write lock
+		close(w.closing)
+		w.lock.Lock()
+		w.closed = true
+		close(w.lines)
+		w.lock.Unlock()
+	})
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		err := ctx.Err()
+		w.abortOnce.Do(func() {
+			w.abortErr = err
+			close(w.abort)
+		})
+		return err
+	}
+}
+
+func (w *AsyncWriter) batchLoop() {
+	defer close(w.batches)
+	var batch []string
+	size := 0
+	timer := time.NewTimer(w.config.maxLatency)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		w.batches <- batch
+		batch = nil
+		size = 0
+	}
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) != 0 && size+len(line)+1 > w.config.maxBatchBytes {
+				flush()
+			}
+			if len(batch) == 0 {
+				timer.Reset(w.config.maxLatency)
+			}
+			batch = append(batch, line)
+			size += len(line) + 1
+			if len(batch) >= w.config.maxBatchLines || size >= w.config.maxBatchBytes {
+				flush()
+			}
+		case <-timer.C:
+			// the timer is only running while the batch has lines
+			w.batches <- batch
+			batch = nil
+			size = 0
+		}
+	}
+}
+
+func (w *AsyncWriter) sendLoop() {
+	defer w.workerWg.Done()
+	for batch := range w.batches {
+		select {
+		case <-w.abort:
+			w.handleError(batch, w.abortErr)
+			continue
+		default:
+		}
+		var reqID int64
+		if w.config.reqIDFunc != nil {
+			reqID = w.config.reqIDFunc()
+		}
+		err := w.insert(w.join(batch), reqID)
+		if err != nil {
+			w.handleError(batch, err)
+		}
+	}
+}
+
+func (w *AsyncWriter) handleError(batch []string, err error) {
+	if w.config.errorHandler != nil {
+		w.config.errorHandler(&BatchError{Lines: batch, Err: err})
+	}
+}
+
+func (w *AsyncWriter) join(batch []string) string {
+	if w.protocol != OpenTSDBJsonFormatProtocol {
+		return strings.Join(batch, "\n")
+	}
+	b := &strings.Builder{}
+	b.WriteByte('[')
+	for _, line := range batch {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			line = strings.TrimSpace(line[1 : len(line)-1])
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(line)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,221 @@
+package schemaless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	lock    sync.Mutex
+	batches []string
+}
+
+func (r *recorder) insert(lines string, _ int64) error {
+	r.lock.Lock()
+	r.batches = append(r.batches, lines)
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *recorder) get() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.batches...)
+}
+
+func TestAsyncWriterBatchLines(t *testing.T) {
+	r := &recorder{}
+	w := NewAsyncWriter(r.insert, InfluxDBLineProtocol, SetMaxBatchLines(3), SetMaxLatency(time.Hour), SetConcurrency(1))
+	for i := 0; i < 7; i++ {
+		require.NoError(t, w.Write(fmt.Sprintf("m v=%di", i)))
+	}
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{
+		"m v=0i\nm v=1i\nm v=2i",
+		"m v=3i\nm v=4i\nm v=5i",
+		"m v=6i",
+	}, r.get())
+	assert.Equal(t, ErrWriterClosed, w.Write("m v=7i"))
+	// closing twice is allowed
+	assert.NoError(t, w.Close(context.Background()))
+}
+
+func TestAsyncWriterBatchBytes(t *testing.T) {
+	r := &recorder{}
+	w := NewAsyncWriter(r.insert, OpenTSDBTelnetLineProtocol, SetMaxBatchBytes(10), SetMaxLatency(time.Hour), SetConcurrency(1))
+	for _, line := range []string{"aaaa", "bbbb", "cccc", strings.Repeat("d", 20), "eeee"} {
+		require.NoError(t, w.Write(line))
+	}
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{"aaaa\nbbbb", "cccc", strings.Repeat("d", 20), "eeee"}, r.get())
+}
+
+func TestAsyncWriterLatency(t *testing.T) {
+	r := &recorder{}
+	w := NewAsyncWriter(r.insert, InfluxDBLineProtocol, SetMaxLatency(20*time.Millisecond))
+	require.NoError(t, w.Write("m v=1i"))
+	require.NoError(t, w.Write("m v=2i"))
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"m v=1i\nm v=2i"}, r.get())
+	require.NoError(t, w.Write("m v=3i"))
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{"m v=1i\nm v=2i", "m v=3i"}, r.get())
+}
+
+func TestAsyncWriterJson(t *testing.T) {
+	r := &recorder{}
+	w := NewAsyncWriter(r.insert, OpenTSDBJsonFormatProtocol, SetMaxLatency(time.Hour))
+	require.NoError(t, w.Write(`{"metric":"a"}`))
+	require.NoError(t, w.Write(` [{"metric":"b"},{"metric":"c"}] `))
+	require.NoError(t, w.Write(`[]`))
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{`[{"metric":"a"},{"metric":"b"},{"metric":"c"}]`}, r.get())
+}
+
+func TestAsyncWriterPoint(t *testing.T) {
+	ts := time.Unix(1626006833, 639000000)
+	r := &recorder{}
+	w := NewAsyncWriter(r.insert, InfluxDBLineProtocol, SetMaxLatency(time.Hour), SetPrecision("ms"))
+	require.NoError(t, w.WritePoint(NewPoint("m").AddTag("t", "1").AddBigintField("value", 1).SetTime(ts)))
+	require.NoError(t, w.Write("m,t=2 value=2i64 1626006833639"))
+	assert.Error(t, w.WritePoint(NewPoint("m").AddTag("t", "3")))
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{"m,t=1 value=1i64 1626006833639\nm,t=2 value=2i64 1626006833639"}, r.get())
+
+	r = &recorder{}
+	w = NewAsyncWriter(r.insert, OpenTSDBJsonFormatProtocol, SetMaxLatency(time.Hour))
+	require.NoError(t, w.WritePoint(NewPoint("m").AddTag("t", "1").AddBigintField("value", 1).SetTime(ts)))
+	require.NoError(t, w.Write(`{"metric":"a"}`))
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{`[{"metric":"m","timestamp":1626006833639,"value":{"value":1,"type":"bigint"},"tags":{"t":"1"}},{"metric":"a"}]`}, r.get())
+}
+
+func TestAsyncWriterError(t *testing.T) {
+	var errs []*BatchError
+	var lock sync.Mutex
+	insertErr := errors.New("insert error")
+	var reqID int64
+	w := NewAsyncWriter(
+		func(lines string, id int64) error {
+			assert.NotZero(t, id)
+			if strings.Contains(lines, "bad") {
+				return insertErr
+			}
+			return nil
+		},
+		InfluxDBLineProtocol,
+		SetMaxBatchLines(1),
+		SetReqIDFunc(func() int64 { return atomic.AddInt64(&reqID, 1) }),
+		SetBatchErrorHandler(func(err *BatchError) {
+			lock.Lock()
+			errs = append(errs, err)
+			lock.Unlock()
+		}),
+	)
+	require.NoError(t, w.Write("good"))
+	require.NoError(t, w.Write("bad"))
+	require.NoError(t, w.Close(context.Background()))
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, []string{"bad"}, errs[0].Lines)
+	assert.True(t, errors.Is(errs[0], insertErr))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&reqID))
+}
+
+func TestAsyncWriterConcurrency(t *testing.T) {
+	var inFlight, maxInFlight, count int64
+	release := make(chan struct{})
+	w := NewAsyncWriter(func(lines string, _ int64) error {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt64(&inFlight, -1)
+		atomic.AddInt64(&count, int64(strings.Count(lines, "\n")+1))
+		return nil
+	}, InfluxDBLineProtocol, SetMaxBatchLines(1), SetConcurrency(3))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				assert.NoError(t, w.Write("m v=1i"))
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&inFlight) == 3
+	}, time.Second, 5*time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, int64(3), atomic.LoadInt64(&maxInFlight))
+	assert.Equal(t, int64(100), atomic.LoadInt64(&count))
+}
+
+func TestAsyncWriterCloseDeadline(t *testing.T) {
+	release := make(chan struct{})
+	var lock sync.Mutex
+	var dropped []string
+	w := NewAsyncWriter(func(lines string, _ int64) error {
+		<-release
+		return nil
+	}, InfluxDBLineProtocol, SetMaxBatchLines(1), SetConcurrency(1), SetBatchErrorHandler(func(err *BatchError) {
+		lock.Lock()
+		dropped = append(dropped, err.Lines...)
+		lock.Unlock()
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	}))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.Write(fmt.Sprintf("m v=%di", i)))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Close(ctx))
+	close(release)
+	require.NoError(t, w.Close(context.Background()))
+	lock.Lock()
+	defer lock.Unlock()
+	// the first line was in flight when the deadline expired
+	assert.Equal(t, []string{"m v=1i", "m v=2i"}, dropped)
+}
+
+func TestAsyncWriterCloseUnblocksWrite(t *testing.T) {
+	release := make(chan struct{})
+	w := NewAsyncWriter(func(lines string, _ int64) error {
+		<-release
+		return nil
+	}, InfluxDBLineProtocol, SetMaxBatchLines(1), SetConcurrency(1), SetQueueSize(0))
+	errCh := make(chan error)
+	go func() {
+		var err error
+		for err == nil {
+			err = w.Write("m v=1i")
+		}
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_ = w.Close(ctx)
+	assert.Equal(t, ErrWriterClosed, <-errCh)
+	close(release)
+}
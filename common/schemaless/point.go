@@ -0,0 +1,443 @@
+package schemaless
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	fieldBool = iota
+	fieldTinyint
+	fieldSmallint
+	fieldInt
+	fieldBigint
+	fieldUTinyint
+	fieldUSmallint
+	fieldUInt
+	fieldUBigint
+	fieldFloat
+	fieldDouble
+	fieldBinary
+	fieldNchar
+)
+
+var (
+	lineSuffix = [...]string{
+		fieldTinyint:   "i8",
+		fieldSmallint:  "i16",
+		fieldInt:       "i32",
+		fieldBigint:    "i64",
+		fieldUTinyint:  "u8",
+		fieldUSmallint: "u16",
+		fieldUInt:      "u32",
+		fieldUBigint:   "u64",
+		fieldFloat:     "f32",
+		fieldDouble:    "f64",
+	}
+	jsonType = [...]string{
+		fieldBool:     "bool",
+		fieldTinyint:  "tinyint",
+		fieldSmallint: "smallint",
+		fieldInt:      "int",
+		fieldBigint:   "bigint",
+		fieldFloat:    "float",
+		fieldDouble:   "double",
+		fieldBinary:   "binary",
+		fieldNchar:    "nchar",
+	}
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+type pointTag struct {
+	key   string
+	value string
+}
+
+type pointField struct {
+	key       string
+	fieldType int
+	value     interface{}
+}
+
+// Point is a row in schemaless format: a measurement (the supertable), string tags, typed fields and a timestamp.
+// It is serialized with Line, Telnet or JSON, or with MarshalPoints for a batch.
+type Point struct {
+	measurement string
+	tags        []pointTag
+	fields      []pointField
+	ts          time.Time
+}
+
+// NewPoint creates a point of the measurement, the timestamp is the server time unless SetTime is called.
+func NewPoint(measurement string) *Point {
+	return &Point{measurement: measurement}
+}
+
+func (p *Point) SetTime(ts time.Time) *Point {
+	p.ts = ts
+	return p
+}
+
+func (p *Point) AddTag(key string, value string) *Point {
+	p.tags = append(p.tags, pointTag{key: key, value: value})
+	return p
+}
+
+func (p *Point) addField(key string, fieldType int, value interface{}) *Point {
+	p.fields = append(p.fields, pointField{key: key, fieldType: fieldType, value: value})
+	return p
+}
+
+func (p *Point) AddBoolField(key string, value bool) *Point {
+	return p.addField(key, fieldBool, value)
+}
+
+func (p *Point) AddTinyintField(key string, value int8) *Point {
+	return p.addField(key, fieldTinyint, int64(value))
+}
+
+func (p *Point) AddSmallintField(key string, value int16) *Point {
+	return p.addField(key, fieldSmallint, int64(value))
+}
+
+func (p *Point) AddIntField(key string, value int32) *Point {
+	return p.addField(key, fieldInt, int64(value))
+}
+
+func (p *Point) AddBigintField(key string, value int64) *Point {
+	return p.addField(key, fieldBigint, value)
+}
+
+func (p *Point) AddUTinyintField(key string, value uint8) *Point {
+	return p.addField(key, fieldUTinyint, uint64(value))
+}
+
+func (p *Point) AddUSmallintField(key string, value uint16) *Point {
+	return p.addField(key, fieldUSmallint, uint64(value))
+}
+
+func (p *Point) AddUIntField(key string, value uint32) *Point {
+	return p.addField(key, fieldUInt, uint64(value))
+}
+
+func (p *Point) AddUBigintField(key string, value uint64) *Point {
+	return p.addField(key, fieldUBigint, value)
+}
+
+func (p *Point) AddFloatField(key string, value float32) *Point {
+	return p.addField(key, fieldFloat, float64(value))
+}
+
+func (p *Point) AddDoubleField(key string, value float64) *Point {
+	return p.addField(key, fieldDouble, value)
+}
+
+// AddBinaryField adds a VARCHAR field.
+func (p *Point) AddBinaryField(key string, value string) *Point {
+	return p.addField(key, fieldBinary, value)
+}
+
+// AddNcharField adds a NCHAR field.
+func (p *Point) AddNcharField(key string, value string) *Point {
+	return p.addField(key, fieldNchar, value)
+}
+
+// Line serializes the point to the InfluxDB line protocol with the timestamp in precision,
+// the precision passed to the schemaless insert: "ns" (or empty), "u", "ms", "s", "m" or "h".
+func (p *Point) Line(precision string) (string, error) {
+	b := &strings.Builder{}
+	err := p.writeLine(b, precision)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (p *Point) writeLine(b *strings.Builder, precision string) error {
+	unit, err := precisionUnit(precision)
+	if err != nil {
+		return err
+	}
+	if err = p.check(); err != nil {
+		return err
+	}
+	b.WriteString(measurementEscaper.Replace(p.measurement))
+	for _, tag := range p.tags {
+		b.WriteByte(',')
+		b.WriteString(keyEscaper.Replace(tag.key))
+		b.WriteByte('=')
+		b.WriteString(keyEscaper.Replace(tag.value))
+	}
+	for i, field := range p.fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(field.key))
+		b.WriteByte('=')
+		err = writeFieldValue(b, field)
+		if err != nil {
+			return err
+		}
+	}
+	if !p.ts.IsZero() {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(p.ts.UnixNano()/int64(unit), 10))
+	}
+	return nil
+}
+
+// Telnet serializes the point to the OpenTSDB telnet line protocol, the point must have exactly one field
+// and the timestamp is in milliseconds.
+func (p *Point) Telnet() (string, error) {
+	b := &strings.Builder{}
+	err := p.writeTelnet(b)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (p *Point) writeTelnet(b *strings.Builder) error {
+	if err := p.checkOpenTSDB(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(p.measurement, " =") {
+		return fmt.Errorf("schemaless: telnet metric %q contains space or '='", p.measurement)
+	}
+	for _, tag := range p.tags {
+		if strings.ContainsAny(tag.key, " =") || strings.ContainsAny(tag.value, " =") {
+			return fmt.Errorf("schemaless: telnet tag %s=%s contains space or '='", tag.key, tag.value)
+		}
+	}
+	if v, ok := p.fields[0].value.(string); ok && strings.ContainsRune(v, ' ') {
+		return fmt.Errorf("schemaless: telnet field value %q contains space", v)
+	}
+	b.WriteString(p.measurement)
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(p.milliseconds(), 10))
+	b.WriteByte(' ')
+	err := writeFieldValue(b, p.fields[0])
+	if err != nil {
+		return err
+	}
+	for _, tag := range p.tags {
+		b.WriteByte(' ')
+		b.WriteString(tag.key)
+		b.WriteByte('=')
+		b.WriteString(tag.value)
+	}
+	return nil
+}
+
+// JSON serializes the point to an object of the OpenTSDB JSON protocol, the point must have exactly one field
+// and the timestamp is in milliseconds. Unsigned fields are not supported by the JSON protocol.
+func (p *Point) JSON() (string, error) {
+	b := &strings.Builder{}
+	err := p.writeJSON(b)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (p *Point) writeJSON(b *strings.Builder) error {
+	if err := p.checkOpenTSDB(); err != nil {
+		return err
+	}
+	field := p.fields[0]
+	if len(jsonType) <= field.fieldType || len(jsonType[field.fieldType]) == 0 {
+		return fmt.Errorf("schemaless: field %s is unsigned, the OpenTSDB JSON protocol does not support it", field.key)
+	}
+	if v, ok := field.value.(float64); ok && (math.IsNaN(v) || math.IsInf(v, 0)) {
+		return fmt.Errorf("schemaless: invalid float value %v of field %s", v, field.key)
+	}
+	tags := make(map[string]string, len(p.tags))
+	for _, tag := range p.tags {
+		tags[tag.key] = tag.value
+	}
+	stream := jsoniter.ConfigCompatibleWithStandardLibrary.BorrowStream(nil)
+	defer jsoniter.ConfigCompatibleWithStandardLibrary.ReturnStream(stream)
+	stream.WriteObjectStart()
+	stream.WriteObjectField("metric")
+	stream.WriteString(p.measurement)
+	stream.WriteMore()
+	stream.WriteObjectField("timestamp")
+	stream.WriteInt64(p.milliseconds())
+	stream.WriteMore()
+	stream.WriteObjectField("value")
+	stream.WriteObjectStart()
+	stream.WriteObjectField("value")
+	if field.fieldType == fieldFloat {
+		stream.WriteFloat32(float32(field.value.(float64)))
+	} else {
+		stream.WriteVal(field.value)
+	}
+	stream.WriteMore()
+	stream.WriteObjectField("type")
+	stream.WriteString(jsonType[field.fieldType])
+	stream.WriteObjectEnd()
+	stream.WriteMore()
+	stream.WriteObjectField("tags")
+	stream.WriteVal(tags)
+	stream.WriteObjectEnd()
+	if stream.Error != nil {
+		return stream.Error
+	}
+	b.Write(stream.Buffer())
+	return nil
+}
+
+// MarshalPoints serializes points to the payload of a schemaless insert with protocol,
+// precision is only used by the InfluxDB line protocol.
+func MarshalPoints(points []*Point, protocol int, precision string) (string, error) {
+	b := &strings.Builder{}
+	if protocol == OpenTSDBJsonFormatProtocol {
+		b.WriteByte('[')
+	}
+	for i, p := range points {
+		var err error
+		switch protocol {
+		case InfluxDBLineProtocol:
+			if i != 0 {
+				b.WriteByte('\n')
+			}
+			err = p.writeLine(b, precision)
+		case OpenTSDBTelnetLineProtocol:
+			if i != 0 {
+				b.WriteByte('\n')
+			}
+			err = p.writeTelnet(b)
+		case OpenTSDBJsonFormatProtocol:
+			if i != 0 {
+				b.WriteByte(',')
+			}
+			err = p.writeJSON(b)
+		default:
+			return "", fmt.Errorf("schemaless: unknown protocol %d", protocol)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if protocol == OpenTSDBJsonFormatProtocol {
+		b.WriteByte(']')
+	}
+	return b.String(), nil
+}
+
+var errNewline = errors.New("schemaless: newline is not allowed in a point")
+
+func (p *Point) check() error {
+	if len(p.measurement) == 0 {
+		return errors.New("schemaless: empty measurement")
+	}
+	if len(p.fields) == 0 {
+		return fmt.Errorf("schemaless: point %s has no fields", p.measurement)
+	}
+	if strings.ContainsAny(p.measurement, "\r\n") {
+		return errNewline
+	}
+	for _, tag := range p.tags {
+		if len(tag.key) == 0 || len(tag.value) == 0 {
+			return fmt.Errorf("schemaless: empty tag key or value of point %s", p.measurement)
+		}
+		if strings.ContainsAny(tag.key, "\r\n") || strings.ContainsAny(tag.value, "\r\n") {
+			return errNewline
+		}
+	}
+	for _, field := range p.fields {
+		if len(field.key) == 0 {
+			return fmt.Errorf("schemaless: empty field key of point %s", p.measurement)
+		}
+		if strings.ContainsAny(field.key, "\r\n") {
+			return errNewline
+		}
+		if s, ok := field.value.(string); ok && strings.ContainsAny(s, "\r\n") {
+			return errNewline
+		}
+	}
+	return nil
+}
+
+func (p *Point) checkOpenTSDB() error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	if len(p.fields) != 1 {
+		return fmt.Errorf("schemaless: OpenTSDB point %s must have exactly one field", p.measurement)
+	}
+	return nil
+}
+
+func (p *Point) milliseconds() int64 {
+	if p.ts.IsZero() {
+		return 0
+	}
+	return p.ts.UnixNano() / int64(time.Millisecond)
+}
+
+func writeFieldValue(b *strings.Builder, field pointField) error {
+	switch v := field.value.(type) {
+	case bool:
+		if v {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+		b.WriteString(lineSuffix[field.fieldType])
+	case uint64:
+		b.WriteString(strconv.FormatUint(v, 10))
+		b.WriteString(lineSuffix[field.fieldType])
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("schemaless: invalid float value %v of field %s", v, field.key)
+		}
+		bitSize := 64
+		if field.fieldType == fieldFloat {
+			bitSize = 32
+		}
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, bitSize))
+		b.WriteString(lineSuffix[field.fieldType])
+	case string:
+		if field.fieldType == fieldNchar {
+			b.WriteByte('L')
+		}
+		b.WriteByte('"')
+		b.WriteString(stringEscaper.Replace(v))
+		b.WriteByte('"')
+	}
+	return nil
+}
+
+func precisionUnit(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "ns":
+		return time.Nanosecond, nil
+	case "u", "μ":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("schemaless: invalid precision %s", precision)
+}
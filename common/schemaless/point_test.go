@@ -0,0 +1,114 @@
+package schemaless
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointLine(t *testing.T) {
+	ts := time.Unix(1626006833, 639000000)
+	p := NewPoint("st,1 a").
+		AddTag(`t=1`, `v 1,\x`).
+		AddTag("t2", "北京").
+		AddBoolField("c1", true).
+		AddTinyintField("c2", -1).
+		AddSmallintField("c3", 2).
+		AddIntField("c4", 3).
+		AddBigintField("c5", 4).
+		AddUTinyintField("c6", 5).
+		AddUSmallintField("c7", 6).
+		AddUIntField("c8", 7).
+		AddUBigintField("c9", 8).
+		AddFloatField("c10", 1.1).
+		AddDoubleField("c 11", 2.2).
+		AddBinaryField("c12", `say "hi" \o/`).
+		AddNcharField("c13", "你好").
+		SetTime(ts)
+	line, err := p.Line("ms")
+	require.NoError(t, err)
+	assert.Equal(t, `st\,1\ a,t\=1=v\ 1\,\\x,t2=北京 c1=true,c2=-1i8,c3=2i16,c4=3i32,c5=4i64,c6=5u8,c7=6u16,c8=7u32,c9=8u64,c10=1.1f32,c\ 11=2.2f64,c12="say \"hi\" \\o/",c13=L"你好" 1626006833639`, line)
+
+	line, err = NewPoint("m").AddBoolField("v", false).SetTime(ts).Line("")
+	require.NoError(t, err)
+	assert.Equal(t, "m v=false 1626006833639000000", line)
+	line, err = NewPoint("m").AddBoolField("v", false).SetTime(ts).Line("u")
+	require.NoError(t, err)
+	assert.Equal(t, "m v=false 1626006833639000", line)
+	line, err = NewPoint("m").AddBoolField("v", false).SetTime(ts).Line("s")
+	require.NoError(t, err)
+	assert.Equal(t, "m v=false 1626006833", line)
+	// the server time is used without timestamp
+	line, err = NewPoint("m").AddBoolField("v", false).Line("ms")
+	require.NoError(t, err)
+	assert.Equal(t, "m v=false", line)
+}
+
+func TestPointTelnet(t *testing.T) {
+	ts := time.Unix(1626006833, 639000000)
+	line, err := NewPoint("meters.current").SetTime(ts).AddFloatField("value", 10.3).AddTag("location", "California.SanFrancisco").AddTag("groupid", "2").Telnet()
+	require.NoError(t, err)
+	assert.Equal(t, "meters.current 1626006833639 10.3f32 location=California.SanFrancisco groupid=2", line)
+	line, err = NewPoint("m").SetTime(ts).AddNcharField("value", `a"b`).AddTag("t", "1").Telnet()
+	require.NoError(t, err)
+	assert.Equal(t, `m 1626006833639 L"a\"b" t=1`, line)
+}
+
+func TestPointJSON(t *testing.T) {
+	ts := time.Unix(1626006833, 639000000)
+	line, err := NewPoint("meters.current").SetTime(ts).AddFloatField("value", 10.3).AddTag("location", `"California"`).AddTag("groupid", "2").JSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"metric":"meters.current","timestamp":1626006833639,"value":{"value":10.3,"type":"float"},"tags":{"groupid":"2","location":"\"California\""}}`, line)
+	line, err = NewPoint("m").AddBinaryField("value", "a\\b").AddTag("t", "1").JSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"metric":"m","timestamp":0,"value":{"value":"a\\b","type":"binary"},"tags":{"t":"1"}}`, line)
+}
+
+func TestMarshalPoints(t *testing.T) {
+	ts := time.Unix(1626006833, 639000000)
+	points := []*Point{
+		NewPoint("m").AddTag("t", "1").AddBigintField("value", 1).SetTime(ts),
+		NewPoint("m").AddTag("t", "2").AddBigintField("value", 2).SetTime(ts),
+	}
+	lines, err := MarshalPoints(points, InfluxDBLineProtocol, "ms")
+	require.NoError(t, err)
+	assert.Equal(t, "m,t=1 value=1i64 1626006833639\nm,t=2 value=2i64 1626006833639", lines)
+	lines, err = MarshalPoints(points, OpenTSDBTelnetLineProtocol, "")
+	require.NoError(t, err)
+	assert.Equal(t, "m 1626006833639 1i64 t=1\nm 1626006833639 2i64 t=2", lines)
+	lines, err = MarshalPoints(points, OpenTSDBJsonFormatProtocol, "")
+	require.NoError(t, err)
+	assert.Equal(t, `[{"metric":"m","timestamp":1626006833639,"value":{"value":1,"type":"bigint"},"tags":{"t":"1"}},{"metric":"m","timestamp":1626006833639,"value":{"value":2,"type":"bigint"},"tags":{"t":"2"}}]`, lines)
+	_, err = MarshalPoints(points, 4, "")
+	assert.Error(t, err)
+}
+
+func TestPointErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		point    *Point
+		protocol int
+	}{
+		{name: "empty measurement", point: NewPoint("").AddBoolField("v", true), protocol: InfluxDBLineProtocol},
+		{name: "no fields", point: NewPoint("m"), protocol: InfluxDBLineProtocol},
+		{name: "empty tag value", point: NewPoint("m").AddTag("t", "").AddBoolField("v", true), protocol: InfluxDBLineProtocol},
+		{name: "newline", point: NewPoint("m").AddBinaryField("v", "a\nb"), protocol: InfluxDBLineProtocol},
+		{name: "nan", point: NewPoint("m").AddDoubleField("v", math.NaN()), protocol: InfluxDBLineProtocol},
+		{name: "telnet two fields", point: NewPoint("m").AddBoolField("v", true).AddBoolField("w", true), protocol: OpenTSDBTelnetLineProtocol},
+		{name: "telnet space", point: NewPoint("m").AddTag("t", "a b").AddBoolField("v", true), protocol: OpenTSDBTelnetLineProtocol},
+		{name: "telnet string space", point: NewPoint("m").AddTag("t", "a").AddBinaryField("v", "a b"), protocol: OpenTSDBTelnetLineProtocol},
+		{name: "json unsigned", point: NewPoint("m").AddUIntField("v", 1), protocol: OpenTSDBJsonFormatProtocol},
+		{name: "json inf", point: NewPoint("m").AddDoubleField("v", math.Inf(1)), protocol: OpenTSDBJsonFormatProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MarshalPoints([]*Point{tt.point}, tt.protocol, "ms")
+			assert.Error(t, err)
+		})
+	}
+	_, err := NewPoint("m").AddBoolField("v", true).Line("us")
+	assert.Error(t, err)
+}
@@ -96,3 +96,144 @@ func ValueArgsToNamedValueArgs(args []driver.Value) (values []driver.NamedValue)
 	}
 	return
 }
+
+func firstKeyword(query string) (string, string) {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	end := strings.IndexAny(query, " \t\r\n(;")
+	if end == -1 {
+		return strings.ToLower(query), ""
+	}
+	return strings.ToLower(query[:end]), query[end:]
+}
+
+// IsReadOnlySQL reports whether the sql only reads data, so it is safe to execute it again.
+func IsReadOnlySQL(query string) bool {
+	keyword, _ := firstKeyword(query)
+	switch keyword {
+	case "select", "show", "describe", "desc":
+		return true
+	}
+	return false
+}
+
+// ParseUseDB returns the database name if the sql is a `use db` statement.
+func ParseUseDB(query string) (string, bool) {
+	keyword, rest := firstKeyword(query)
+	if keyword != "use" {
+		return "", false
+	}
+	fields := strings.Fields(strings.TrimRight(strings.TrimSpace(rest), ";"))
+	if len(fields) != 1 {
+		return "", false
+	}
+	return strings.Trim(fields[0], "`"), true
+}
+
+// ErrTxBatchNotInsert is returned for statements other than insert executed in a txBatch transaction.
+var ErrTxBatchNotInsert = errors.New("only insert statements can be executed in a txBatch transaction")
+
+// TxBatchCommitError is returned by the Commit of a txBatch transaction when a write fails.
+// Commit is not atomic, the writes before the failed one are not rolled back.
+type TxBatchCommitError struct {
+	// Applied is the number of insert statements and prepared statement rows written before the failure
+	Applied int
+	// Total is the number of insert statements and prepared statement rows buffered in the transaction
+	Total int
+	Err   error
+}
+
+func (e *TxBatchCommitError) Error() string {
+	return fmt.Sprintf("txBatch commit failed after %d of %d writes: %s", e.Applied, e.Total, e.Err)
+}
+
+func (e *TxBatchCommitError) Unwrap() error {
+	return e.Err
+}
+
+// InsertClause returns the part of an insert statement after `insert into`,
+// insert ... select statements can not be merged and are not accepted.
+func InsertClause(query string) (string, bool) {
+	keyword, rest := firstKeyword(query)
+	if keyword != "insert" {
+		return "", false
+	}
+	keyword, rest = firstKeyword(rest)
+	if keyword != "into" {
+		return "", false
+	}
+	clause := strings.TrimRight(strings.TrimSpace(rest), "; \t\r\n")
+	if len(clause) == 0 || hasUnquotedWord(clause, "select") {
+		return "", false
+	}
+	return clause, true
+}
+
+// MergeInserts joins insert clauses into multi-table insert statements,
+// a statement only exceeds maxLength if a single clause does.
+func MergeInserts(clauses []string, maxLength int) []string {
+	statements, _ := MergeInsertsCount(clauses, maxLength)
+	return statements
+}
+
+// MergeInsertsCount is MergeInserts that also returns the number of clauses merged into each statement.
+func MergeInsertsCount(clauses []string, maxLength int) ([]string, []int) {
+	const prefix = "insert into"
+	var statements []string
+	var counts []int
+	builder := &strings.Builder{}
+	count := 0
+	for _, clause := range clauses {
+		if builder.Len() != 0 && builder.Len()+len(clause)+1 > maxLength {
+			statements = append(statements, builder.String())
+			counts = append(counts, count)
+			builder.Reset()
+			count = 0
+		}
+		if builder.Len() == 0 {
+			builder.WriteString(prefix)
+		}
+		builder.WriteByte(' ')
+		builder.WriteString(clause)
+		count++
+	}
+	if builder.Len() != 0 {
+		statements = append(statements, builder.String())
+		counts = append(counts, count)
+	}
+	return statements, counts
+}
+
+// hasUnquotedWord reports whether word appears in s outside of quotes, case-insensitively.
+func hasUnquotedWord(s string, word string) bool {
+	var quote byte
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		var c byte
+		if i < len(s) {
+			c = s[i]
+		}
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		isWordChar := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if isWordChar {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 && strings.EqualFold(s[start:i], word) {
+			return true
+		}
+		start = -1
+		if c == '\'' || c == '"' || c == '`' {
+			quote = c
+		}
+	}
+	return false
+}
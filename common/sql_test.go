@@ -134,3 +134,101 @@ func TestValueArgsToNamedValueArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "select * from t", want: true},
+		{query: "  SELECT 1", want: true},
+		{query: "(select 1) union (select 2)", want: true},
+		{query: "show databases", want: true},
+		{query: "describe t", want: true},
+		{query: "desc t", want: true},
+		{query: "insert into t values(now,1)", want: false},
+		{query: "selector", want: false},
+		{query: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsReadOnlySQL(tt.query); got != tt.want {
+			t.Errorf("IsReadOnlySQL(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseUseDB(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{query: "use db1", want: "db1", wantOK: true},
+		{query: " USE `db1`;", want: "db1", wantOK: true},
+		{query: "use", wantOK: false},
+		{query: "use db1 db2", wantOK: false},
+		{query: "user db1", wantOK: false},
+		{query: "select 1", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseUseDB(tt.query)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseUseDB(%q) = %q, %v, want %q, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestInsertClause(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{query: "insert into t1 values(now,1)", want: "t1 values(now,1)", wantOK: true},
+		{query: " INSERT INTO d1 using stb tags(1) values(now,'select');", want: "d1 using stb tags(1) values(now,'select')", wantOK: true},
+		{query: "insert into t1 (ts, v) values(now, 1) t2 values(now, 2)", want: "t1 (ts, v) values(now, 1) t2 values(now, 2)", wantOK: true},
+		{query: "insert into t1 select * from t2", wantOK: false},
+		{query: "insert into t1 (ts, v) (SELECT ts, v from t2)", wantOK: false},
+		{query: "insert into", wantOK: false},
+		{query: "insert t1 values(now,1)", wantOK: false},
+		{query: "select * from t1", wantOK: false},
+		{query: "delete from t1", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := InsertClause(tt.query)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("InsertClause(%q) = %q, %v, want %q, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMergeInserts(t *testing.T) {
+	clauses := []string{"t1 values(now,1)", "t2 values(now,2)", "t3 values(now,3)"}
+	got := MergeInserts(clauses, MaxTaosSqlLen)
+	want := []string{"insert into t1 values(now,1) t2 values(now,2) t3 values(now,3)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeInserts() = %v, want %v", got, want)
+	}
+	got = MergeInserts(clauses, 50)
+	want = []string{"insert into t1 values(now,1) t2 values(now,2)", "insert into t3 values(now,3)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeInserts() = %v, want %v", got, want)
+	}
+	got = MergeInserts(clauses, 10)
+	want = []string{"insert into t1 values(now,1)", "insert into t2 values(now,2)", "insert into t3 values(now,3)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeInserts() = %v, want %v", got, want)
+	}
+	if got = MergeInserts(nil, 10); len(got) != 0 {
+		t.Errorf("MergeInserts(nil) = %v", got)
+	}
+}
+
+func TestMergeInsertsCount(t *testing.T) {
+	clauses := []string{"t1 values(now,1)", "t2 values(now,2)", "t3 values(now,3)"}
+	statements, counts := MergeInsertsCount(clauses, 50)
+	want := []string{"insert into t1 values(now,1) t2 values(now,2)", "insert into t3 values(now,3)"}
+	if !reflect.DeepEqual(statements, want) || !reflect.DeepEqual(counts, []int{2, 1}) {
+		t.Errorf("MergeInsertsCount() = %v, %v, want %v, [2 1]", statements, counts, want)
+	}
+}
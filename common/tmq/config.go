@@ -3,8 +3,13 @@ package tmq
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/taosdata/driver-go/v3/common/log"
 )
 
+// LoggerKey is the config key of the log.Logger of a consumer, the package-level logger of common/log is used if it is not set.
+const LoggerKey = "logger"
+
 type ConfigValue interface{}
 type ConfigMap map[string]ConfigValue
 
@@ -25,6 +30,19 @@ func (m ConfigMap) get(key string, defval ConfigValue) (ConfigValue, error) {
 	return v, nil
 }
 
+// GetLogger returns the logger set with LoggerKey, or the package-level logger.
+func (m ConfigMap) GetLogger() (log.Logger, error) {
+	v, ok := m[LoggerKey]
+	if !ok || v == nil {
+		return log.GetLogger(), nil
+	}
+	logger, ok := v.(log.Logger)
+	if !ok {
+		return nil, fmt.Errorf("%s expects type log.Logger, not %T", LoggerKey, v)
+	}
+	return logger, nil
+}
+
 func (m ConfigMap) Clone() ConfigMap {
 	m2 := make(ConfigMap)
 	for k, v := range m {
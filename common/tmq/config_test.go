@@ -2,8 +2,11 @@ package tmq
 
 import (
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"testing"
+
+	"github.com/taosdata/driver-go/v3/common/log"
 )
 
 func TestConfigMap_Get(t *testing.T) {
@@ -50,3 +53,19 @@ func TestConfigMap_Clone(t *testing.T) {
 		t.Errorf("Clone() = %v, want %v", clone, config)
 	}
 }
+
+func TestConfigMap_GetLogger(t *testing.T) {
+	logger, err := ConfigMap{}.GetLogger()
+	if err != nil || logger != log.GetLogger() {
+		t.Errorf("GetLogger() = %v, want the package-level logger (error: %v)", logger, err)
+	}
+	textLogger := log.NewTextLogger(ioutil.Discard, log.LevelInfo)
+	logger, err = ConfigMap{LoggerKey: textLogger}.GetLogger()
+	if err != nil || logger != textLogger {
+		t.Errorf("GetLogger() = %v, want %v (error: %v)", logger, textLogger, err)
+	}
+	wantErr := "logger expects type log.Logger, not string"
+	if _, err = (ConfigMap{LoggerKey: "stdout"}).GetLogger(); err == nil || err.Error() != wantErr {
+		t.Errorf("GetLogger() error = %v, want %v", err, wantErr)
+	}
+}
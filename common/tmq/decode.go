@@ -0,0 +1,62 @@
+package tmq
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/taosdata/driver-go/v3/common/scan"
+)
+
+// TableNameColumn is the column name that maps the subtable name of a block onto a struct field, `taos:"tbname"`.
+const TableNameColumn = "tbname"
+
+// DecodeData appends the rows of data to dest, a pointer to a slice of structs or struct pointers.
+// Columns are matched with struct fields by name as in the common/scan package, so the decoding
+// does not depend on the column order of the server. Timestamps keep the location of the consumer's timezone
+// and DECIMAL values can be decoded into string or float fields.
+func DecodeData(data []*Data, dest interface{}) error {
+	for _, block := range data {
+		if len(block.Data) == 0 {
+			continue
+		}
+		if len(block.Fields) == 0 {
+			return fmt.Errorf("tmq: block of table %s has no column schema", block.TableName)
+		}
+		columns := make([]string, len(block.Fields)+1)
+		for i, field := range block.Fields {
+			if len(field.Name) == 0 {
+				return fmt.Errorf("tmq: block of table %s has no column names", block.TableName)
+			}
+			columns[i] = field.Name
+		}
+		columns[len(block.Fields)] = TableNameColumn
+		rows := make([][]driver.Value, len(block.Data))
+		for i, row := range block.Data {
+			if len(row) != len(block.Fields) {
+				return fmt.Errorf("tmq: expected %d values, got %d", len(block.Fields), len(row))
+			}
+			values := make([]driver.Value, len(row)+1)
+			copy(values, row)
+			values[len(row)] = block.TableName
+			rows[i] = values
+		}
+		err := scan.Values(columns, rows, dest)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode appends the rows of the message to dest, see DecodeData.
+func (m *DataMessage) Decode(dest interface{}) error {
+	return DecodeData(m.data, dest)
+}
+
+// Decode appends the rows of the message to dest, see DecodeData.
+func (m *MetaDataMessage) Decode(dest interface{}) error {
+	if m.metaData == nil {
+		return nil
+	}
+	return DecodeData(m.metaData.Data, dest)
+}
@@ -0,0 +1,84 @@
+package tmq
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+type meter struct {
+	Table   string            `taos:"tbname"`
+	Ts      time.Time         `taos:"ts"`
+	TsMs    int64             `taos:"ts,ms"`
+	Current *float32          `taos:"current"`
+	Voltage types.NullInt32   `taos:"voltage"`
+	Price   string            `taos:"price"`
+	Amount  float64           `taos:"price"`
+	Extra   map[string]string `taos:"-"`
+}
+
+func TestDecodeData(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	ts := time.Unix(1626006833, 639000000).In(loc)
+	fields := []*Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Bytes: 8},
+		{Name: "current", Type: common.TSDB_DATA_TYPE_FLOAT, Bytes: 4},
+		{Name: "voltage", Type: common.TSDB_DATA_TYPE_INT, Bytes: 4},
+		{Name: "price", Type: common.TSDB_DATA_TYPE_DECIMAL64, Bytes: 8, Precision: 10, Scale: 2},
+	}
+	// the server reordered the columns of the second block
+	reordered := []*Field{fields[2], fields[0], fields[3], fields[1]}
+	message := &DataMessage{}
+	message.SetData([]*Data{
+		{
+			TableName: "d1",
+			Fields:    fields,
+			Data: [][]driver.Value{
+				{ts, float32(10.5), int32(220), "12.34"},
+				{ts.Add(time.Second), nil, nil, nil},
+			},
+		},
+		{
+			TableName: "d2",
+			Fields:    reordered,
+			Data: [][]driver.Value{
+				{int32(230), ts, "1.50", float32(11)},
+			},
+		},
+	})
+	var meters []meter
+	require.NoError(t, message.Decode(&meters))
+	current := float32(10.5)
+	current2 := float32(11)
+	assert.Equal(t, []meter{
+		{Table: "d1", Ts: ts, TsMs: 1626006833639, Current: &current, Voltage: types.NullInt32{Inner: 220, Valid: true}, Price: "12.34", Amount: 12.34},
+		{Table: "d1", Ts: ts.Add(time.Second), TsMs: 1626006834639},
+		{Table: "d2", Ts: ts, TsMs: 1626006833639, Current: &current2, Voltage: types.NullInt32{Inner: 230, Valid: true}, Price: "1.50", Amount: 1.5},
+	}, meters)
+	assert.Equal(t, loc, meters[0].Ts.Location())
+
+	var pointers []*meter
+	metaData := &MetaDataMessage{}
+	metaData.SetMetaData(&MetaData{Data: message.Value().([]*Data)})
+	require.NoError(t, metaData.Decode(&pointers))
+	assert.Equal(t, 3, len(pointers))
+}
+
+func TestDecodeDataErrors(t *testing.T) {
+	var meters []meter
+	err := DecodeData([]*Data{{TableName: "d1", Data: [][]driver.Value{{1}}}}, &meters)
+	assert.Error(t, err)
+	err = DecodeData([]*Data{{TableName: "d1", Fields: []*Field{{Type: common.TSDB_DATA_TYPE_INT}}, Data: [][]driver.Value{{1}}}}, &meters)
+	assert.Error(t, err)
+	err = DecodeData([]*Data{{TableName: "d1", Fields: []*Field{{Name: "voltage", Type: common.TSDB_DATA_TYPE_INT}}, Data: [][]driver.Value{{1, 2}}}}, &meters)
+	assert.Error(t, err)
+	err = DecodeData([]*Data{{TableName: "d1", Fields: []*Field{{Name: "voltage", Type: common.TSDB_DATA_TYPE_INT}}, Data: [][]driver.Value{{int32(1)}}}}, meters)
+	assert.Error(t, err)
+	// blocks without rows are skipped
+	assert.NoError(t, DecodeData([]*Data{{TableName: "d1"}}, &meters))
+}
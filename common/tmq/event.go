@@ -11,6 +11,8 @@ import (
 type Data struct {
 	TableName string
 	Data      [][]driver.Value
+	// Fields describes the columns of Data in order
+	Fields []*Field `json:",omitempty"`
 }
 type Event interface {
 	String() string
@@ -0,0 +1,25 @@
+package tmq
+
+import "github.com/taosdata/driver-go/v3/common"
+
+// Field describes a column of a consumed data block.
+type Field struct {
+	Name string
+	// Type is one of the common.TSDB_DATA_TYPE_* types
+	Type int
+	// Bytes is the storage length of the column, including the length header of variable length types
+	Bytes int
+	// Precision is the timestamp precision (common.PrecisionMilliSecond, ...) of TIMESTAMP columns
+	// and the precision of DECIMAL columns
+	Precision int
+	// Scale is the scale of DECIMAL columns
+	Scale int
+}
+
+// TypeName returns the TDengine name of the column type, such as "INT" or "VARCHAR".
+func (f *Field) TypeName() string {
+	if f.Type < 0 || f.Type >= len(common.TypeNameArray) {
+		return ""
+	}
+	return common.TypeNameArray[f.Type]
+}
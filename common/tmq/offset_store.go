@@ -0,0 +1,232 @@
+package tmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// OffsetStore saves the offsets of a consumer group outside of TDengine, for example in the database of a sink
+// in the same transaction as the consumed data. The stored offset of a vgroup is the offset of the next message to consume.
+type OffsetStore interface {
+	// Load returns the stored offsets of the partitions in order,
+	// the Offset of a partition without stored offset is OffsetInvalid.
+	Load(groupID string, partitions []TopicPartition) ([]TopicPartition, error)
+	// Store saves the offsets of the partitions.
+	Store(groupID string, offsets []TopicPartition) error
+}
+
+type OffsetStoreMode int
+
+const (
+	// StoreAndCommit saves the offsets in the OffsetStore and commits them to the server
+	StoreAndCommit OffsetStoreMode = iota
+	// StoreOnly saves the offsets only in the OffsetStore, Committed reads them from the store
+	StoreOnly
+)
+
+// groupOffsets is the offset of each vgroup of each topic
+type groupOffsets map[string]map[int32]int64
+
+func (o groupOffsets) load(partitions []TopicPartition) []TopicPartition {
+	offsets := make([]TopicPartition, len(partitions))
+	for i, partition := range partitions {
+		offsets[i] = TopicPartition{Topic: partition.Topic, Partition: partition.Partition, Offset: OffsetInvalid}
+		if partition.Topic == nil {
+			continue
+		}
+		if offset, exist := o[*partition.Topic][partition.Partition]; exist {
+			offsets[i].Offset = Offset(offset)
+		}
+	}
+	return offsets
+}
+
+func (o groupOffsets) store(offsets []TopicPartition) error {
+	for _, offset := range offsets {
+		if offset.Topic == nil {
+			return fmt.Errorf("tmq: offset of vgroup %d without topic", offset.Partition)
+		}
+		vgroups, exist := o[*offset.Topic]
+		if !exist {
+			vgroups = make(map[int32]int64)
+			o[*offset.Topic] = vgroups
+		}
+		vgroups[offset.Partition] = int64(offset.Offset)
+	}
+	return nil
+}
+
+// MemoryOffsetStore keeps offsets in memory, they are lost when the process exits.
+type MemoryOffsetStore struct {
+	lock   sync.RWMutex
+	groups map[string]groupOffsets
+}
+
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{groups: make(map[string]groupOffsets)}
+}
+
+func (s *MemoryOffsetStore) Load(groupID string, partitions []TopicPartition) ([]TopicPartition, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.groups[groupID].load(partitions), nil
+}
+
+func (s *MemoryOffsetStore) Store(groupID string, offsets []TopicPartition) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, exist := s.groups[groupID]
+	if !exist {
+		group = make(groupOffsets)
+		s.groups[groupID] = group
+	}
+	return group.store(offsets)
+}
+
+// FileOffsetStore keeps offsets in a JSON file. The file is replaced atomically on every Store,
+// so it always contains the offsets of a complete Store call.
+type FileOffsetStore struct {
+	lock   sync.Mutex
+	path   string
+	groups map[string]groupOffsets
+}
+
+// fileOffsets is the content of the file, group id -> topic -> vgroup id -> offset
+type fileOffsets map[string]map[string]map[string]int64
+
+// NewFileOffsetStore opens the offset file at path, the file is created by the first Store if it does not exist.
+func NewFileOffsetStore(path string) (*FileOffsetStore, error) {
+	s := &FileOffsetStore{path: path, groups: make(map[string]groupOffsets)}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var stored fileOffsets
+	if err = json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("tmq: invalid offset file %s: %w", path, err)
+	}
+	for groupID, topics := range stored {
+		group := make(groupOffsets, len(topics))
+		for topic, vgroups := range topics {
+			group[topic] = make(map[int32]int64, len(vgroups))
+			for vgroupID, offset := range vgroups {
+				id, err := strconv.ParseInt(vgroupID, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("tmq: invalid vgroup id %q in offset file %s", vgroupID, path)
+				}
+				group[topic][int32(id)] = offset
+			}
+		}
+		s.groups[groupID] = group
+	}
+	return s, nil
+}
+
+func (s *FileOffsetStore) Load(groupID string, partitions []TopicPartition) ([]TopicPartition, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.groups[groupID].load(partitions), nil
+}
+
+func (s *FileOffsetStore) Store(groupID string, offsets []TopicPartition) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, exist := s.groups[groupID]
+	if !exist {
+		group = make(groupOffsets)
+	}
+	// stored in a copy, the offsets are kept in memory only if the file is written
+	updated := make(groupOffsets, len(group))
+	for topic, vgroups := range group {
+		updated[topic] = make(map[int32]int64, len(vgroups))
+		for vgroupID, offset := range vgroups {
+			updated[topic][vgroupID] = offset
+		}
+	}
+	if err := updated.store(offsets); err != nil {
+		return err
+	}
+	stored := make(fileOffsets, len(s.groups)+1)
+	for id, g := range s.groups {
+		stored[id] = g.toFile()
+	}
+	stored[groupID] = updated.toFile()
+	if err := s.write(stored); err != nil {
+		return err
+	}
+	s.groups[groupID] = updated
+	return nil
+}
+
+func (o groupOffsets) toFile() map[string]map[string]int64 {
+	topics := make(map[string]map[string]int64, len(o))
+	for topic, vgroups := range o {
+		topics[topic] = make(map[string]int64, len(vgroups))
+		for vgroupID, offset := range vgroups {
+			topics[topic][strconv.FormatInt(int64(vgroupID), 10)] = offset
+		}
+	}
+	return topics
+}
+
+func (s *FileOffsetStore) write(stored fileOffsets) error {
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Seeker is the part of the native and websocket consumers used to restore offsets.
+type Seeker interface {
+	Seek(partition TopicPartition, ignoredTimeoutMs int) error
+}
+
+// RestoreOffsets seeks the partitions that have an offset in the store and returns the restored offsets.
+func RestoreOffsets(consumer Seeker, store OffsetStore, groupID string, partitions []TopicPartition) ([]TopicPartition, error) {
+	if len(partitions) == 0 {
+		return nil, nil
+	}
+	offsets, err := store.Load(groupID, partitions)
+	if err != nil {
+		return nil, err
+	}
+	restored := make([]TopicPartition, 0, len(offsets))
+	for _, offset := range offsets {
+		if offset.Offset == OffsetInvalid || offset.Offset < 0 {
+			continue
+		}
+		if err = consumer.Seek(offset, 0); err != nil {
+			return nil, err
+		}
+		restored = append(restored, offset)
+	}
+	return restored, nil
+}
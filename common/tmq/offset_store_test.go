@@ -0,0 +1,118 @@
+package tmq
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func partitions(topic string, vgroups ...int32) []TopicPartition {
+	result := make([]TopicPartition, len(vgroups))
+	for i, vgroup := range vgroups {
+		result[i] = TopicPartition{Topic: &topic, Partition: vgroup}
+	}
+	return result
+}
+
+func testOffsetStore(t *testing.T, store OffsetStore) {
+	offsets, err := store.Load("g1", partitions("t1", 1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, OffsetInvalid, offsets[0].Offset)
+	assert.Equal(t, OffsetInvalid, offsets[1].Offset)
+
+	stored := partitions("t1", 1, 2)
+	stored[0].Offset = 10
+	stored[1].Offset = 20
+	require.NoError(t, store.Store("g1", stored))
+	stored = partitions("t1", 2)
+	stored[0].Offset = 30
+	require.NoError(t, store.Store("g2", stored))
+
+	offsets, err = store.Load("g1", partitions("t1", 2, 1, 3))
+	require.NoError(t, err)
+	assert.Equal(t, "t1", *offsets[0].Topic)
+	assert.Equal(t, int32(2), offsets[0].Partition)
+	assert.Equal(t, Offset(20), offsets[0].Offset)
+	assert.Equal(t, Offset(10), offsets[1].Offset)
+	assert.Equal(t, OffsetInvalid, offsets[2].Offset)
+	offsets, err = store.Load("g2", partitions("t1", 2))
+	require.NoError(t, err)
+	assert.Equal(t, Offset(30), offsets[0].Offset)
+	offsets, err = store.Load("g1", partitions("t2", 1))
+	require.NoError(t, err)
+	assert.Equal(t, OffsetInvalid, offsets[0].Offset)
+
+	assert.Error(t, store.Store("g1", []TopicPartition{{Partition: 1}}))
+}
+
+func TestMemoryOffsetStore(t *testing.T) {
+	testOffsetStore(t, NewMemoryOffsetStore())
+}
+
+func TestFileOffsetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offset_store")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "offsets.json")
+	store, err := NewFileOffsetStore(path)
+	require.NoError(t, err)
+	testOffsetStore(t, store)
+
+	reopened, err := NewFileOffsetStore(path)
+	require.NoError(t, err)
+	offsets, err := reopened.Load("g1", partitions("t1", 1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, Offset(10), offsets[0].Offset)
+	assert.Equal(t, Offset(20), offsets[1].Offset)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(files), "temporary files must be removed")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0644))
+	_, err = NewFileOffsetStore(path)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"g1":{"t1":{"a":1}}}`), 0644))
+	_, err = NewFileOffsetStore(path)
+	assert.Error(t, err)
+}
+
+type fakeSeeker struct {
+	seeks []TopicPartition
+	err   error
+}
+
+func (s *fakeSeeker) Seek(partition TopicPartition, _ int) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.seeks = append(s.seeks, partition)
+	return nil
+}
+
+func TestRestoreOffsets(t *testing.T) {
+	store := NewMemoryOffsetStore()
+	stored := partitions("t1", 1)
+	stored[0].Offset = 10
+	require.NoError(t, store.Store("g1", stored))
+	seeker := &fakeSeeker{}
+	restored, err := RestoreOffsets(seeker, store, "g1", partitions("t1", 1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(restored))
+	assert.Equal(t, Offset(10), restored[0].Offset)
+	assert.Equal(t, restored, seeker.seeks)
+
+	restored, err = RestoreOffsets(seeker, store, "g1", nil)
+	require.NoError(t, err)
+	assert.Nil(t, restored)
+
+	seeker.err = errors.New("seek error")
+	_, err = RestoreOffsets(seeker, store, "g1", partitions("t1", 1))
+	assert.Equal(t, seeker.err, err)
+}
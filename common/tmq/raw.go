@@ -0,0 +1,80 @@
+package tmq
+
+import (
+	"fmt"
+)
+
+// RawData is the raw payload of a consumed message, the content of tmq_raw_data.
+// It is opaque to the client and can be written to another cluster as is.
+type RawData struct {
+	// MetaType is the raw_type of tmq_raw_data
+	MetaType uint16
+	Data     []byte
+}
+
+// RawMessage is a message polled without parsing, used to replicate topics between clusters.
+type RawMessage struct {
+	TopicPartition TopicPartition
+	dbName         string
+	topic          string
+	offset         Offset
+	// messageType is common.TMQ_RES_DATA, common.TMQ_RES_TABLE_META or common.TMQ_RES_METADATA
+	messageType int32
+	raw         *RawData
+}
+
+func (m *RawMessage) String() string {
+	var length int
+	var metaType uint16
+	if m.raw != nil {
+		length = len(m.raw.Data)
+		metaType = m.raw.MetaType
+	}
+	return fmt.Sprintf("RawMessage: %s[%s]:type %d, meta type %d, %d bytes", m.topic, m.dbName, m.messageType, metaType, length)
+}
+
+func (m *RawMessage) SetDbName(dbName string) {
+	m.dbName = dbName
+}
+
+func (m *RawMessage) SetTopic(topic string) {
+	m.topic = topic
+}
+
+func (m *RawMessage) SetOffset(offset Offset) {
+	m.offset = offset
+}
+
+func (m *RawMessage) SetMessageType(messageType int32) {
+	m.messageType = messageType
+}
+
+func (m *RawMessage) SetRaw(raw *RawData) {
+	m.raw = raw
+}
+
+func (m *RawMessage) Topic() string {
+	return m.topic
+}
+
+func (m *RawMessage) DBName() string {
+	return m.dbName
+}
+
+func (m *RawMessage) Offset() Offset {
+	return m.offset
+}
+
+// MessageType returns common.TMQ_RES_DATA, common.TMQ_RES_TABLE_META or common.TMQ_RES_METADATA.
+func (m *RawMessage) MessageType() int32 {
+	return m.messageType
+}
+
+// Value returns the *RawData of the message.
+func (m *RawMessage) Value() interface{} {
+	return m.raw
+}
+
+func (m *RawMessage) Raw() *RawData {
+	return m.raw
+}
@@ -0,0 +1,66 @@
+package tmq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AssignedPartitions is passed to the rebalance callback when vgroups are assigned to the consumer.
+type AssignedPartitions struct {
+	Partitions []TopicPartition
+}
+
+func (e AssignedPartitions) String() string {
+	return fmt.Sprintf("AssignedPartitions: %s", formatPartitions(e.Partitions))
+}
+
+// RevokedPartitions is passed to the rebalance callback when vgroups are no longer assigned to the consumer.
+type RevokedPartitions struct {
+	Partitions []TopicPartition
+}
+
+func (e RevokedPartitions) String() string {
+	return fmt.Sprintf("RevokedPartitions: %s", formatPartitions(e.Partitions))
+}
+
+func formatPartitions(partitions []TopicPartition) string {
+	items := make([]string, len(partitions))
+	for i, partition := range partitions {
+		items[i] = partition.String()
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// DiffAssignment compares two assignments by topic and vgroup, it returns the partitions of current
+// that are not in previous and the partitions of previous that are not in current.
+func DiffAssignment(previous, current []TopicPartition) (assigned, revoked []TopicPartition) {
+	type key struct {
+		topic     string
+		partition int32
+	}
+	keyOf := func(partition TopicPartition) key {
+		k := key{partition: partition.Partition}
+		if partition.Topic != nil {
+			k.topic = *partition.Topic
+		}
+		return k
+	}
+	previousKeys := make(map[key]struct{}, len(previous))
+	for _, partition := range previous {
+		previousKeys[keyOf(partition)] = struct{}{}
+	}
+	currentKeys := make(map[key]struct{}, len(current))
+	for _, partition := range current {
+		k := keyOf(partition)
+		currentKeys[k] = struct{}{}
+		if _, exist := previousKeys[k]; !exist {
+			assigned = append(assigned, partition)
+		}
+	}
+	for _, partition := range previous {
+		if _, exist := currentKeys[keyOf(partition)]; !exist {
+			revoked = append(revoked, partition)
+		}
+	}
+	return assigned, revoked
+}
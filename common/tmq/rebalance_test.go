@@ -0,0 +1,33 @@
+package tmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffAssignment(t *testing.T) {
+	previous := append(partitions("t1", 1, 2), partitions("t2", 1)...)
+	current := append(partitions("t1", 2, 3), partitions("t2", 1)...)
+	// offsets are ignored
+	current[1].Offset = 100
+	assigned, revoked := DiffAssignment(previous, current)
+	assert.Equal(t, []TopicPartition{current[1]}, assigned)
+	assert.Equal(t, []TopicPartition{previous[0]}, revoked)
+
+	assigned, revoked = DiffAssignment(nil, previous)
+	assert.Equal(t, previous, assigned)
+	assert.Nil(t, revoked)
+	assigned, revoked = DiffAssignment(previous, nil)
+	assert.Nil(t, assigned)
+	assert.Equal(t, previous, revoked)
+}
+
+func TestRebalanceEvents(t *testing.T) {
+	event := AssignedPartitions{Partitions: partitions("t1", 1, 2)}
+	assert.Equal(t, "AssignedPartitions: [t1[1]@0, t1[2]@0]", event.String())
+	revoked := RevokedPartitions{Partitions: partitions("t1", 1)}
+	assert.Equal(t, "RevokedPartitions: [t1[1]@0]", revoked.String())
+	var _ Event = event
+	var _ Event = revoked
+}
@@ -0,0 +1,340 @@
+package tmq
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// Consumer is the part of the native and websocket consumers used by Runner.
+type Consumer interface {
+	Poll(timeoutMs int) Event
+	Position(partitions []TopicPartition) ([]TopicPartition, error)
+	CommitOffsets(offsets []TopicPartition) ([]TopicPartition, error)
+}
+
+// Handler processes a *DataMessage, *MetaMessage or *MetaDataMessage.
+type Handler func(ctx context.Context, message Event) error
+
+type RunnerConfig struct {
+	workers        int
+	queueSize      int
+	pollTimeoutMs  int
+	commitInterval time.Duration
+	errorHandler   func(error) error
+}
+
+// SetWorkers sets the number of goroutines running the handler, default 4.
+func SetWorkers(workers int) func(*RunnerConfig) {
+	return func(c *RunnerConfig) {
+		c.workers = workers
+	}
+}
+
+// SetQueueSize sets the number of messages waiting for each worker before polling blocks, default 16.
+func SetQueueSize(queueSize int) func(*RunnerConfig) {
+	return func(c *RunnerConfig) {
+		c.queueSize = queueSize
+	}
+}
+
+// SetPollTimeout sets the timeout of each poll, default 100ms.
+func SetPollTimeout(timeoutMs int) func(*RunnerConfig) {
+	return func(c *RunnerConfig) {
+		c.pollTimeoutMs = timeoutMs
+	}
+}
+
+// SetCommitInterval sets how often the offsets of handled messages are committed, default 1s.
+// With 0 the offsets are committed after every poll.
+func SetCommitInterval(commitInterval time.Duration) func(*RunnerConfig) {
+	return func(c *RunnerConfig) {
+		c.commitInterval = commitInterval
+	}
+}
+
+// SetErrorHandler sets the handler of poll and commit errors. Run continues if it returns nil
+// and stops with the returned error otherwise. Without a handler Run stops with the first error.
+func SetErrorHandler(errorHandler func(error) error) func(*RunnerConfig) {
+	return func(c *RunnerConfig) {
+		c.errorHandler = errorHandler
+	}
+}
+
+// Runner polls a consumer and dispatches the messages to a pool of workers.
+//
+// Messages of the same vgroup (topic and TopicPartition.Partition) are always handled by the same worker
+// in the order they were polled. The offset of a vgroup is committed only up to the first message that
+// has not been handled successfully, so a message is never committed before its handler returned nil
+// and the messages that were not handled are consumed again after a restart.
+type Runner struct {
+	consumer Consumer
+	config   RunnerConfig
+}
+
+func NewRunner(consumer Consumer, opts ...func(*RunnerConfig)) *Runner {
+	config := RunnerConfig{
+		workers:        4,
+		queueSize:      16,
+		pollTimeoutMs:  100,
+		commitInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.workers <= 0 {
+		config.workers = 1
+	}
+	if config.queueSize < 0 {
+		config.queueSize = 0
+	}
+	if config.pollTimeoutMs <= 0 {
+		config.pollTimeoutMs = 100
+	}
+	return &Runner{consumer: consumer, config: config}
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+type partitionState struct {
+	topic     *string
+	partition int32
+	// start offsets of the dispatched messages not handled yet, in poll order
+	pending []Offset
+	dirty   bool
+}
+
+type runnerTask struct {
+	key     partitionKey
+	message Event
+}
+
+type runnerResult struct {
+	key  partitionKey
+	done bool
+}
+
+// Run polls and handles messages until ctx is cancelled, a handler fails or an error is not handled by the error handler.
+// On return the messages being handled are finished, the queued messages are dropped
+// and the offsets of the handled messages are committed.
+// Run returns nil when ctx is cancelled and the first error otherwise.
+func (r *Runner) Run(ctx context.Context, handler Handler) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := r.config.workers
+	queues := make([]chan *runnerTask, workers)
+	// buffered for every message a worker can hold, so workers rarely wait for the poll loop
+	results := make(chan *runnerResult, workers*(r.config.queueSize+1))
+	errCh := make(chan error, workers)
+	done := make(chan struct{})
+	remaining := workers
+	for i := 0; i < workers; i++ {
+		queues[i] = make(chan *runnerTask, r.config.queueSize)
+		go func(queue chan *runnerTask) {
+			defer func() {
+				done <- struct{}{}
+			}()
+			for task := range queue {
+				if runCtx.Err() != nil {
+					results <- &runnerResult{key: task.key}
+					continue
+				}
+				if err := handler(runCtx, task.message); err != nil {
+					if ctx.Err() == nil {
+						errCh <- err
+					}
+					// stop before the next message of the vgroup is handled
+					cancel()
+					results <- &runnerResult{key: task.key}
+					continue
+				}
+				results <- &runnerResult{key: task.key, done: true}
+			}
+		}(queues[i])
+	}
+
+	states := make(map[partitionKey]*partitionState)
+	handleResult := func(result *runnerResult) {
+		if !result.done {
+			return
+		}
+		state := states[result.key]
+		state.pending = state.pending[1:]
+		state.dirty = true
+	}
+	var runErr error
+	fail := func(err error) {
+		if r.config.errorHandler != nil {
+			err = r.config.errorHandler(err)
+		}
+		if err != nil && runErr == nil {
+			runErr = err
+			cancel()
+		}
+	}
+	nextCommit := time.Now().Add(r.config.commitInterval)
+	for runCtx.Err() == nil {
+		for drained := false; !drained; {
+			select {
+			case result := <-results:
+				handleResult(result)
+			default:
+				drained = true
+			}
+		}
+		if !time.Now().Before(nextCommit) {
+			if err := r.commit(states); err != nil {
+				fail(err)
+			}
+			nextCommit = time.Now().Add(r.config.commitInterval)
+			if runCtx.Err() != nil {
+				break
+			}
+		}
+		event := r.consumer.Poll(r.config.pollTimeoutMs)
+		var tp TopicPartition
+		switch e := event.(type) {
+		case nil:
+			continue
+		case Error:
+			fail(e)
+			continue
+		case *DataMessage:
+			tp = e.TopicPartition
+		case *MetaMessage:
+			tp = e.TopicPartition
+		case *MetaDataMessage:
+			tp = e.TopicPartition
+		default:
+			continue
+		}
+		key := partitionKey{partition: tp.Partition}
+		if tp.Topic != nil {
+			key.topic = *tp.Topic
+		}
+		state, exist := states[key]
+		if !exist {
+			state = &partitionState{topic: tp.Topic, partition: tp.Partition}
+			states[key] = state
+		}
+		// the message is pending even if it is never dispatched, so its offset is not committed
+		state.pending = append(state.pending, tp.Offset)
+		queue := queues[workerIndex(key, workers)]
+		task := &runnerTask{key: key, message: event}
+		for sent := false; !sent; {
+			select {
+			case queue <- task:
+				sent = true
+			case result := <-results:
+				handleResult(result)
+			case <-runCtx.Done():
+				sent = true
+			}
+		}
+	}
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	for remaining > 0 {
+		select {
+		case result := <-results:
+			handleResult(result)
+		case <-done:
+			remaining--
+		}
+	}
+	for drained := false; !drained; {
+		select {
+		case result := <-results:
+			handleResult(result)
+		default:
+			drained = true
+		}
+	}
+	select {
+	case err := <-errCh:
+		if runErr == nil {
+			runErr = err
+		}
+	default:
+	}
+	if err := r.commit(states); err != nil && runErr == nil {
+		if r.config.errorHandler != nil {
+			err = r.config.errorHandler(err)
+		}
+		runErr = err
+	}
+	return runErr
+}
+
+func workerIndex(key partitionKey, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key.topic))
+	return int((h.Sum32() + uint32(key.partition)) % uint32(workers))
+}
+
+// commit commits the start offset of the first pending message of each vgroup,
+// or the consumer position if all polled messages of the vgroup are handled.
+func (r *Runner) commit(states map[partitionKey]*partitionState) error {
+	var offsets, idle []TopicPartition
+	var committed []*partitionState
+	for _, state := range states {
+		if !state.dirty {
+			continue
+		}
+		if len(state.pending) != 0 {
+			offsets = append(offsets, TopicPartition{Topic: state.topic, Partition: state.partition, Offset: state.pending[0]})
+		} else {
+			idle = append(idle, TopicPartition{Topic: state.topic, Partition: state.partition})
+		}
+		committed = append(committed, state)
+	}
+	if len(idle) != 0 {
+		positions, err := r.consumer.Position(idle)
+		if err != nil {
+			return err
+		}
+		for _, position := range positions {
+			if position.Offset >= 0 {
+				offsets = append(offsets, position)
+			}
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+	_, err := r.consumer.CommitOffsets(offsets)
+	if err != nil {
+		return err
+	}
+	for _, state := range committed {
+		state.dirty = false
+	}
+	return nil
+}
+
+// Events polls the consumer in a goroutine and sends the polled events, including errors, to the returned channel
+// until ctx is cancelled, then the channel is closed. Offsets are not committed, and Events must not be used
+// together with Run or Poll on the same consumer.
+func (r *Runner) Events(ctx context.Context) <-chan Event {
+	events := make(chan Event, r.config.queueSize)
+	go func() {
+		defer close(events)
+		for ctx.Err() == nil {
+			event := r.consumer.Poll(r.config.pollTimeoutMs)
+			if event == nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
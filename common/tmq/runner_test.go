@@ -0,0 +1,235 @@
+package tmq
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsumer struct {
+	lock      sync.Mutex
+	events    []Event
+	positions map[int32]Offset
+	committed map[int32]Offset
+	commitErr error
+}
+
+func newFakeConsumer(events ...Event) *fakeConsumer {
+	c := &fakeConsumer{
+		events:    events,
+		positions: make(map[int32]Offset),
+		committed: make(map[int32]Offset),
+	}
+	return c
+}
+
+func (c *fakeConsumer) Poll(_ int) Event {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.events) == 0 {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	event := c.events[0]
+	c.events = c.events[1:]
+	if m, ok := event.(*DataMessage); ok {
+		// the position is after the polled message
+		c.positions[m.TopicPartition.Partition] = m.TopicPartition.Offset + 1
+	}
+	return event
+}
+
+func (c *fakeConsumer) Position(partitions []TopicPartition) ([]TopicPartition, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result := make([]TopicPartition, len(partitions))
+	for i, partition := range partitions {
+		result[i] = TopicPartition{Topic: partition.Topic, Partition: partition.Partition, Offset: c.positions[partition.Partition]}
+	}
+	return result, nil
+}
+
+func (c *fakeConsumer) CommitOffsets(offsets []TopicPartition) ([]TopicPartition, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.commitErr != nil {
+		return nil, c.commitErr
+	}
+	for _, offset := range offsets {
+		c.committed[offset.Partition] = offset.Offset
+	}
+	return offsets, nil
+}
+
+func (c *fakeConsumer) getCommitted() map[int32]Offset {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	committed := make(map[int32]Offset, len(c.committed))
+	for k, v := range c.committed {
+		committed[k] = v
+	}
+	return committed
+}
+
+func (c *fakeConsumer) empty() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.events) == 0
+}
+
+func dataMessage(partition int32, offset Offset) *DataMessage {
+	topic := "topic"
+	m := &DataMessage{TopicPartition: TopicPartition{Topic: &topic, Partition: partition, Offset: offset}}
+	m.SetTopic(topic)
+	m.SetOffset(offset)
+	return m
+}
+
+func TestRunnerOrderAndCommit(t *testing.T) {
+	var events []Event
+	for offset := Offset(0); offset < 20; offset++ {
+		for partition := int32(0); partition < 5; partition++ {
+			events = append(events, dataMessage(partition, offset))
+		}
+	}
+	consumer := newFakeConsumer(events...)
+	runner := NewRunner(consumer, SetWorkers(3), SetQueueSize(2), SetCommitInterval(0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	handled := make(map[int32][]Offset)
+	count := 0
+	err := runner.Run(ctx, func(_ context.Context, message Event) error {
+		m := message.(*DataMessage)
+		lock.Lock()
+		defer lock.Unlock()
+		handled[m.TopicPartition.Partition] = append(handled[m.TopicPartition.Partition], m.Offset())
+		count++
+		if count == len(events) {
+			cancel()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	for partition := int32(0); partition < 5; partition++ {
+		offsets := handled[partition]
+		require.Equal(t, 20, len(offsets))
+		assert.True(t, sort.SliceIsSorted(offsets, func(i, j int) bool { return offsets[i] < offsets[j] }), "partition %d out of order: %v", partition, offsets)
+	}
+	assert.Equal(t, map[int32]Offset{0: 20, 1: 20, 2: 20, 3: 20, 4: 20}, consumer.getCommitted())
+}
+
+func TestRunnerHandlerError(t *testing.T) {
+	consumer := newFakeConsumer(
+		dataMessage(0, 0),
+		dataMessage(1, 0),
+		dataMessage(0, 1),
+		dataMessage(1, 1),
+		dataMessage(0, 2),
+		dataMessage(1, 2),
+	)
+	runner := NewRunner(consumer, SetWorkers(2), SetCommitInterval(time.Hour))
+	handlerErr := errors.New("handler error")
+	var lock sync.Mutex
+	var handled []Offset
+	block := make(chan struct{})
+	err := runner.Run(context.Background(), func(_ context.Context, message Event) error {
+		m := message.(*DataMessage)
+		if m.TopicPartition.Partition == 0 {
+			lock.Lock()
+			handled = append(handled, m.Offset())
+			lock.Unlock()
+			if m.Offset() == 1 {
+				<-block
+				return handlerErr
+			}
+			return nil
+		}
+		if m.Offset() == 0 {
+			close(block)
+		}
+		return nil
+	})
+	assert.Equal(t, handlerErr, err)
+	// the message after the failed one is not handled
+	assert.Equal(t, []Offset{0, 1}, handled)
+	committed := consumer.getCommitted()
+	// committed up to the failed message
+	assert.Equal(t, Offset(1), committed[0])
+}
+
+func TestRunnerCancel(t *testing.T) {
+	consumer := newFakeConsumer(dataMessage(0, 0), dataMessage(0, 1), dataMessage(0, 2))
+	runner := NewRunner(consumer, SetWorkers(1), SetCommitInterval(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	err := runner.Run(ctx, func(handlerCtx context.Context, message Event) error {
+		if message.(*DataMessage).Offset() == 1 {
+			cancel()
+			<-handlerCtx.Done()
+			return handlerCtx.Err()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int32]Offset{0: 1}, consumer.getCommitted())
+}
+
+func TestRunnerPollError(t *testing.T) {
+	pollErr := NewTMQError(0x1, "poll error")
+	consumer := newFakeConsumer(pollErr, dataMessage(0, 0))
+	runner := NewRunner(consumer)
+	err := runner.Run(context.Background(), func(_ context.Context, message Event) error {
+		return nil
+	})
+	assert.Equal(t, pollErr, err)
+
+	consumer = newFakeConsumer(pollErr, dataMessage(0, 0))
+	var errs []error
+	runner = NewRunner(consumer, SetErrorHandler(func(err error) error {
+		errs = append(errs, err)
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	err = runner.Run(ctx, func(_ context.Context, message Event) error {
+		cancel()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{pollErr}, errs)
+	assert.Equal(t, map[int32]Offset{0: 1}, consumer.getCommitted())
+}
+
+func TestRunnerCommitError(t *testing.T) {
+	consumer := newFakeConsumer(dataMessage(0, 0))
+	commitErr := errors.New("commit error")
+	consumer.commitErr = commitErr
+	runner := NewRunner(consumer, SetCommitInterval(0))
+	err := runner.Run(context.Background(), func(_ context.Context, message Event) error {
+		return nil
+	})
+	assert.Equal(t, commitErr, err)
+}
+
+func TestRunnerEvents(t *testing.T) {
+	consumer := newFakeConsumer(dataMessage(0, 0), NewTMQError(0x1, "poll error"), dataMessage(0, 1))
+	runner := NewRunner(consumer, SetQueueSize(0))
+	ctx, cancel := context.WithCancel(context.Background())
+	events := runner.Events(ctx)
+	var received []Event
+	for i := 0; i < 3; i++ {
+		received = append(received, <-events)
+	}
+	assert.Equal(t, Offset(0), received[0].(*DataMessage).Offset())
+	assert.Equal(t, 0x1, received[1].(Error).Code())
+	assert.Equal(t, Offset(1), received[2].(*DataMessage).Offset())
+	assert.True(t, consumer.empty())
+	cancel()
+	for range events {
+	}
+}
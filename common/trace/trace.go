@@ -0,0 +1,180 @@
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// Span names of the traced operations.
+const (
+	SpanQuery            = "tdengine.query"
+	SpanFetch            = "tdengine.fetch"
+	SpanStmtBind         = "tdengine.stmt.bind"
+	SpanStmtExec         = "tdengine.stmt.exec"
+	SpanSchemalessInsert = "tdengine.schemaless.insert"
+	SpanTMQPoll          = "tdengine.tmq.poll"
+	SpanTMQCommit        = "tdengine.tmq.commit"
+)
+
+// Attribute keys recorded on spans.
+const (
+	AttrSQL       = "db.statement"
+	AttrReqID     = "tdengine.req_id"
+	AttrReqIDs    = "tdengine.req_ids"
+	AttrRows      = "tdengine.rows"
+	AttrBytes     = "tdengine.bytes"
+	AttrErrorCode = "tdengine.error_code"
+	AttrTopic     = "tdengine.tmq.topic"
+	AttrVgroupID  = "tdengine.tmq.vgroup_id"
+)
+
+// Attribute is a key/value recorded on a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttributes records attributes of the operation.
+	SetAttributes(attrs ...Attribute)
+	// End ends the operation, err is nil if it succeeded.
+	End(err error)
+}
+
+// Tracer creates spans, it is usually an adapter of an OpenTelemetry tracer.
+type Tracer interface {
+	// Start starts a span as a child of the span of ctx and returns a context that carries the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+	// SpanID returns the id of the span carried by ctx, ok is false if ctx carries no span.
+	SpanID(ctx context.Context) (spanID [8]byte, ok bool)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) End(error)                  {}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (nopTracer) SpanID(context.Context) ([8]byte, bool) {
+	return [8]byte{}, false
+}
+
+// Nop is a Tracer that records nothing, it is the default tracer.
+var Nop Tracer = nopTracer{}
+
+type tracerHolder struct {
+	tracer Tracer
+	redact func(sql string) string
+}
+
+var global atomic.Value
+
+func init() {
+	global.Store(tracerHolder{tracer: Nop})
+}
+
+// SetTracer sets the tracer of all connectors, nil restores Nop. When redact is not nil,
+// the recorded SQL is redact(sql), RedactLiterals hides the literal values of a statement.
+func SetTracer(tracer Tracer, redact func(sql string) string) {
+	if tracer == nil {
+		tracer = Nop
+	}
+	global.Store(tracerHolder{tracer: tracer, redact: redact})
+}
+
+// GetTracer returns the tracer set by SetTracer.
+func GetTracer() Tracer {
+	return global.Load().(tracerHolder).tracer
+}
+
+// Start starts a span with the tracer set by SetTracer.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return GetTracer().Start(ctx, name, attrs...)
+}
+
+// SQL returns the attribute of a statement, redacted if SetTracer is called with a redact function.
+func SQL(sql string) Attribute {
+	if redact := global.Load().(tracerHolder).redact; redact != nil {
+		sql = redact(sql)
+	}
+	return Attribute{Key: AttrSQL, Value: sql}
+}
+
+func ReqID(reqID int64) Attribute {
+	return Attribute{Key: AttrReqID, Value: reqID}
+}
+
+// ReqIDs returns the attribute of the req_ids of the requests sent by one operation.
+func ReqIDs(reqIDs []int64) Attribute {
+	return Attribute{Key: AttrReqIDs, Value: reqIDs}
+}
+
+func Rows(rows int) Attribute {
+	return Attribute{Key: AttrRows, Value: rows}
+}
+
+func Bytes(bytes int) Attribute {
+	return Attribute{Key: AttrBytes, Value: bytes}
+}
+
+// GetReqID returns the req_id of a request made with ctx: the req_id set with common.ReqIDKey,
+// or the req_id derived from the span carried by ctx, or 0 if there is neither.
+func GetReqID(ctx context.Context) (int64, error) {
+	reqID, err := common.GetReqIDFromCtx(ctx)
+	if err != nil || reqID != 0 {
+		return reqID, err
+	}
+	if spanID, ok := GetTracer().SpanID(ctx); ok {
+		return ReqIDFromSpanID(spanID), nil
+	}
+	return 0, nil
+}
+
+// NewReqID returns the req_id derived from the span carried by ctx, or a new req_id from common.GetReqID.
+func NewReqID(ctx context.Context) int64 {
+	if spanID, ok := GetTracer().SpanID(ctx); ok {
+		return ReqIDFromSpanID(spanID)
+	}
+	return common.GetReqID()
+}
+
+// ReqIDFromSpanID derives a positive req_id from a span id, so that the logs of taosAdapter and taosd
+// can be found from a span.
+func ReqIDFromSpanID(spanID [8]byte) int64 {
+	reqID := int64(binary.BigEndian.Uint64(spanID[:]) & math.MaxInt64)
+	if reqID == 0 {
+		return common.GetReqID()
+	}
+	return reqID
+}
+
+// End records the error code of err, if it is a TDengine error, and ends span.
+func End(span Span, err error) {
+	if err != nil {
+		var taosErr *taosErrors.TaosError
+		if errors.As(err, &taosErr) {
+			span.SetAttributes(Attribute{Key: AttrErrorCode, Value: taosErr.Code})
+		}
+	}
+	span.End(err)
+}
+
+var literalPattern = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"|\b\d+(?:\.\d+)?(?:[eE][-+]?\d+)?\b`)
+
+// RedactLiterals replaces the string and number literals of sql with '?'.
+func RedactLiterals(sql string) string {
+	return literalPattern.ReplaceAllString(sql, "?")
+}
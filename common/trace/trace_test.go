@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+type spanKey struct{}
+
+type recordSpan struct {
+	name  string
+	attrs map[string]interface{}
+	ended bool
+	err   error
+}
+
+func (s *recordSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordTracer struct {
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, [8]byte{0x80, 0, 0, 0, 0, 0, 0, byte(len(t.spans))}), span
+}
+
+func (t *recordTracer) SpanID(ctx context.Context) ([8]byte, bool) {
+	spanID, ok := ctx.Value(spanKey{}).([8]byte)
+	return spanID, ok
+}
+
+func TestTracer(t *testing.T) {
+	defer SetTracer(nil, nil)
+	assert.Equal(t, Nop, GetTracer())
+	ctx, span := Start(context.Background(), SpanQuery)
+	assert.Equal(t, context.Background(), ctx)
+	End(span, nil)
+	reqID, err := GetReqID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), reqID)
+
+	tracer := &recordTracer{}
+	SetTracer(tracer, RedactLiterals)
+	ctx, span = Start(context.Background(), SpanQuery, SQL("select * from t1 where ts > 1626006833639 and v = 'a''b'"))
+	reqID, err = GetReqID(ctx)
+	require.NoError(t, err)
+	// the sign bit of the span id is cleared
+	assert.Equal(t, int64(1), reqID)
+	span.SetAttributes(ReqID(reqID), Rows(2), Bytes(10))
+	End(span, taosErrors.NewError(0x2603, "Table does not exist"))
+	recorded := tracer.spans[0]
+	assert.Equal(t, SpanQuery, recorded.name)
+	assert.True(t, recorded.ended)
+	assert.Equal(t, map[string]interface{}{
+		AttrSQL:       "select * from t1 where ts > ? and v = ??",
+		AttrReqID:     int64(1),
+		AttrRows:      2,
+		AttrBytes:     10,
+		AttrErrorCode: int32(0x2603),
+	}, recorded.attrs)
+
+	// the req_id of the context wins
+	//nolint:staticcheck
+	ctx = context.WithValue(ctx, common.ReqIDKey, int64(100))
+	reqID, err = GetReqID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), reqID)
+
+	assert.Equal(t, int64(1), NewReqID(ctx))
+	assert.True(t, NewReqID(context.Background()) > 0)
+
+	_, span = Start(context.Background(), SpanFetch)
+	End(span, errors.New("other error"))
+	_, exist := tracer.spans[1].attrs[AttrErrorCode]
+	assert.False(t, exist)
+}
+
+func TestReqIDFromSpanID(t *testing.T) {
+	assert.Equal(t, int64(0x0102030405060708), ReqIDFromSpanID([8]byte{0x81, 2, 3, 4, 5, 6, 7, 8}))
+	assert.True(t, ReqIDFromSpanID([8]byte{}) > 0)
+}
+
+func TestRedactLiterals(t *testing.T) {
+	assert.Equal(t, "insert into d0 values(?, ?, ?)", RedactLiterals(`insert into d0 values(1626006833639, 'a\'b', "c")`))
+	assert.Equal(t, "select avg(v1) from t1 where v2 > ?", RedactLiterals("select avg(v1) from t1 where v2 > 1.5e3"))
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -17,11 +18,15 @@ import (
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
 )
 
 type taosConn struct {
 	cfg            *Config
+	logger         log.Logger
 	client         *http.Client
 	url            *url.URL
 	timezone       *time.Location
@@ -29,6 +34,7 @@ type taosConn struct {
 	baseRawQuery   string
 	header         map[string][]string
 	readBufferSize int
+	tx             *txBatch
 }
 
 func newTaosConn(cfg *Config) (*taosConn, error) {
@@ -38,6 +44,7 @@ func newTaosConn(cfg *Config) (*taosConn, error) {
 	}
 	tc := &taosConn{
 		cfg:            cfg,
+		logger:         log.Or(cfg.Logger),
 		timezone:       cfg.Timezone,
 		readBufferSize: readBufferSize,
 	}
@@ -97,14 +104,14 @@ func newTaosConn(cfg *Config) (*taosConn, error) {
 		baseRawQueryBuilder.WriteString(tc.timezoneStr)
 	}
 	tc.baseRawQuery = baseRawQueryBuilder.String()
+	tc.logger.Info("connection opened", "host", host)
 	return tc, nil
 }
 
-func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support transaction"}
-}
-
 func (tc *taosConn) Close() (err error) {
+	if tc.url != nil {
+		tc.logger.Info("connection closed", "host", tc.url.Host)
+	}
 	tc.client = nil
 	tc.url = nil
 	tc.cfg = nil
@@ -132,6 +139,9 @@ func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.Nam
 		}
 		query = prepared
 	}
+	if tc.tx != nil {
+		return tc.tx.addInsert(query)
+	}
 	result, err := tc.taosQuery(ctx, query, 512)
 	if err != nil {
 		return nil, err
@@ -147,6 +157,9 @@ func (tc *taosConn) QueryContext(ctx context.Context, query string, args []drive
 }
 
 func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if tc.tx != nil {
+		return nil, errTxBatchNotInsert()
+	}
 	if len(args) != 0 {
 		if !tc.cfg.InterpolateParams {
 			return nil, driver.ErrSkip
@@ -158,36 +171,89 @@ func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.Na
 		}
 		query = prepared
 	}
-	result, err := tc.taosQuery(ctx, query, tc.readBufferSize)
+	return tc.taosQueryStream(ctx, query, tc.readBufferSize)
+}
+
+func (tc *taosConn) Ping(ctx context.Context) (err error) {
+	return nil
+}
+
+func (tc *taosConn) taosQuery(ctx context.Context, sql string, bufferSize int) (data *common.TDEngineRestfulResp, err error) {
+	start := time.Now()
+	ctx, span := trace.Start(ctx, trace.SpanQuery, trace.SQL(sql))
+	defer func() {
+		if err == nil && len(data.Data) == 1 && len(data.Data[0]) == 1 {
+			if affected, ok := data.Data[0][0].(int32); ok {
+				span.SetAttributes(trace.Rows(int(affected)))
+			}
+		}
+		metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), err)
+		trace.End(span, err)
+	}()
+	body, err := tc.sendQuery(ctx, sql, span)
 	if err != nil {
 		return nil, err
 	}
-	if result == nil {
-		return nil, errors.New("wrong result")
+	defer func() {
+		_ = body.Close()
+	}()
+	data, err = common.UnmarshalRestfulBodyWithTimezone(body, bufferSize, tc.timezone)
+	if err != nil {
+		tc.logger.Error("protocol error", "error", err)
+		return nil, err
 	}
-	// Read Result
-	rs := &rows{
-		result: result,
+	metrics.GetMetrics().AddFetched(len(data.Data), body.counter.n)
+	if data.Code != 0 {
+		return nil, taosErrors.NewError(data.Code, data.Desc)
 	}
-	return rs, err
-}
-
-func (tc *taosConn) Ping(ctx context.Context) (err error) {
-	return nil
+	return data, nil
 }
 
-func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support transaction"}
+// taosQueryStream decodes the rows while they are read from the response body,
+// the body is closed by the returned rows.
+func (tc *taosConn) taosQueryStream(ctx context.Context, sql string, bufferSize int) (rs *rows, err error) {
+	start := time.Now()
+	ctx, span := trace.Start(ctx, trace.SpanQuery, trace.SQL(sql))
+	defer func() {
+		metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), err)
+		trace.End(span, err)
+	}()
+	body, err := tc.sendQuery(ctx, sql, span)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := common.NewRestfulRowReader(body, bufferSize, tc.timezone)
+	if err != nil {
+		tc.logger.Error("protocol error", "error", err)
+		_ = body.Close()
+		return nil, err
+	}
+	result := reader.Result()
+	if result.Code != 0 {
+		reader.Close()
+		_ = body.Close()
+		return nil, taosErrors.NewError(result.Code, result.Desc)
+	}
+	// the rows are decoded after the query returns, reading them is traced by a fetch span that ends with the rows
+	_, fetchSpan := trace.Start(ctx, trace.SpanFetch, trace.ReqID(body.reqID))
+	return &rows{
+		result: result,
+		reader: reader,
+		body:   body,
+		span:   fetchSpan,
+	}, nil
 }
 
-func (tc *taosConn) taosQuery(ctx context.Context, sql string, bufferSize int) (*common.TDEngineRestfulResp, error) {
-	reqIDValue, err := common.GetReqIDFromCtx(ctx)
+// sendQuery returns the uncompressed response body of a successful request, the req_id is recorded on span.
+func (tc *taosConn) sendQuery(ctx context.Context, sql string, span trace.Span) (*responseBody, error) {
+	reqIDValue, err := trace.GetReqID(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if reqIDValue == 0 {
 		reqIDValue = common.GetReqID()
 	}
+	span.SetAttributes(trace.ReqID(reqIDValue))
 	tc.url.RawQuery = fmt.Sprintf("%s&req_id=%d", tc.baseRawQuery, reqIDValue)
 	body := ioutil.NopCloser(strings.NewReader(sql))
 	req := &http.Request{
@@ -203,38 +269,75 @@ func (tc *taosConn) taosQuery(ctx context.Context, sql string, bufferSize int) (
 	if ctx != nil {
 		req = req.WithContext(ctx)
 	}
+	tc.logger.Debug("query", "req_id", uint64(reqIDValue))
+	start := time.Now()
 	resp, err := tc.client.Do(req)
 	if err != nil {
+		tc.logger.Warn("request failed", "req_id", uint64(reqIDValue), "error", err)
 		return nil, err
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+	log.Slow(tc.logger, "query", uint64(reqIDValue), start)
 	if resp.StatusCode != http.StatusOK {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("server response: %s - %s", resp.Status, string(body))
+		err = fmt.Errorf("server response: %s - %s", resp.Status, string(body))
+		tc.logger.Error("protocol error", "req_id", uint64(reqIDValue), "error", err)
+		return nil, err
 	}
-	respBody := resp.Body
-	defer func() {
-		_, _ = ioutil.ReadAll(respBody)
-	}()
+	counter := &countingReader{reader: resp.Body}
+	respBody := &responseBody{Reader: counter, body: resp.Body, counter: counter, reqID: reqIDValue}
 	if !tc.cfg.DisableCompression && EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-		respBody, err = gzip.NewReader(resp.Body)
+		gzipReader, err := gzip.NewReader(counter)
 		if err != nil {
+			_ = resp.Body.Close()
 			return nil, err
 		}
+		respBody.Reader = gzipReader
+		respBody.gzipReader = gzipReader
 	}
-	data, err := common.UnmarshalRestfulBodyWithTimezone(respBody, bufferSize, tc.timezone)
-	if err != nil {
-		return nil, err
+	return respBody, nil
+}
+
+type responseBody struct {
+	io.Reader
+	body       io.ReadCloser
+	gzipReader *gzip.Reader
+	// counter counts the bytes received, before they are uncompressed
+	counter *countingReader
+	reqID   int64
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+// Close drains the rest of the body so the http connection can be reused.
+func (b *responseBody) Close() error {
+	_, _ = ioutil.ReadAll(b.Reader)
+	if b.gzipReader != nil {
+		_ = b.gzipReader.Close()
 	}
-	if data.Code != 0 {
-		return nil, taosErrors.NewError(data.Code, data.Desc)
+	return b.body.Close()
+}
+
+// Abort closes the body without reading the rest of a large response, the http connection is not reused.
+func (b *responseBody) Abort() error {
+	if b.gzipReader != nil {
+		_ = b.gzipReader.Close()
 	}
-	return data, nil
+	return b.body.Close()
 }
 
 // EqualFold is strings.EqualFold, ASCII only. It reports whether s and t
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
 	"github.com/taosdata/driver-go/v3/errors"
 )
 
@@ -36,6 +37,8 @@ type Config struct {
 	SkipVerify         bool
 	Timezone           *time.Location // Timezone for connection, e.g., "Asia%2FShanghai" or "UTC"
 	BearerToken        string         // BearerToken for TSDB auth
+	TxBatch            bool           // Begin returns a write batch that buffers inserts until commit
+	Logger             log.Logger     // Logger of the connections, the package-level logger of common/log if nil, not parsed from DSN
 }
 
 // NewConfig creates a new Config and sets default values.
@@ -177,6 +180,11 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			}
 		case "bearerToken":
 			cfg.BearerToken = value
+		case "txBatch":
+			cfg.TxBatch, err = strconv.ParseBool(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid bool value: " + value}
+			}
 		default:
 			// lazy init
 			if cfg.Params == nil {
@@ -335,6 +335,22 @@ func TestParseDsn(t *testing.T) {
 				BearerToken:        "ABmTXHdQAN9au7w4JcXcp4gpXgrDxLxhjaIOiumuA8f1bJDpE3YRDTirvsftPtP",
 			},
 		},
+		{
+			name: "txBatch",
+			dsn:  "@http(:0)/?txBatch=true",
+			want: &Config{
+				DisableCompression: true,
+				ReadBufferSize:     4096,
+				InterpolateParams:  true,
+				Net:                "http",
+				TxBatch:            true,
+			},
+		},
+		{
+			name: "invalid txBatch",
+			dsn:  "@http(:0)/?txBatch=abc",
+			errs: "invalid bool value: abc",
+		},
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
@@ -6,11 +6,20 @@ import (
 	"reflect"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	"github.com/taosdata/driver-go/v3/common/trace"
 )
 
 type rows struct {
-	result   *common.TDEngineRestfulResp
-	rowIndex int
+	result *common.TDEngineRestfulResp
+	reader *common.RestfulRowReader
+	body   *responseBody
+	// fetched is the number of rows read
+	fetched int
+	// span traces reading the rows, it ends when the rows are closed
+	span trace.Span
+	// err is the first error that stopped reading the rows
+	err error
 }
 
 func (rs *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
@@ -41,14 +50,30 @@ func (rs *rows) ColumnTypeScanType(i int) reflect.Type {
 }
 
 func (rs *rows) Close() error {
-	return nil
+	if rs.body == nil {
+		return nil
+	}
+	finished := rs.reader.Finished()
+	rs.reader.Close()
+	body := rs.body
+	rs.body = nil
+	metrics.GetMetrics().AddFetched(rs.fetched, body.counter.n)
+	rs.span.SetAttributes(trace.Rows(rs.fetched), trace.Bytes(body.counter.n))
+	trace.End(rs.span, rs.err)
+	if finished {
+		return body.Close()
+	}
+	// stop reading a result that was not consumed
+	return body.Abort()
 }
 
+// Next decodes the next row from the response body, rows are not buffered.
 func (rs *rows) Next(dest []driver.Value) error {
-	if rs.rowIndex >= len(rs.result.Data) {
-		return io.EOF
+	err := rs.reader.Next(dest)
+	if err == nil {
+		rs.fetched++
+	} else if err != io.EOF && rs.err == nil {
+		rs.err = err
 	}
-	copy(dest, rs.result.Data[rs.rowIndex])
-	rs.rowIndex += 1
-	return nil
+	return err
 }
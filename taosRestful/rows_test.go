@@ -0,0 +1,157 @@
+package taosRestful
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common/trace"
+)
+
+func TestStreamRows(t *testing.T) {
+	const rowCount = 1000
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var writer io.Writer = w
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			gzipWriter := gzip.NewWriter(w)
+			defer func() {
+				_ = gzipWriter.Close()
+			}()
+			writer = gzipWriter
+		}
+		_, _ = io.WriteString(writer, `{"code":0,"column_meta":[["ts","TIMESTAMP",8],["v","INT",4]],"data":[`)
+		for i := 0; i < rowCount; i++ {
+			if i != 0 {
+				_, _ = io.WriteString(writer, ",")
+			}
+			_, _ = fmt.Fprintf(writer, `["2025-03-20T09:11:11.634Z",%d]`, i)
+		}
+		_, _ = fmt.Fprintf(writer, `],"rows":%d}`, rowCount)
+	}))
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	for _, disableCompression := range []bool{true, false} {
+		t.Run(fmt.Sprintf("disableCompression %v", disableCompression), func(t *testing.T) {
+			tc, err := newTaosConn(&Config{Net: "http", Addr: host, Port: port, DisableCompression: disableCompression, ReadBufferSize: 64})
+			require.NoError(t, err)
+			result, err := tc.QueryContext(context.Background(), "select * from t", nil)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"ts", "v"}, result.Columns())
+			dest := make([]driver.Value, 2)
+			count := 0
+			for {
+				err = result.Next(dest)
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				assert.Equal(t, int32(count), dest[1])
+				count += 1
+			}
+			assert.Equal(t, rowCount, count)
+			assert.NoError(t, result.Close())
+
+			result, err = tc.QueryContext(context.Background(), "select * from t", nil)
+			require.NoError(t, err)
+			require.NoError(t, result.Next(dest))
+			assert.NoError(t, result.Close())
+			assert.Equal(t, io.EOF, result.Next(dest))
+		})
+	}
+}
+
+type spanKey struct{}
+
+type recordSpan struct {
+	name   string
+	parent [8]byte
+	attrs  map[string]interface{}
+	ended  bool
+	err    error
+}
+
+func (s *recordSpan) SetAttributes(attrs ...trace.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordTracer struct {
+	lock  sync.Mutex
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.parent, _ = ctx.Value(spanKey{}).([8]byte)
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, [8]byte{0, 0, 0, 0, 0, 0, 1, byte(len(t.spans))}), span
+}
+
+func (t *recordTracer) SpanID(ctx context.Context) ([8]byte, bool) {
+	spanID, ok := ctx.Value(spanKey{}).([8]byte)
+	return spanID, ok
+}
+
+func TestStreamRowsTracer(t *testing.T) {
+	reqIDs := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqIDs <- r.URL.Query().Get("req_id")
+		_, _ = io.WriteString(w, `{"code":0,"column_meta":[["v","INT",4]],"data":[[1],[2],[3]],"rows":3}`)
+	}))
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	tracer := &recordTracer{}
+	trace.SetTracer(tracer, nil)
+	defer trace.SetTracer(nil, nil)
+
+	tc, err := newTaosConn(&Config{Net: "http", Addr: host, Port: port, DisableCompression: true})
+	require.NoError(t, err)
+	result, err := tc.QueryContext(context.Background(), "select v from t", nil)
+	require.NoError(t, err)
+	reqID := <-reqIDs
+	require.Len(t, tracer.spans, 2)
+	query, fetch := tracer.spans[0], tracer.spans[1]
+	assert.Equal(t, trace.SpanQuery, query.name)
+	assert.True(t, query.ended)
+	assert.Equal(t, trace.SpanFetch, fetch.name)
+	assert.Equal(t, [8]byte{0, 0, 0, 0, 0, 0, 1, 1}, fetch.parent)
+	assert.Equal(t, reqID, strconv.FormatInt(fetch.attrs[trace.AttrReqID].(int64), 10))
+	assert.False(t, fetch.ended)
+
+	dest := make([]driver.Value, 1)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, result.Next(dest))
+	}
+	assert.Equal(t, io.EOF, result.Next(dest))
+	require.NoError(t, result.Close())
+	assert.True(t, fetch.ended)
+	assert.NoError(t, fetch.err)
+	assert.Equal(t, 3, fetch.attrs[trace.AttrRows])
+	assert.Greater(t, fetch.attrs[trace.AttrBytes], 0)
+}
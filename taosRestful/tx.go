@@ -0,0 +1,78 @@
+package taosRestful
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// txBatch is not a real transaction, TDengine has no transactions.
+// It buffers the insert statements executed on the connection and
+// sends them as multi-table inserts when committed.
+type txBatch struct {
+	ctx     context.Context
+	conn    *taosConn
+	inserts []string
+}
+
+func (tx *txBatch) addInsert(query string) (driver.Result, error) {
+	clause, ok := common.InsertClause(query)
+	if !ok {
+		return nil, errTxBatchNotInsert()
+	}
+	tx.inserts = append(tx.inserts, clause)
+	// executed when the transaction commits
+	return driver.RowsAffected(0), nil
+}
+
+func (tx *txBatch) Commit() error {
+	tx.conn.tx = nil
+	inserts := tx.inserts
+	tx.inserts = nil
+	statements, counts := common.MergeInsertsCount(inserts, common.MaxTaosSqlLen)
+	applied := 0
+	for i, query := range statements {
+		_, err := tx.conn.taosQuery(tx.ctx, query, 512)
+		if err != nil {
+			return &common.TxBatchCommitError{Applied: applied, Total: len(inserts), Err: err}
+		}
+		applied += counts[i]
+	}
+	return nil
+}
+
+func (tx *txBatch) Rollback() error {
+	tx.conn.tx = nil
+	tx.inserts = nil
+	return nil
+}
+
+func (tc *taosConn) Begin() (driver.Tx, error) {
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a write batch if txBatch is enabled in the DSN.
+// Insert statements are buffered until Commit, Exec returns 0 affected rows for them.
+// Other statements are rejected until the batch is committed or rolled back.
+// Commit sends the inserts in order with ctx and is not atomic: when an insert fails, the inserts before it stay
+// written and Commit returns a *common.TxBatchCommitError with their number.
+func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if !tc.cfg.TxBatch {
+		return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support transaction"}
+	}
+	if opts.ReadOnly || sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "txBatch does not support isolation level or read only"}
+	}
+	if tc.tx != nil {
+		return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "transaction already started"}
+	}
+	tc.tx = &txBatch{ctx: ctx, conn: tc}
+	return tc.tx, nil
+}
+
+func errTxBatchNotInsert() error {
+	return &taosErrors.TaosError{Code: 0xffff, ErrStr: common.ErrTxBatchNotInsert.Error()}
+}
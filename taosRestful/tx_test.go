@@ -0,0 +1,91 @@
+package taosRestful
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+func TestTxBatch(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(body))
+		_, _ = w.Write([]byte(`{"code":0,"column_meta":[["affected_rows","INT",4]],"data":[[2]],"rows":1}`))
+	}))
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tc, err := newTaosConn(&Config{Net: "http", Addr: host, Port: port, DisableCompression: true, InterpolateParams: true})
+	require.NoError(t, err)
+	_, err = tc.Begin()
+	assert.Error(t, err)
+
+	tc, err = newTaosConn(&Config{Net: "http", Addr: host, Port: port, DisableCompression: true, InterpolateParams: true, TxBatch: true})
+	require.NoError(t, err)
+	_, err = tc.BeginTx(ctx, driver.TxOptions{ReadOnly: true})
+	assert.Error(t, err)
+	tx, err := tc.Begin()
+	require.NoError(t, err)
+	_, err = tc.Begin()
+	assert.Error(t, err)
+	result, err := tc.ExecContext(ctx, "insert into t1 values(now, 1)", nil)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+	_, err = tc.ExecContext(ctx, "insert into t2 values(now, ?)", []driver.NamedValue{{Ordinal: 1, Value: int64(2)}})
+	require.NoError(t, err)
+	_, err = tc.ExecContext(ctx, "create table t3(ts timestamp, v int)", nil)
+	assert.Error(t, err)
+	_, err = tc.QueryContext(ctx, "select * from t1", nil)
+	assert.Error(t, err)
+	assert.Empty(t, received)
+	err = tx.Commit()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"insert into t1 values(now, 1) t2 values(now, 2)"}, received)
+
+	received = nil
+	tx, err = tc.Begin()
+	require.NoError(t, err)
+	_, err = tc.ExecContext(ctx, "insert into t1 values(now, 1)", nil)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+	assert.Empty(t, received)
+	result, err = tc.ExecContext(ctx, "insert into t1 values(now, 1)", nil)
+	require.NoError(t, err)
+	affected, err = result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	// the inserts are sent with the context of the transaction
+	received = nil
+	cancelCtx, cancel := context.WithCancel(ctx)
+	tx, err = tc.BeginTx(cancelCtx, driver.TxOptions{})
+	require.NoError(t, err)
+	_, err = tc.ExecContext(ctx, "insert into t1 values(now, 1)", nil)
+	require.NoError(t, err)
+	cancel()
+	err = tx.Commit()
+	var commitErr *common.TxBatchCommitError
+	require.True(t, errors.As(err, &commitErr), err)
+	assert.Equal(t, 0, commitErr.Applied)
+	assert.Equal(t, 1, commitErr.Total)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Empty(t, received)
+}
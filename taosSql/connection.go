@@ -7,6 +7,9 @@ import (
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 	"github.com/taosdata/driver-go/v3/wrapper/handler"
@@ -15,13 +18,16 @@ import (
 type taosConn struct {
 	taos        unsafe.Pointer
 	cfg         *Config
+	logger      log.Logger
 	timezone    *time.Location
 	timezoneStr string
+	tx          *txBatch
 }
 
 func newTaosConn(cfg *Config) *taosConn {
 	conn := &taosConn{
 		cfg:      cfg,
+		logger:   log.Or(cfg.Logger),
 		timezone: cfg.Timezone,
 	}
 	if conn.timezone != nil {
@@ -30,15 +36,12 @@ func newTaosConn(cfg *Config) *taosConn {
 	return conn
 }
 
-func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &errors.TaosError{Code: 0xffff, ErrStr: "taosSql does not support transaction"}
-}
-
 func (tc *taosConn) Close() (err error) {
 	if tc.taos != nil {
 		locker.Lock()
 		wrapper.TaosClose(tc.taos)
 		locker.Unlock()
+		tc.logger.Info("connection closed", "addr", tc.cfg.Addr)
 	}
 	tc.taos = nil
 	return nil
@@ -106,9 +109,15 @@ func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.Nam
 		}
 		query = prepared
 	}
+	if tc.tx != nil {
+		return tc.tx.addInsert(query)
+	}
 	h := asyncHandlerPool.Get()
 	defer asyncHandlerPool.Put(h)
-	result := tc.taosQuery(query, h, reqIDValue)
+	result, err := tc.taosQuery(ctx, query, h, reqIDValue)
+	if err != nil {
+		return nil, err
+	}
 	return tc.processExecResult(result)
 }
 
@@ -138,6 +147,9 @@ func (tc *taosConn) QueryContext(ctx context.Context, query string, args []drive
 }
 
 func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if tc.tx != nil {
+		return nil, errTxBatchNotInsert()
+	}
 	reqIDValue, err := common.GetReqIDFromCtx(ctx)
 	if err != nil {
 		return nil, err
@@ -154,11 +166,15 @@ func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.Na
 		query = prepared
 	}
 	h := asyncHandlerPool.Get()
-	result := tc.taosQuery(query, h, reqIDValue)
-	return tc.processRows(result, h)
+	result, err := tc.taosQuery(ctx, query, h, reqIDValue)
+	if err != nil {
+		asyncHandlerPool.Put(h)
+		return nil, err
+	}
+	return tc.processRows(ctx, result, h)
 }
 
-func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler) (driver.Rows, error) {
+func (tc *taosConn) processRows(ctx context.Context, result *handler.AsyncResult, h *handler.Handler) (driver.Rows, error) {
 	res := result.Res
 	code := wrapper.TaosError(res)
 	if code != int(errors.SUCCESS) {
@@ -177,6 +193,7 @@ func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler)
 	}
 	precision := wrapper.TaosResultPrecision(res)
 	rs := newRows(h, rowsHeader, res, precision, false, tc.timezone)
+	rs.ctx = ctx
 	return rs, nil
 }
 
@@ -188,14 +205,53 @@ func (tc *taosConn) Ping(ctx context.Context) (err error) {
 	return errors.ErrTscInvalidConnection
 }
 
-func (tc *taosConn) taosQuery(sqlStr string, handler *handler.Handler, reqID int64) *handler.AsyncResult {
-	locker.Lock()
+// taosQuery kills the query when ctx is done before it completes and returns ctx.Err().
+func (tc *taosConn) taosQuery(ctx context.Context, sqlStr string, handler *handler.Handler, reqID int64) (result *handler.AsyncResult, err error) {
+	start := time.Now()
+	spanCtx, span := trace.Start(ctx, trace.SpanQuery, trace.SQL(sqlStr))
+	defer func() {
+		// the error of the result is returned by the caller, it is only recorded on the span and in the metrics
+		spanErr := err
+		if err == nil {
+			if code := wrapper.TaosError(result.Res); code != int(errors.SUCCESS) {
+				spanErr = errors.NewError(code, wrapper.TaosErrorStr(result.Res))
+			} else if wrapper.TaosIsUpdateQuery(result.Res) {
+				span.SetAttributes(trace.Rows(wrapper.TaosAffectedRows(result.Res)))
+			}
+		}
+		metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), spanErr)
+		trace.End(span, spanErr)
+	}()
 	if reqID == 0 {
-		wrapper.TaosQueryA(tc.taos, sqlStr, handler.Handler)
-	} else {
-		wrapper.TaosQueryAWithReqID(tc.taos, sqlStr, handler.Handler, reqID)
+		reqID = trace.NewReqID(spanCtx)
+	}
+	span.SetAttributes(trace.ReqID(reqID))
+	tc.logger.Debug("query", "req_id", uint64(reqID))
+	defer log.Slow(tc.logger, "query", uint64(reqID), start)
+	locker.Lock()
+	wrapper.TaosQueryAWithReqID(tc.taos, sqlStr, handler.Handler, reqID)
+	locker.Unlock()
+	select {
+	case r := <-handler.Caller.QueryResult:
+		if code := wrapper.TaosError(r.Res); code != int(errors.SUCCESS) {
+			tc.logger.Debug("query failed", "req_id", uint64(reqID), "code", code)
+		}
+		return r, nil
+	case <-ctx.Done():
 	}
+	tc.logger.Info("kill query", "req_id", uint64(reqID), "error", ctx.Err())
+	locker.Lock()
+	wrapper.TaosKillQuery(tc.taos)
 	locker.Unlock()
+	// the callback is always called, with the result of the killed query or of a query completed meanwhile
 	r := <-handler.Caller.QueryResult
-	return r
+	if wrapper.TaosError(r.Res) == int(errors.SUCCESS) {
+		return r, nil
+	}
+	if r.Res != nil {
+		locker.Lock()
+		wrapper.TaosFreeResult(r.Res)
+		locker.Unlock()
+	}
+	return nil, ctx.Err()
 }
@@ -3,9 +3,11 @@ package taosSql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/taosdata/driver-go/v3/common"
 )
 
@@ -77,3 +79,36 @@ func TestWrongReqID(t *testing.T) {
 	_, err = execContext(ctx, db, "create database if not exists test_wrong_req_id")
 	assert.Error(t, err)
 }
+
+func TestCancelFetch(t *testing.T) {
+	db, err := sql.Open("taosSql", dataSourceName)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+	err = conn.Raw(func(driverConn interface{}) error {
+		tc := driverConn.(*taosConn)
+		ctx, cancel := context.WithCancel(context.Background())
+		rs, err := tc.QueryContext(ctx, "show databases", nil)
+		require.NoError(t, err)
+		cancel()
+		values := make([]driver.Value, len(rs.Columns()))
+		assert.Equal(t, context.Canceled, rs.Next(values))
+		assert.NoError(t, rs.Close())
+
+		_, err = tc.QueryContext(ctx, "show databases", nil)
+		assert.Equal(t, context.Canceled, err)
+
+		// the connection is still usable
+		rs, err = tc.QueryContext(context.Background(), "show databases", nil)
+		require.NoError(t, err)
+		assert.NoError(t, rs.Next(values))
+		return rs.Close()
+	})
+	assert.NoError(t, err)
+}
@@ -68,6 +68,7 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 	taos, err := wrapper.TaosConnect(tc.cfg.Addr, tc.cfg.User, tc.cfg.Passwd, tc.cfg.DbName, tc.cfg.Port)
 	locker.Unlock()
 	if err != nil {
+		tc.logger.Warn("connect failed", "addr", tc.cfg.Addr, "error", err)
 		return nil, err
 	}
 	if tc.timezoneStr != "" {
@@ -79,6 +80,7 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 		}
 	}
 	tc.taos = taos
+	tc.logger.Info("connection opened", "addr", tc.cfg.Addr)
 	return tc, nil
 }
 
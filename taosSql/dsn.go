@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
 	"github.com/taosdata/driver-go/v3/errors"
 )
 
@@ -36,6 +37,8 @@ type Config struct {
 	CgoThread               int
 	CgoAsyncHandlerPoolSize int
 	Timezone                *time.Location // Timezone for connection, e.g., "Asia%2FShanghai" or "UTC"
+	TxBatch                 bool           // Begin returns a write batch that buffers inserts until commit
+	Logger                  log.Logger     // Logger of the connections, the package-level logger of common/log if nil, not parsed from DSN
 }
 
 // NewConfig creates a new Config and sets default values.
@@ -189,6 +192,11 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			if err != nil {
 				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid timezone value: " + escapedValue + ", " + err.Error()}
 			}
+		case "txBatch":
+			cfg.TxBatch, err = strconv.ParseBool(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid txBatch value: " + value}
+			}
 		default:
 			if err = setParam(cfg, param[0], value); err != nil {
 				return
@@ -408,6 +408,26 @@ func TestParseDsn(t *testing.T) {
 			dsn:  "user:passwd@net([ab:cd:ef:ab::cd:ef]:6041)/dbname?timezone=Local",
 			errs: "invalid timezone value: Local, timezone cannot be 'Local'",
 		},
+		{
+			name: "txBatch",
+			dsn:  "user:passwd@net(fqdn:6030)/dbname?txBatch=true",
+			want: &Config{
+				User:              "user",
+				Passwd:            "passwd",
+				Net:               "net",
+				Addr:              "fqdn",
+				Port:              6030,
+				DbName:            "dbname",
+				Loc:               time.UTC,
+				InterpolateParams: true,
+				TxBatch:           true,
+			},
+		},
+		{
+			name: "invalid txBatch",
+			dsn:  "user:passwd@net(fqdn:6030)/dbname?txBatch=abc",
+			errs: "invalid txBatch value: abc",
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -1,6 +1,7 @@
 package taosSql
 
 import (
+	"context"
 	"database/sql/driver"
 	"io"
 	"reflect"
@@ -8,7 +9,9 @@ import (
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 	"github.com/taosdata/driver-go/v3/wrapper/handler"
@@ -25,6 +28,8 @@ type rows struct {
 	result      unsafe.Pointer
 	precision   int
 	isStmt      bool
+	// ctx of the query, fetching is canceled when it is done
+	ctx context.Context
 }
 
 func newRows(handler *handler.Handler, rowsHeader *wrapper.RowsHeader, result unsafe.Pointer, precision int, isStmt bool, timezone *time.Location) *rows {
@@ -35,6 +40,7 @@ func newRows(handler *handler.Handler, rowsHeader *wrapper.RowsHeader, result un
 		precision:  precision,
 		isStmt:     isStmt,
 		timezone:   timezone,
+		ctx:        context.Background(),
 	}
 }
 func (rs *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
@@ -122,10 +128,16 @@ func (rs *rows) FormatTime(ts int64, precision int) driver.Value {
 	return common.TimestampConvertToTimeWithLocation(ts, precision, rs.timezone)
 }
 
-func (rs *rows) taosFetchBlock() error {
-	result := rs.asyncFetchRows()
+func (rs *rows) taosFetchBlock() (err error) {
+	_, span := trace.Start(rs.ctx, trace.SpanFetch)
+	defer func() { trace.End(span, err) }()
+	result, err := rs.asyncFetchRows()
+	if err != nil {
+		return err
+	}
 	if result.N == 0 {
 		rs.blockSize = 0
+		span.SetAttributes(trace.Rows(0))
 		return nil
 	}
 	if result.N < 0 {
@@ -136,13 +148,33 @@ func (rs *rows) taosFetchBlock() error {
 	rs.blockSize = result.N
 	rs.block = wrapper.TaosGetRawBlock(result.Res)
 	rs.blockOffset = 0
+	bytes := int(parser.RawBlockGetLength(rs.block))
+	span.SetAttributes(trace.Rows(result.N), trace.Bytes(bytes))
+	metrics.GetMetrics().AddFetched(result.N, bytes)
 	return nil
 }
 
-func (rs *rows) asyncFetchRows() *handler.AsyncResult {
-	locker.Lock()
-	wrapper.TaosFetchRawBlockA(rs.result, rs.handler.Handler)
-	locker.Unlock()
-	r := <-rs.handler.Caller.FetchResult
-	return r
+// asyncFetchRows stops the query and frees the result when ctx is done before the block is fetched.
+func (rs *rows) asyncFetchRows() (*handler.AsyncResult, error) {
+	if rs.ctx.Err() == nil {
+		locker.Lock()
+		wrapper.TaosFetchRawBlockA(rs.result, rs.handler.Handler)
+		locker.Unlock()
+		select {
+		case r := <-rs.handler.Caller.FetchResult:
+			return r, nil
+		case <-rs.ctx.Done():
+		}
+		locker.Lock()
+		wrapper.TaosStopQuery(rs.result)
+		locker.Unlock()
+		<-rs.handler.Caller.FetchResult
+	}
+	if !rs.isStmt {
+		locker.Lock()
+		wrapper.TaosFreeResult(rs.result)
+		locker.Unlock()
+	}
+	rs.result = nil
+	return nil, rs.ctx.Err()
 }
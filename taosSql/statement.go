@@ -1,6 +1,7 @@
 package taosSql
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/taosdata/driver-go/v3/common"
 	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/types"
 	"github.com/taosdata/driver-go/v3/wrapper"
@@ -27,6 +29,11 @@ type Stmt struct {
 }
 
 func (stmt *Stmt) Close() error {
+	if stmt.tc != nil && stmt.tc.tx != nil && stmt.tc.tx.hasRows(stmt) {
+		// closed after the pending rows are flushed
+		stmt.tc.tx.closeAfterFlush(stmt)
+		return nil
+	}
 	if stmt.stmt != nil {
 		locker.Lock()
 		wrapper.TaosStmtClose(stmt.stmt)
@@ -44,54 +51,75 @@ func (stmt *Stmt) NumInput() int {
 }
 
 func (stmt *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.exec(context.Background(), args)
+}
+
+// ExecContext implements driver.StmtExecContext, the spans of the bind and exec are children of the span of ctx.
+func (stmt *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values, err := namedValueToValue(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.exec(ctx, values)
+}
+
+func (stmt *Stmt) exec(ctx context.Context, args []driver.Value) (driver.Result, error) {
 	if stmt.tc == nil || stmt.tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
 	if len(args) != len(stmt.cols) {
 		return nil, fmt.Errorf("stmt exec error: wrong number of parameters")
 	}
+	if stmt.tc.tx != nil {
+		if !stmt.isInsert {
+			return nil, errTxBatchNotInsert()
+		}
+		return stmt.tc.tx.addRow(stmt, args), nil
+	}
 	locker.Lock()
 	defer locker.Unlock()
-	code := wrapper.TaosStmtBindParam(stmt.stmt, args)
-	if code != 0 {
-		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
-	}
-	code = wrapper.TaosStmtAddBatch(stmt.stmt)
-	if code != 0 {
-		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
-	}
-	code = wrapper.TaosStmtExecute(stmt.stmt)
-	if code != 0 {
-		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
+	affectRows, err := stmt.execBatch(ctx, [][]driver.Value{args})
+	if err != nil {
+		return nil, err
 	}
-	affectRows := wrapper.TaosStmtAffectedRowsOnce(stmt.stmt)
 	return driver.RowsAffected(affectRows), nil
 }
 
 func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.query(context.Background(), args)
+}
+
+// QueryContext implements driver.StmtQueryContext, the spans of the bind and exec are children of the span of ctx
+// and fetching the rows is canceled when ctx is done.
+func (stmt *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := namedValueToValue(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.query(ctx, values)
+}
+
+func (stmt *Stmt) query(ctx context.Context, args []driver.Value) (driver.Rows, error) {
 	if stmt.tc == nil || stmt.tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
+	if stmt.tc.tx != nil {
+		return nil, errTxBatchNotInsert()
+	}
 	locker.Lock()
 	defer locker.Unlock()
-	code := wrapper.TaosStmtBindParam(stmt.stmt, args)
-	if code != 0 {
-		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
-	}
-	code = wrapper.TaosStmtAddBatch(stmt.stmt)
-	if code != 0 {
-		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
+	if err := stmt.bindParam(ctx, args); err != nil {
+		return nil, err
 	}
-	code = wrapper.TaosStmtExecute(stmt.stmt)
+	span, _ := startSpan(ctx, trace.SpanStmtExec, trace.SQL(stmt.pSql))
+	code := wrapper.TaosStmtExecute(stmt.stmt)
 	if code != 0 {
 		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-		return nil, errors.NewError(code, errStr)
+		err := errors.NewError(code, errStr)
+		trace.End(span, err)
+		return nil, err
 	}
+	trace.End(span, nil)
 	res := wrapper.TaosStmtUseResult(stmt.stmt)
 	handler := asyncHandlerPool.Get()
 	numFields := wrapper.TaosNumFields(res)
@@ -101,9 +129,68 @@ func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 	}
 	precision := wrapper.TaosResultPrecision(res)
 	rs := newRows(handler, rowsHeader, res, precision, true, stmt.tc.timezone)
+	rs.ctx = ctx
 	return rs, nil
 }
 
+// execBatch binds every row as one batch and executes the statement once, the caller holds locker.
+func (stmt *Stmt) execBatch(ctx context.Context, rows [][]driver.Value) (int, error) {
+	for _, row := range rows {
+		if err := stmt.bindParam(ctx, row); err != nil {
+			return 0, err
+		}
+	}
+	span, _ := startSpan(ctx, trace.SpanStmtExec, trace.SQL(stmt.pSql))
+	code := wrapper.TaosStmtExecute(stmt.stmt)
+	if code != 0 {
+		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
+		err := errors.NewError(code, errStr)
+		trace.End(span, err)
+		return 0, err
+	}
+	affectRows := wrapper.TaosStmtAffectedRowsOnce(stmt.stmt)
+	span.SetAttributes(trace.Rows(affectRows))
+	trace.End(span, nil)
+	return affectRows, nil
+}
+
+// bindParam binds a row of parameters and adds it to the batch, the caller holds locker.
+func (stmt *Stmt) bindParam(ctx context.Context, args []driver.Value) (err error) {
+	span, _ := startSpan(ctx, trace.SpanStmtBind, trace.SQL(stmt.pSql))
+	defer func() { trace.End(span, err) }()
+	code := wrapper.TaosStmtBindParam(stmt.stmt, args)
+	if code != 0 {
+		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
+		return errors.NewError(code, errStr)
+	}
+	code = wrapper.TaosStmtAddBatch(stmt.stmt)
+	if code != 0 {
+		errStr := wrapper.TaosStmtErrStr(stmt.stmt)
+		return errors.NewError(code, errStr)
+	}
+	return nil
+}
+
+// startSpan starts a span of ctx with a req_id derived from it. The stmt calls of the native connection take no
+// req_id, it is recorded on the span only.
+func startSpan(ctx context.Context, name string, attrs ...trace.Attribute) (trace.Span, int64) {
+	ctx, span := trace.Start(ctx, name, attrs...)
+	reqID := trace.NewReqID(ctx)
+	span.SetAttributes(trace.ReqID(reqID))
+	return span, reqID
+}
+
+func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("sql: driver does not support the use of Named Parameters")
+		}
+		args[i] = arg.Value
+	}
+	return args, nil
+}
+
 func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
 	if stmt.isInsert {
 		if stmt.cols == nil {
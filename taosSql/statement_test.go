@@ -1,14 +1,18 @@
 package taosSql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/wrapper"
 )
 
@@ -2278,3 +2282,80 @@ func TestStmtDecimal(t *testing.T) {
 	}
 	assert.Equal(t, 1, count)
 }
+
+type spanKey struct{}
+
+type recordSpan struct {
+	name   string
+	parent [8]byte
+	attrs  map[string]interface{}
+}
+
+func (s *recordSpan) SetAttributes(attrs ...trace.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) End(_ error) {}
+
+type recordTracer struct {
+	lock  sync.Mutex
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.parent, _ = ctx.Value(spanKey{}).([8]byte)
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, [8]byte{0, 0, 0, 0, 0, 0, 1, byte(len(t.spans))}), span
+}
+
+func (t *recordTracer) SpanID(ctx context.Context) ([8]byte, bool) {
+	spanID, ok := ctx.Value(spanKey{}).([8]byte)
+	return spanID, ok
+}
+
+func TestStmtTracer(t *testing.T) {
+	db, err := sql.Open(driverName, dataSourceName)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	_, err = db.Exec("create database if not exists test_stmt_tracer")
+	require.NoError(t, err)
+	defer func() {
+		_, err = db.Exec("drop database if exists test_stmt_tracer")
+		assert.NoError(t, err)
+	}()
+	_, err = db.Exec("create table if not exists test_stmt_tracer.t(ts timestamp, v int)")
+	require.NoError(t, err)
+	stmt, err := db.Prepare("insert into test_stmt_tracer.t values(?, ?)")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, stmt.Close())
+	}()
+	tracer := &recordTracer{}
+	trace.SetTracer(tracer, nil)
+	defer trace.SetTracer(nil, nil)
+	ctx, span := trace.Start(context.Background(), "caller")
+	result, err := stmt.ExecContext(ctx, time.Now(), 1)
+	span.End(err)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	require.Equal(t, 3, len(tracer.spans))
+	callerID := [8]byte{0, 0, 0, 0, 0, 0, 1, 1}
+	for i, name := range []string{trace.SpanStmtBind, trace.SpanStmtExec} {
+		assert.Equal(t, name, tracer.spans[i+1].name)
+		assert.Equal(t, callerID, tracer.spans[i+1].parent)
+		// the req_id is derived from the span
+		assert.Equal(t, trace.ReqIDFromSpanID([8]byte{0, 0, 0, 0, 0, 0, 1, byte(i + 2)}), tracer.spans[i+1].attrs[trace.AttrReqID])
+	}
+	assert.Equal(t, 1, tracer.spans[2].attrs[trace.AttrRows])
+}
@@ -0,0 +1,181 @@
+package taosSql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/errors"
+)
+
+// txBatch is not a real transaction, TDengine has no transactions.
+// It buffers the insert statements and the rows of prepared insert statements executed on the connection.
+// On commit the writes are replayed in the order they were executed: adjacent insert statements are merged
+// into multi-table inserts and adjacent rows of a prepared statement are added as one batch and executed once.
+type txBatch struct {
+	ctx        context.Context
+	conn       *taosConn
+	ops        []*txOp
+	closeStmts []*Stmt
+}
+
+// txOp is a run of adjacent writes, either insert clauses or rows of stmt.
+type txOp struct {
+	inserts []string
+	stmt    *Stmt
+	rows    [][]driver.Value
+}
+
+func newTxBatch(ctx context.Context, conn *taosConn) *txBatch {
+	return &txBatch{
+		ctx:  ctx,
+		conn: conn,
+	}
+}
+
+func (tx *txBatch) addInsert(query string) (driver.Result, error) {
+	clause, ok := common.InsertClause(query)
+	if !ok {
+		return nil, errTxBatchNotInsert()
+	}
+	if op := tx.last(); op != nil && op.stmt == nil {
+		op.inserts = append(op.inserts, clause)
+	} else {
+		tx.ops = append(tx.ops, &txOp{inserts: []string{clause}})
+	}
+	// executed when the transaction commits
+	return driver.RowsAffected(0), nil
+}
+
+func (tx *txBatch) addRow(stmt *Stmt, row []driver.Value) driver.Result {
+	// the caller may reuse the slice
+	row = append([]driver.Value(nil), row...)
+	if op := tx.last(); op != nil && op.stmt == stmt {
+		op.rows = append(op.rows, row)
+	} else {
+		tx.ops = append(tx.ops, &txOp{stmt: stmt, rows: [][]driver.Value{row}})
+	}
+	// executed when the transaction commits
+	return driver.RowsAffected(0)
+}
+
+func (tx *txBatch) last() *txOp {
+	if len(tx.ops) == 0 {
+		return nil
+	}
+	return tx.ops[len(tx.ops)-1]
+}
+
+func (tx *txBatch) hasRows(stmt *Stmt) bool {
+	for _, op := range tx.ops {
+		if op.stmt == stmt {
+			return true
+		}
+	}
+	return false
+}
+
+func (tx *txBatch) closeAfterFlush(stmt *Stmt) {
+	tx.closeStmts = append(tx.closeStmts, stmt)
+}
+
+func (tx *txBatch) Commit() error {
+	tx.conn.tx = nil
+	total := 0
+	for _, op := range tx.ops {
+		total += len(op.inserts) + len(op.rows)
+	}
+	applied := 0
+	var err error
+	for _, op := range tx.ops {
+		var n int
+		n, err = tx.flush(op)
+		applied += n
+		if err != nil {
+			err = &common.TxBatchCommitError{Applied: applied, Total: total, Err: err}
+			break
+		}
+	}
+	tx.finish()
+	return err
+}
+
+// flush writes op and returns the number of insert statements or rows written.
+func (tx *txBatch) flush(op *txOp) (int, error) {
+	if op.stmt == nil {
+		statements, counts := common.MergeInsertsCount(op.inserts, common.MaxTaosSqlLen)
+		applied := 0
+		for i, query := range statements {
+			_, err := tx.conn.execCtx(tx.ctx, query, nil)
+			if err != nil {
+				return applied, err
+			}
+			applied += counts[i]
+		}
+		return applied, nil
+	}
+	err := op.stmt.execRows(tx.ctx, op.rows)
+	if err != nil {
+		return 0, err
+	}
+	return len(op.rows), nil
+}
+
+func (tx *txBatch) Rollback() error {
+	tx.conn.tx = nil
+	tx.finish()
+	return nil
+}
+
+func (tx *txBatch) finish() {
+	for _, stmt := range tx.closeStmts {
+		_ = stmt.Close()
+	}
+	tx.ops = nil
+	tx.closeStmts = nil
+}
+
+// execRows writes the rows as one batch, it returns ctx.Err() without writing when ctx is done.
+func (stmt *Stmt) execRows(ctx context.Context, rows [][]driver.Value) error {
+	if stmt.stmt == nil {
+		return driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	locker.Lock()
+	defer locker.Unlock()
+	_, err := stmt.execBatch(ctx, rows)
+	return err
+}
+
+func (tc *taosConn) Begin() (driver.Tx, error) {
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a write batch if txBatch is enabled in the DSN.
+// Insert statements and rows of prepared insert statements are buffered until Commit, Exec returns 0 affected rows for them.
+// Other statements are rejected until the batch is committed or rolled back.
+// Commit replays the writes in order with ctx and is not atomic: when a write fails, the writes before it stay
+// written and Commit returns a *common.TxBatchCommitError with their number.
+func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if !tc.cfg.TxBatch {
+		return nil, &errors.TaosError{Code: 0xffff, ErrStr: "taosSql does not support transaction"}
+	}
+	if opts.ReadOnly || sql.IsolationLevel(opts.Isolation) != sql.LevelDefault {
+		return nil, &errors.TaosError{Code: 0xffff, ErrStr: "txBatch does not support isolation level or read only"}
+	}
+	if tc.taos == nil {
+		return nil, driver.ErrBadConn
+	}
+	if tc.tx != nil {
+		return nil, &errors.TaosError{Code: 0xffff, ErrStr: "transaction already started"}
+	}
+	tc.tx = newTxBatch(ctx, tc)
+	return tc.tx, nil
+}
+
+func errTxBatchNotInsert() error {
+	return &errors.TaosError{Code: 0xffff, ErrStr: common.ErrTxBatchNotInsert.Error()}
+}
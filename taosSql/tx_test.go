@@ -0,0 +1,121 @@
+package taosSql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+func TestTxBatch(t *testing.T) {
+	db, err := sql.Open("taosSql", dataSourceName+"&txBatch=true")
+	require.NoError(t, err)
+	defer func() {
+		err = db.Close()
+		assert.NoError(t, err)
+	}()
+	testDbName := "test_taossql_tx_batch"
+	_, err = db.Exec(fmt.Sprintf("drop database if exists %s", testDbName))
+	require.NoError(t, err)
+	_, err = db.Exec(fmt.Sprintf("create database %s", testDbName))
+	require.NoError(t, err)
+	defer func() {
+		_, err = db.Exec(fmt.Sprintf("drop database if exists %s", testDbName))
+		assert.NoError(t, err)
+	}()
+	_, err = db.Exec(fmt.Sprintf("create table %s.t(ts timestamp, v int)", testDbName))
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		result, err := tx.Exec(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName), now.Add(time.Duration(i)*time.Second), i)
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), affected)
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName))
+	require.NoError(t, err)
+	for i := 5; i < 10; i++ {
+		_, err = stmt.Exec(now.Add(time.Duration(i)*time.Second), i)
+		require.NoError(t, err)
+	}
+	_, err = tx.Exec(fmt.Sprintf("create table %s.t2(ts timestamp, v int)", testDbName))
+	assert.Error(t, err)
+	_, err = tx.Query(fmt.Sprintf("select * from %s.t", testDbName))
+	assert.Error(t, err)
+	var count int
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.t", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	err = tx.Commit()
+	require.NoError(t, err)
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.t", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName), now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.t", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+}
+
+func TestTxBatchOrder(t *testing.T) {
+	db, err := sql.Open("taosSql", dataSourceName+"&txBatch=true")
+	require.NoError(t, err)
+	defer func() {
+		err = db.Close()
+		assert.NoError(t, err)
+	}()
+	testDbName := "test_taossql_tx_batch_order"
+	_, err = db.Exec(fmt.Sprintf("drop database if exists %s", testDbName))
+	require.NoError(t, err)
+	_, err = db.Exec(fmt.Sprintf("create database %s", testDbName))
+	require.NoError(t, err)
+	defer func() {
+		_, err = db.Exec(fmt.Sprintf("drop database if exists %s", testDbName))
+		assert.NoError(t, err)
+	}()
+	_, err = db.Exec(fmt.Sprintf("create table %s.t(ts timestamp, v int)", testDbName))
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	stmt, err := tx.Prepare(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName))
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = stmt.Exec(now, 1)
+	require.NoError(t, err)
+	// the last write of a timestamp wins
+	_, err = tx.Exec(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName), now, 2)
+	require.NoError(t, err)
+	err = tx.Commit()
+	require.NoError(t, err)
+	var v int
+	err = db.QueryRow(fmt.Sprintf("select v from %s.t", testDbName)).Scan(&v)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec(fmt.Sprintf("insert into %s.t values(?, ?)", testDbName), now.Add(time.Second), 3)
+	require.NoError(t, err)
+	_, err = tx.Exec(fmt.Sprintf("insert into %s.not_exist values(?, ?)", testDbName), now, 4)
+	require.NoError(t, err)
+	err = tx.Commit()
+	var commitErr *common.TxBatchCommitError
+	require.True(t, errors.As(err, &commitErr), err)
+	assert.Equal(t, 2, commitErr.Total)
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/url"
 	"strconv"
@@ -18,9 +19,13 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
 	"github.com/taosdata/driver-go/v3/common/tdversion"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
 var jsonI = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -36,6 +41,12 @@ const (
 	STMTClose        = "close"
 	STMTGetColFields = "get_col_fields"
 	STMTUseResult    = "use_result"
+
+	STMT2Init    = "stmt2_init"
+	STMT2Prepare = "stmt2_prepare"
+	STMT2Exec    = "stmt2_exec"
+	STMT2Result  = "stmt2_result"
+	STMT2Close   = "stmt2_close"
 )
 
 const (
@@ -47,127 +58,207 @@ const (
 var (
 	NotQueryError    = errors.New("sql is an update statement not a query statement")
 	ReadTimeoutError = errors.New("read timeout")
+	ResultLostError  = errors.New("result lost after reconnect")
 )
 
 //revive:enable
 
+// taosConn is safe for concurrent use, the requests of concurrent queries share its websocket session.
 type taosConn struct {
-	buf          *bytes.Buffer
-	client       *websocket.Conn
-	timezone     *time.Location
-	timezoneStr  string
-	writeLock    sync.Mutex
-	readTimeout  time.Duration
-	writeTimeout time.Duration
-	cfg          *Config
-	messageChan  chan *message
-	messageError error
-	endpoint     string
-	closed       uint32
-	closeCh      chan struct{}
-	closeOnce    sync.Once
-}
-
-type message struct {
-	mt      int
-	message []byte
-	err     error
+	timezone      *time.Location
+	timezoneStr   string
+	readTimeout   time.Duration
+	writeTimeout  time.Duration
+	cfg           *Config
+	logger        log.Logger
+	stateLock     sync.RWMutex
+	session       *session
+	reconnectLock sync.Mutex
+	endpoint      string
+	closed        uint32
+	closeOnce     sync.Once
+	tx            *txBatch
+	balancer      *endpointBalancer
+	// generation is increased when the session is replaced, server side statements and results of older generations are lost
+	generation uint64
+	currentDB  string
+	// stmt2Unsupported is set when the server rejects stmt2_init, the statements are prepared with stmt1
+	stmt2Unsupported uint32
 }
 
 func newTaosConn(cfg *Config) (*taosConn, error) {
-	host := net.JoinHostPort(cfg.Addr, strconv.Itoa(cfg.Port))
+	return newTaosConnWithBalancer(cfg, newEndpointBalancer(cfg))
+}
+
+func newTaosConnWithBalancer(cfg *Config, balancer *endpointBalancer) (*taosConn, error) {
+	tc := &taosConn{
+		timezone:     cfg.Timezone,
+		readTimeout:  cfg.ReadTimeout,
+		writeTimeout: cfg.WriteTimeout,
+		cfg:          cfg,
+		logger:       log.Or(cfg.Logger),
+		balancer:     balancer,
+		currentDB:    cfg.DbName,
+	}
+	if cfg.Timezone != nil {
+		tc.timezoneStr = cfg.Timezone.String()
+	}
+	err := balancer.dial(tc.open)
+	if err != nil {
+		_ = tc.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// open dials the endpoint and replaces the websocket session of the connection.
+func (tc *taosConn) open(addr string, port int) error {
+	host := net.JoinHostPort(addr, strconv.Itoa(port))
 	endpointUrl := &url.URL{
-		Scheme: cfg.Net,
+		Scheme: tc.cfg.Net,
 		Host:   host,
 		Path:   "/ws",
 	}
-	if cfg.Token != "" {
-		endpointUrl.RawQuery = fmt.Sprintf("token=%s", cfg.Token)
+	if tc.cfg.Token != "" {
+		endpointUrl.RawQuery = fmt.Sprintf("token=%s", tc.cfg.Token)
 	}
 	endpoint := endpointUrl.String()
 	dialer := common.DefaultDialer
-	dialer.EnableCompression = cfg.EnableCompression
+	dialer.EnableCompression = tc.cfg.EnableCompression
 	ws, _, err := dialer.Dial(endpoint, nil)
 	if err != nil {
-		return nil, err
+		tc.logger.Warn("dial failed", "host", host, "error", err)
+		return err
 	}
-	ws.EnableWriteCompression(cfg.EnableCompression)
+	ws.EnableWriteCompression(tc.cfg.EnableCompression)
 	err = ws.SetReadDeadline(time.Now().Add(common.DefaultPongWait))
 	if err != nil {
-		return nil, err
-	}
-	ws.SetPongHandler(func(string) error {
-		_ = ws.SetReadDeadline(time.Now().Add(common.DefaultPongWait))
-		return nil
-	})
-	tc := &taosConn{
-		buf:          &bytes.Buffer{},
-		client:       ws,
-		timezone:     cfg.Timezone,
-		readTimeout:  cfg.ReadTimeout,
-		writeTimeout: cfg.WriteTimeout,
-		cfg:          cfg,
-		endpoint:     endpoint,
-		closeCh:      make(chan struct{}),
-		messageChan:  make(chan *message, 10),
-	}
-	if cfg.Timezone != nil {
-		tc.timezoneStr = cfg.Timezone.String()
+		_ = ws.Close()
+		return err
 	}
 	err = tdversion.WSCheckVersion(ws)
 	if err != nil {
-		_ = tc.Close()
-		return nil, NewBadConnError(err)
+		_ = ws.Close()
+		return NewBadConnError(err)
+	}
+	s := newSession(ws, tc.writeTimeout, tc.logger)
+	tc.stateLock.Lock()
+	oldSession := tc.session
+	tc.session = s
+	tc.endpoint = endpoint
+	if oldSession != nil {
+		atomic.AddUint64(&tc.generation, 1)
+	}
+	tc.stateLock.Unlock()
+	if oldSession != nil {
+		oldSession.close()
+	}
+	if tc.isClosed() {
+		s.close()
+		return driver.ErrBadConn
 	}
-	go tc.ping()
-	go tc.read()
 	err = tc.connect()
 	if err != nil {
-		_ = tc.Close()
-		return nil, NewBadConnError(err)
+		tc.logger.Warn("connect failed", "host", host, "error", err)
+		s.close()
+		return NewBadConnError(err)
 	}
-	return tc, nil
+	tc.logger.Info("connection opened", "host", host, "generation", tc.getGeneration())
+	return nil
 }
 
-func (tc *taosConn) ping() {
-	ticker := time.NewTicker(common.DefaultPingPeriod)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-tc.closeCh:
-			return
-		case <-ticker.C:
-			_ = tc.writePing()
+// reconnect replaces the broken websocket session, the session database and timezone are restored by the connect request.
+// Concurrent requests that failed with the same session reconnect once.
+func (tc *taosConn) reconnect() error {
+	tc.reconnectLock.Lock()
+	defer tc.reconnectLock.Unlock()
+	err := tc.getMessageError()
+	if err == nil {
+		// replaced by a concurrent request
+		return nil
+	}
+	tc.logger.Warn("reconnecting", "endpoint", tc.endpoint, "error", err)
+	for i := 0; i < tc.cfg.ReconnectRetryCount; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(tc.cfg.ReconnectIntervalMs) * time.Millisecond)
 		}
+		if tc.isClosed() {
+			return driver.ErrBadConn
+		}
+		err = tc.balancer.dial(tc.open)
+		if err == nil {
+			tc.logger.Info("reconnected", "attempts", i+1)
+			metrics.GetMetrics().AddReconnect()
+			return nil
+		}
+		tc.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
+	}
+	if err == nil {
+		err = errors.New("reconnect retry count is 0")
 	}
+	tc.logger.Error("reconnect failed", "error", err)
+	return NewBadConnError(fmt.Errorf("reconnect failed: %s", err.Error()))
 }
 
-func (tc *taosConn) read() {
-	for tc.client != nil && !tc.isClosed() {
-		mt, msg, err := tc.client.ReadMessage()
-		tc.messageChan <- &message{
-			mt:      mt,
-			message: msg,
-			err:     err,
-		}
-		if err != nil {
-			tc.messageError = NewBadConnError(err)
-			break
-		}
+// ensureConnected is called before sending a new request, nothing has been sent on a broken connection,
+// so the returned error allows database/sql to retry on another connection.
+func (tc *taosConn) ensureConnected() error {
+	if tc.isClosed() {
+		return driver.ErrBadConn
 	}
+	err := tc.getMessageError()
+	if err == nil {
+		return nil
+	}
+	if !tc.cfg.AutoReconnect {
+		return err
+	}
+	return tc.reconnect()
+}
+
+// getGeneration returns the generation of the current session.
+func (tc *taosConn) getGeneration() uint64 {
+	return atomic.LoadUint64(&tc.generation)
+}
+
+func (tc *taosConn) getSession() *session {
+	tc.stateLock.RLock()
+	defer tc.stateLock.RUnlock()
+	return tc.session
+}
+
+// getMessageError returns the error that broke the current session.
+func (tc *taosConn) getMessageError() error {
+	s := tc.getSession()
+	if s == nil {
+		return driver.ErrBadConn
+	}
+	return s.getError()
 }
 
-func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "websocket does not support transaction"}
+// IsValid implements driver.Validator, a broken connection is kept if it can reconnect.
+func (tc *taosConn) IsValid() bool {
+	if tc.isClosed() {
+		return false
+	}
+	return tc.cfg.AutoReconnect || tc.getMessageError() == nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (tc *taosConn) ResetSession(_ context.Context) error {
+	if !tc.IsValid() {
+		return driver.ErrBadConn
+	}
+	return nil
 }
 
 func (tc *taosConn) Close() (err error) {
 	tc.closeOnce.Do(func() {
 		atomic.StoreUint32(&tc.closed, 1)
-		close(tc.closeCh)
-		if tc.client != nil {
-			err = tc.client.Close()
+		if s := tc.getSession(); s != nil {
+			s.close()
 		}
+		tc.logger.Info("connection closed")
 	})
 	return err
 }
@@ -181,7 +272,7 @@ func (tc *taosConn) Prepare(query string) (driver.Stmt, error) {
 }
 
 func getReqID(ctx context.Context) (uint64, error) {
-	reqID, err := common.GetReqIDFromCtx(ctx)
+	reqID, err := trace.GetReqID(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -190,6 +281,15 @@ func getReqID(ctx context.Context) (uint64, error) {
 	}
 	return uint64(reqID), nil
 }
+
+// startSpan starts a span of a request that has no req_id of its own, the req_id is derived from the span.
+func startSpan(ctx context.Context, name string, attrs ...trace.Attribute) (trace.Span, uint64) {
+	ctx, span := trace.Start(ctx, name, attrs...)
+	reqID := trace.NewReqID(ctx)
+	span.SetAttributes(trace.ReqID(reqID))
+	return span, uint64(reqID)
+}
+
 func (tc *taosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	if tc.isClosed() {
 		return nil, driver.ErrBadConn
@@ -198,21 +298,26 @@ func (tc *taosConn) PrepareContext(ctx context.Context, query string) (driver.St
 	if err != nil {
 		return nil, err
 	}
-	stmtID, err := tc.stmtInit(reqID)
+	err = tc.ensureConnected()
 	if err != nil {
 		return nil, err
 	}
-	isInsert, err := tc.stmtPrepare(stmtID, query)
+	stmt := &Stmt{
+		conn:    tc,
+		pSql:    query,
+		isStmt2: tc.cfg.EnableStmt2 && atomic.LoadUint32(&tc.stmt2Unsupported) == 0,
+	}
+	err = stmt.prepare(reqID)
+	if err != nil && isBadConnError(err) && tc.cfg.AutoReconnect {
+		// prepare has no side effect, it is safe to replay
+		err = tc.reconnect()
+		if err == nil {
+			err = stmt.prepare(reqID)
+		}
+	}
 	if err != nil {
-		_ = tc.stmtClose(stmtID)
 		return nil, err
 	}
-	stmt := &Stmt{
-		conn:     tc,
-		stmtID:   stmtID,
-		isInsert: isInsert,
-		pSql:     query,
-	}
 	return stmt, nil
 }
 
@@ -228,17 +333,14 @@ func (tc *taosConn) stmtInit(reqID uint64) (uint64, error) {
 		Action: STMTInit,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return 0, err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return 0, err
 	}
 	var resp StmtInitResp
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return 0, err
@@ -261,17 +363,14 @@ func (tc *taosConn) stmtPrepare(stmtID uint64, sql string) (bool, error) {
 		Action: STMTPrepare,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return false, err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return false, err
 	}
 	var resp StmtPrepareResponse
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return false, err
@@ -293,16 +392,13 @@ func (tc *taosConn) stmtClose(stmtID uint64) error {
 		Action: STMTClose,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return err
 	}
-	err = tc.writeText(tc.buf.Bytes())
-	if err != nil {
-		return err
-	}
-	return nil
+	return tc.writeText(envelope)
 }
 
 func (tc *taosConn) stmtGetColFields(stmtID uint64) ([]*stmtCommon.StmtField, error) {
@@ -319,17 +415,14 @@ func (tc *taosConn) stmtGetColFields(stmtID uint64) ([]*stmtCommon.StmtField, er
 		Action: STMTGetColFields,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return nil, err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return nil, err
 	}
 	var resp StmtGetColFieldsResponse
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return nil, err
@@ -337,19 +430,19 @@ func (tc *taosConn) stmtGetColFields(stmtID uint64) ([]*stmtCommon.StmtField, er
 	return resp.Fields, nil
 }
 
-func (tc *taosConn) stmtBindParam(stmtID uint64, block []byte) error {
-	reqID := uint64(common.GetReqID())
-	tc.buf.Reset()
-	WriteUint64(tc.buf, reqID)
-	WriteUint64(tc.buf, stmtID)
-	WriteUint64(tc.buf, BindMessage)
-	tc.buf.Write(block)
-	err := tc.writeBinary(tc.buf.Bytes())
-	if err != nil {
-		return err
-	}
+func (tc *taosConn) stmtBindParam(ctx context.Context, stmtID uint64, block []byte) (err error) {
+	span, reqID := startSpan(ctx, trace.SpanStmtBind, trace.Bytes(len(block)))
+	defer func() {
+		trace.End(span, err)
+	}()
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	WriteUint64(envelope.Msg, reqID)
+	WriteUint64(envelope.Msg, stmtID)
+	WriteUint64(envelope.Msg, BindMessage)
+	envelope.Msg.Write(block)
 	var resp StmtBindResponse
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.BinaryMessage, envelope, reqID, &resp)
 	return handleResponseError(err, resp.Code, resp.Message)
 }
 
@@ -390,22 +483,23 @@ func (tc *taosConn) stmtAddBatch(stmtID uint64) error {
 		Action: STMTAddBatch,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return err
 	}
 	var resp StmtAddBatchResponse
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	return handleResponseError(err, resp.Code, resp.Message)
 }
 
-func (tc *taosConn) stmtExec(stmtID uint64) (int, error) {
-	reqID := uint64(common.GetReqID())
+func (tc *taosConn) stmtExec(ctx context.Context, stmtID uint64) (affected int, err error) {
+	span, reqID := startSpan(ctx, trace.SpanStmtExec)
+	defer func() {
+		span.SetAttributes(trace.Rows(affected))
+		trace.End(span, err)
+	}()
 	req := &StmtExecRequest{
 		ReqID:  reqID,
 		StmtID: stmtID,
@@ -418,17 +512,14 @@ func (tc *taosConn) stmtExec(stmtID uint64) (int, error) {
 		Action: STMTExec,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return 0, err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return 0, err
 	}
 	var resp StmtExecResponse
-	err = tc.readTo(&resp, reqID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return 0, err
@@ -450,17 +541,166 @@ func (tc *taosConn) stmtUseResult(stmtID uint64) (*rows, error) {
 		Action: STMTUseResult,
 		Args:   reqArgs,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return nil, err
 	}
-	err = tc.writeText(tc.buf.Bytes())
+	var resp StmtUseResultResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
+	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return nil, err
 	}
-	var resp StmtUseResultResponse
-	err = tc.readTo(&resp, reqID)
+	rs := newRows(
+		tc,
+		resp.ResultID,
+		resp.FieldsCount,
+		resp.FieldsNames,
+		resp.FieldsTypes,
+		resp.FieldsLengths,
+		resp.FieldsPrecisions,
+		resp.FieldsScales,
+		resp.Precision,
+		true,
+		tc.timezone,
+	)
+	return rs, nil
+}
+
+func (tc *taosConn) stmt2Init(reqID uint64) (uint64, error) {
+	req := &Stmt2InitRequest{
+		ReqID: reqID,
+	}
+	reqArgs, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	action := &WSAction{
+		Action: STMT2Init,
+		Args:   reqArgs,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return 0, err
+	}
+	var resp Stmt2InitResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
+	err = handleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StmtID, nil
+}
+
+func (tc *taosConn) stmt2Prepare(stmtID uint64, sql string) (bool, []*stmtCommon.Stmt2AllField, error) {
+	reqID := uint64(common.GetReqID())
+	req := &Stmt2PrepareRequest{
+		ReqID:     reqID,
+		StmtID:    stmtID,
+		SQL:       sql,
+		GetFields: true,
+	}
+	reqArgs, err := json.Marshal(req)
+	if err != nil {
+		return false, nil, err
+	}
+	action := &WSAction{
+		Action: STMT2Prepare,
+		Args:   reqArgs,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return false, nil, err
+	}
+	var resp Stmt2PrepareResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
+	err = handleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return false, nil, err
+	}
+	return resp.IsInsert, resp.Fields, nil
+}
+
+func (tc *taosConn) stmt2Bind(ctx context.Context, stmtID uint64, data []byte) (err error) {
+	span, reqID := startSpan(ctx, trace.SpanStmtBind, trace.Bytes(len(data)))
+	defer func() {
+		trace.End(span, err)
+	}()
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	WriteUint64(envelope.Msg, reqID)
+	WriteUint64(envelope.Msg, stmtID)
+	WriteUint64(envelope.Msg, Stmt2BindMessage)
+	WriteUint16(envelope.Msg, Stmt2BindProtocolVersion1)
+	// col_idx -1 means bind all columns
+	WriteUint32(envelope.Msg, math.MaxUint32)
+	envelope.Msg.Write(data)
+	var resp Stmt2BindResponse
+	_, err = tc.requestTo(websocket.BinaryMessage, envelope, reqID, &resp)
+	return handleResponseError(err, resp.Code, resp.Message)
+}
+
+func (tc *taosConn) stmt2Exec(ctx context.Context, stmtID uint64) (affected int, err error) {
+	span, reqID := startSpan(ctx, trace.SpanStmtExec)
+	defer func() {
+		span.SetAttributes(trace.Rows(affected))
+		trace.End(span, err)
+	}()
+	req := &Stmt2ExecRequest{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	reqArgs, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	action := &WSAction{
+		Action: STMT2Exec,
+		Args:   reqArgs,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return 0, err
+	}
+	var resp Stmt2ExecResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
+	err = handleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Affected, nil
+}
+
+func (tc *taosConn) stmt2UseResult(stmtID uint64) (*rows, error) {
+	reqID := uint64(common.GetReqID())
+	req := &Stmt2UseResultRequest{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	reqArgs, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	action := &WSAction{
+		Action: STMT2Result,
+		Args:   reqArgs,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return nil, err
+	}
+	var resp Stmt2UseResultResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
 	err = handleResponseError(err, resp.Code, resp.Message)
 	if err != nil {
 		return nil, err
@@ -481,11 +721,39 @@ func (tc *taosConn) stmtUseResult(stmtID uint64) (*rows, error) {
 	return rs, nil
 }
 
+func (tc *taosConn) stmt2Close(stmtID uint64) error {
+	reqID := uint64(common.GetReqID())
+	req := &Stmt2CloseRequest{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	reqArgs, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	action := &WSAction{
+		Action: STMT2Close,
+		Args:   reqArgs,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return err
+	}
+	var resp Stmt2CloseResponse
+	_, err = tc.requestTo(websocket.TextMessage, envelope, reqID, &resp)
+	return handleResponseError(err, resp.Code, resp.Message)
+}
+
 func (tc *taosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
 	return tc.execCtx(ctx, query, args)
 }
 
 func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if tc.tx != nil {
+		return tc.tx.exec(query, args)
+	}
 	resp, err := tc.doQuery(ctx, query, args)
 	if err != nil {
 		return nil, err
@@ -501,6 +769,9 @@ func (tc *taosConn) QueryContext(ctx context.Context, query string, args []drive
 }
 
 func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if tc.tx != nil {
+		return nil, errTxBatchNotInsert()
+	}
 	resp, err := tc.doQuery(ctx, query, args)
 	if err != nil {
 		return nil, err
@@ -524,17 +795,14 @@ func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.Na
 		false,
 		tc.timezone,
 	)
+	rs.ctx = ctx
 	return rs, err
 }
 
-func (tc *taosConn) doQuery(ctx context.Context, query string, args []driver.NamedValue) (*WSQueryResp, error) {
+func (tc *taosConn) doQuery(ctx context.Context, query string, args []driver.NamedValue) (resp *WSQueryResp, err error) {
 	if tc.isClosed() {
 		return nil, driver.ErrBadConn
 	}
-	reqID, err := getReqID(ctx)
-	if err != nil {
-		return nil, err
-	}
 	if len(args) != 0 {
 		if !tc.cfg.InterpolateParams {
 			return nil, driver.ErrSkip
@@ -546,40 +814,162 @@ func (tc *taosConn) doQuery(ctx context.Context, query string, args []driver.Nam
 		}
 		query = prepared
 	}
-	tc.buf.Reset()
-
-	WriteUint64(tc.buf, reqID) // req id
-	WriteUint64(tc.buf, 0)     // message id
-	WriteUint64(tc.buf, BinaryQueryMessage)
-	WriteUint16(tc.buf, 1)                  // version
-	WriteUint32(tc.buf, uint32(len(query))) // sql length
-	tc.buf.WriteString(query)
-	err = tc.writeBinary(tc.buf.Bytes())
+	start := time.Now()
+	spanCtx, span := trace.Start(ctx, trace.SpanQuery, trace.SQL(query))
+	defer func() {
+		endQuery(span, start, resp, err)
+	}()
+	reqID, err := getReqID(spanCtx)
 	if err != nil {
 		return nil, err
 	}
-	var resp WSQueryResp
-	err = tc.readTo(&resp, reqID)
+	span.SetAttributes(trace.ReqID(int64(reqID)))
+	err = tc.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	tc.logger.Debug("query", "req_id", reqID)
+	defer log.Slow(tc.logger, "query", reqID, start)
+	resp, sent, err := tc.sendQuery(ctx, reqID, query)
+	if err != nil && isBadConnError(err) {
+		if sent && !common.IsReadOnlySQL(query) {
+			// the server may have executed the request, replaying it could write twice
+			return nil, &ConnLostError{err: err}
+		}
+		if !tc.cfg.AutoReconnect {
+			return nil, err
+		}
+		err = tc.reconnect()
+		if err != nil {
+			return nil, err
+		}
+		resp, _, err = tc.sendQuery(ctx, reqID, query)
+		if err != nil && isBadConnError(err) && !common.IsReadOnlySQL(query) {
+			return nil, &ConnLostError{err: err}
+		}
+	}
 	if err != nil {
+		tc.logger.Debug("query failed", "req_id", reqID, "error", err)
 		return nil, err
 	}
-	return &resp, nil
+	if resp.Code != 0 {
+		tc.logger.Debug("query failed", "req_id", reqID, "code", resp.Code, "message", resp.Message)
+	}
+	if resp.Code == 0 {
+		if db, ok := common.ParseUseDB(query); ok {
+			// restored by the connect request after a reconnect
+			tc.stateLock.Lock()
+			tc.currentDB = db
+			tc.stateLock.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// endQuery records a query started at start on its span and in the metrics
+func endQuery(span trace.Span, start time.Time, resp *WSQueryResp, err error) {
+	if err == nil && resp.Code != 0 {
+		err = taosErrors.NewError(resp.Code, resp.Message)
+	}
+	if err == nil && resp.IsUpdate {
+		span.SetAttributes(trace.Rows(resp.AffectedRows))
+	}
+	metrics.GetMetrics().ObserveRequest(metrics.KindQuery, time.Since(start), err)
+	trace.End(span, err)
+}
+
+// sendQuery reports whether the request has been written to the server. When ctx is done before the response,
+// the result of the query is freed when its response arrives.
+func (tc *taosConn) sendQuery(ctx context.Context, reqID uint64, query string) (*WSQueryResp, bool, error) {
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	WriteUint64(envelope.Msg, reqID) // req id
+	WriteUint64(envelope.Msg, 0)     // message id
+	WriteUint64(envelope.Msg, BinaryQueryMessage)
+	WriteUint16(envelope.Msg, 1)                  // version
+	WriteUint32(envelope.Msg, uint32(len(query))) // sql length
+	envelope.Msg.WriteString(query)
+	msg, sent, err := tc.request(ctx, websocket.BinaryMessage, envelope, reqID, tc.freeLateResult)
+	if err != nil {
+		return nil, sent, err
+	}
+	var resp WSQueryResp
+	err = decodeResponse(msg, &resp)
+	if err != nil {
+		tc.logger.Error("protocol error", "req_id", reqID, "error", err)
+		return nil, true, err
+	}
+	return &resp, true, nil
+}
+
+// freeLateResult frees the result of a query response that arrived after the query was canceled, on the session
+// that received it. The result id is not valid on a session that replaced it.
+func (tc *taosConn) freeLateResult(s *session, msg *message) {
+	var resp WSQueryResp
+	if decodeResponse(msg, &resp) != nil || resp.Code != 0 || resp.IsUpdate || resp.ID == 0 {
+		return
+	}
+	if tc.isClosed() || s != tc.getSession() {
+		return
+	}
+	_ = freeResultOn(s, resp.ID)
+}
+
+// freeResult releases a result on the current session, free_result has no response.
+func (tc *taosConn) freeResult(resultID uint64) error {
+	if tc.isClosed() {
+		return driver.ErrBadConn
+	}
+	s := tc.getSession()
+	if s == nil {
+		return driver.ErrBadConn
+	}
+	return freeResultOn(s, resultID)
+}
+
+func freeResultOn(s *session, resultID uint64) error {
+	req := &WSFreeResultReq{
+		ReqID: uint64(common.GetReqID()),
+		ID:    resultID,
+	}
+	args, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	action := &WSAction{
+		Action: WSFreeResult,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return err
+	}
+	envelope.Type = websocket.TextMessage
+	return s.write(envelope)
 }
 
 func (tc *taosConn) Ping(ctx context.Context) (err error) {
 	if tc.isClosed() {
 		return driver.ErrBadConn
 	}
-	return tc.writePing()
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	envelope.Type = websocket.PingMessage
+	return tc.write(envelope)
 }
 
 func (tc *taosConn) connect() error {
 	redID := uint64(common.GetReqID())
+	tc.stateLock.RLock()
+	db := tc.currentDB
+	tc.stateLock.RUnlock()
 	req := &WSConnectReq{
 		ReqID:       redID,
 		User:        tc.cfg.User,
 		Password:    tc.cfg.Passwd,
-		DB:          tc.cfg.DbName,
+		DB:          db,
 		TZ:          tc.timezoneStr,
 		App:         common.GetProcessName(),
 		Connector:   common.GetConnectorInfo("ws"),
@@ -594,103 +984,88 @@ func (tc *taosConn) connect() error {
 		Action: WSConnect,
 		Args:   args,
 	}
-	tc.buf.Reset()
-	err = jsonI.NewEncoder(tc.buf).Encode(action)
-	if err != nil {
-		return err
-	}
-	err = tc.writeText(tc.buf.Bytes())
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = jsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
 		return err
 	}
 	var resp WSConnectResp
-	err = tc.readTo(&resp, redID)
+	_, err = tc.requestTo(websocket.TextMessage, envelope, redID, &resp)
 	return handleResponseError(err, resp.Code, resp.Message)
 }
 
-func (tc *taosConn) writeText(data []byte) error {
-	return tc.write(websocket.TextMessage, data)
+// writeText writes a text message that has no response.
+func (tc *taosConn) writeText(envelope *client.Envelope) error {
+	envelope.Type = websocket.TextMessage
+	return tc.write(envelope)
 }
 
-func (tc *taosConn) writeBinary(data []byte) error {
-	return tc.write(websocket.BinaryMessage, data)
-}
-
-func (tc *taosConn) writePing() error {
-	return tc.write(websocket.PingMessage, nil)
+func (tc *taosConn) write(envelope *client.Envelope) error {
+	if tc.isClosed() {
+		return driver.ErrBadConn
+	}
+	s := tc.getSession()
+	if s == nil {
+		return driver.ErrBadConn
+	}
+	return s.write(envelope)
 }
 
-func (tc *taosConn) write(messageType int, data []byte) error {
-	tc.writeLock.Lock()
-	defer tc.writeLock.Unlock()
+// request sends a message of messageType on the current session and waits for the response with reqID,
+// it reports whether the message has been written. See session.request for ctx and discard.
+func (tc *taosConn) request(ctx context.Context, messageType int, envelope *client.Envelope, reqID uint64, discard func(*session, *message)) (*message, bool, error) {
 	if tc.isClosed() {
-		return driver.ErrBadConn
+		return nil, false, driver.ErrBadConn
 	}
-	if tc.messageError != nil {
-		return tc.messageError
+	s := tc.getSession()
+	if s == nil {
+		return nil, false, driver.ErrBadConn
 	}
-	err := tc.client.SetWriteDeadline(time.Now().Add(tc.writeTimeout))
+	envelope.Type = messageType
+	return s.request(ctx, envelope, reqID, tc.readTimeout, discard)
+}
+
+// requestTo sends a message and decodes the JSON response into to.
+func (tc *taosConn) requestTo(messageType int, envelope *client.Envelope, reqID uint64, to interface{}) (bool, error) {
+	start := time.Now()
+	msg, sent, err := tc.request(context.Background(), messageType, envelope, reqID, nil)
 	if err != nil {
-		return NewBadConnError(err)
+		return sent, err
 	}
-	err = tc.client.WriteMessage(messageType, data)
+	log.Slow(tc.logger, "request", reqID, start)
+	err = decodeResponse(msg, to)
 	if err != nil {
-		return NewBadConnErrorWithCtx(err, string(data))
+		tc.logger.Error("protocol error", "req_id", reqID, "error", err)
 	}
-	return nil
+	return true, err
 }
 
-func (tc *taosConn) readTo(to interface{}, expectRedID uint64) error {
-	mt, respBytes, err := tc.readResponse()
-	if err != nil {
-		return err
+func decodeResponse(msg *message, to interface{}) error {
+	if msg.mt != websocket.TextMessage {
+		return fmt.Errorf("decodeResponse: got wrong message type %d, context: %s", msg.mt, formatBytes(msg.message))
 	}
-	if mt != websocket.TextMessage {
-		return NewBadConnErrorWithCtx(fmt.Errorf("readTo: got wrong message type %d", mt), formatBytes(respBytes))
-	}
-	err = jsonI.Unmarshal(respBytes, to)
+	err := jsonI.Unmarshal(msg.message, to)
 	if err != nil {
-		return NewBadConnErrorWithCtx(err, string(respBytes))
-	}
-	if respI, ok := to.(RespInterface); ok {
-		if respI.GetReqID() != expectRedID {
-			return NewBadConnErrorWithCtx(fmt.Errorf("readTo: got wrong reqID %d, expect %d", respI.GetReqID(), expectRedID), string(respBytes))
-		}
+		return fmt.Errorf("decodeResponse: %w, context: %s", err, string(msg.message))
 	}
 	return nil
 }
 
-func (tc *taosConn) readBytes() ([]byte, error) {
-	mt, respBytes, err := tc.readResponse()
+// requestBytes sends a binary message and returns the binary response, it returns ctx.Err() when ctx is done first.
+func (tc *taosConn) requestBytes(ctx context.Context, envelope *client.Envelope, reqID uint64) ([]byte, error) {
+	start := time.Now()
+	msg, _, err := tc.request(ctx, websocket.BinaryMessage, envelope, reqID, nil)
 	if err != nil {
 		return nil, err
 	}
-	if mt != websocket.BinaryMessage {
-		return nil, NewBadConnErrorWithCtx(fmt.Errorf("readBytes: got wrong message type %d", mt), string(respBytes))
-	}
-	return respBytes, err
-}
-
-func (tc *taosConn) readResponse() (int, []byte, error) {
-	if tc.isClosed() {
-		return 0, nil, driver.ErrBadConn
-	}
-	if tc.messageError != nil {
-		return 0, nil, tc.messageError
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), tc.readTimeout)
-	defer cancel()
-	select {
-	case <-tc.closeCh:
-		return 0, nil, driver.ErrBadConn
-	case msg := <-tc.messageChan:
-		if msg.err != nil {
-			return 0, nil, NewBadConnError(msg.err)
-		}
-		return msg.mt, msg.message, nil
-	case <-ctx.Done():
-		return 0, nil, NewBadConnError(ReadTimeoutError)
+	log.Slow(tc.logger, "fetch", reqID, start)
+	if msg.mt != websocket.BinaryMessage {
+		err = fmt.Errorf("requestBytes: got wrong message type %d, context: %s", msg.mt, string(msg.message))
+		tc.logger.Error("protocol error", "req_id", reqID, "error", err)
+		return nil, err
 	}
+	return msg.message, nil
 }
 
 func formatBytes(bs []byte) string {
@@ -3,12 +3,15 @@ package taosWS
 import (
 	"context"
 	"database/sql/driver"
+	"sync"
 
 	"github.com/taosdata/driver-go/v3/common"
 )
 
 type connector struct {
-	cfg *Config
+	cfg          *Config
+	balancerOnce sync.Once
+	balancer     *endpointBalancer
 }
 
 // Connect implements driver.Connector interface.
@@ -36,8 +39,22 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 	if c.cfg.WriteTimeout == 0 {
 		c.cfg.WriteTimeout = common.DefaultWriteWait
 	}
-	tc, err := newTaosConn(c.cfg)
-	return tc, err
+	if c.cfg.AutoReconnect {
+		if c.cfg.ReconnectRetryCount == 0 {
+			c.cfg.ReconnectRetryCount = 3
+		}
+		if c.cfg.ReconnectIntervalMs == 0 {
+			c.cfg.ReconnectIntervalMs = 2000
+		}
+	}
+	c.balancerOnce.Do(func() {
+		c.balancer = newEndpointBalancer(c.cfg)
+	})
+	tc, err := newTaosConnWithBalancer(c.cfg, c.balancer)
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
 }
 
 // Driver implements driver.Connector interface.
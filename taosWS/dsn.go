@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
 	"github.com/taosdata/driver-go/v3/errors"
 )
 
@@ -22,27 +23,47 @@ var (
 // If a new Config is created instead of being parsed from a DSN string,
 // the NewConfig function should be used, which sets default values.
 type Config struct {
-	User              string // Username
-	Passwd            string // Password (requires User)
-	Net               string // Network type
-	Addr              string // Network address (requires Net)
-	Port              int
-	DbName            string            // Database name
-	Params            map[string]string // Connection parameters
-	InterpolateParams bool              // Interpolate placeholders into query string
-	Token             string            // cloud platform Token
-	EnableCompression bool              // Enable write compression
-	ReadTimeout       time.Duration     // read message timeout
-	WriteTimeout      time.Duration     // write message timeout
-	Timezone          *time.Location    // Timezone for connection, e.g., "Asia%2FShanghai" or "UTC"
-	BearerToken       string            // BearerToken for TSDB auth
-	TotpCode          string            // TOTP code for TSDB TOTP auth
+	User                string // Username
+	Passwd              string // Password (requires User)
+	Net                 string // Network type
+	Addr                string // Network address (requires Net)
+	Port                int
+	DbName              string            // Database name
+	Params              map[string]string // Connection parameters
+	InterpolateParams   bool              // Interpolate placeholders into query string
+	Token               string            // cloud platform Token
+	EnableCompression   bool              // Enable write compression
+	ReadTimeout         time.Duration     // read message timeout
+	WriteTimeout        time.Duration     // write message timeout
+	Timezone            *time.Location    // Timezone for connection, e.g., "Asia%2FShanghai" or "UTC"
+	BearerToken         string            // BearerToken for TSDB auth
+	TotpCode            string            // TOTP code for TSDB TOTP auth
+	EnableStmt2         bool              // Use stmt2 for prepared statements, stmt1 is used if the server rejects stmt2
+	TxBatch             bool              // Begin returns a write batch that buffers inserts until commit
+	Endpoints           []Endpoint        // All endpoints when multiple addresses are specified, Addr and Port hold the first one
+	LoadBalance         string            // Endpoint selection for new connections, LoadBalanceRoundRobin or LoadBalanceRandom
+	AutoReconnect       bool              // Reconnect a broken connection and replay requests that are safe to replay
+	ReconnectRetryCount int               // Reconnect attempts, default 3
+	ReconnectIntervalMs int               // Interval between reconnect attempts in milliseconds, default 2000
+	Logger              log.Logger        // Logger of the connections, the package-level logger of common/log if nil, not parsed from DSN
 }
 
+// Endpoint is a taosAdapter address.
+type Endpoint struct {
+	Host string
+	Port int
+}
+
+const (
+	LoadBalanceRoundRobin = "roundRobin"
+	LoadBalanceRandom     = "random"
+)
+
 // NewConfig creates a new Config and sets default values.
 func NewConfig() *Config {
 	return &Config{
 		InterpolateParams: true,
+		EnableStmt2:       true,
 	}
 }
 
@@ -51,7 +72,7 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 	// New Config with some default values
 	cfg = NewConfig()
 
-	// [user[:password]@][net[(addr)]]/dbname[?param1=value1&paramN=valueN]
+	// [user[:password]@][net[(addr[,addr...])]]/dbname[?param1=value1&paramN=valueN]
 	// Find the last '/' (since the password or the net addr might contain a '/')
 	foundSlash := false
 	for i := len(dsn) - 1; i >= 0; i-- {
@@ -90,18 +111,27 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 							}
 							//return nil, errInvalidDSNAddr
 						}
-						addr := dsn[k+1 : i-1]
-						host, port, err := net.SplitHostPort(addr)
-						if err != nil {
-							return nil, ErrInvalidDSNAddr
-						}
-						if len(port) != 0 {
-							cfg.Port, err = strconv.Atoi(port)
+						addrs := strings.Split(dsn[k+1:i-1], ",")
+						for index, addr := range addrs {
+							host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
 							if err != nil {
-								return nil, ErrInvalidDSNPort
+								return nil, ErrInvalidDSNAddr
+							}
+							endpoint := Endpoint{Host: host}
+							if len(port) != 0 {
+								endpoint.Port, err = strconv.Atoi(port)
+								if err != nil {
+									return nil, ErrInvalidDSNPort
+								}
+							}
+							if index == 0 {
+								cfg.Addr = endpoint.Host
+								cfg.Port = endpoint.Port
+							}
+							if len(addrs) > 1 {
+								cfg.Endpoints = append(cfg.Endpoints, endpoint)
 							}
 						}
-						cfg.Addr = host
 						break
 					}
 				}
@@ -178,6 +208,36 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			cfg.BearerToken = value
 		case "totpCode":
 			cfg.TotpCode = value
+		case "enableStmt2":
+			cfg.EnableStmt2, err = strconv.ParseBool(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid enableStmt2 value: " + value}
+			}
+		case "loadBalance":
+			if value != LoadBalanceRoundRobin && value != LoadBalanceRandom {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid loadBalance value: " + value}
+			}
+			cfg.LoadBalance = value
+		case "txBatch":
+			cfg.TxBatch, err = strconv.ParseBool(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid txBatch value: " + value}
+			}
+		case "autoReconnect":
+			cfg.AutoReconnect, err = strconv.ParseBool(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid autoReconnect value: " + value}
+			}
+		case "reconnectRetryCount":
+			cfg.ReconnectRetryCount, err = strconv.Atoi(value)
+			if err != nil || cfg.ReconnectRetryCount < 0 {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid reconnectRetryCount value: " + value}
+			}
+		case "reconnectIntervalMs":
+			cfg.ReconnectIntervalMs, err = strconv.Atoi(value)
+			if err != nil || cfg.ReconnectIntervalMs < 0 {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid reconnectIntervalMs value: " + value}
+			}
 		default:
 			// lazy init
 			if cfg.Params == nil {
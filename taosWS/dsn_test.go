@@ -20,14 +20,14 @@ func TestParseDsn(t *testing.T) {
 		want *Config
 	}{
 		{name: "invalid DSN", dsn: "abcd", errs: "invalid DSN: missing the slash separating the database name"},
-		{name: "common DSN", dsn: "user:passwd@ws(fqdn:6041)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", Addr: "fqdn", Port: 6041, DbName: "dbname", InterpolateParams: true}},
+		{name: "common DSN", dsn: "user:passwd@ws(fqdn:6041)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", Addr: "fqdn", Port: 6041, DbName: "dbname", InterpolateParams: true, EnableStmt2: true}},
 		{name: "missing closing brace", dsn: "user:passwd@ws()/dbname", errs: "invalid DSN: network address not terminated (missing closing brace)"},
-		{name: "default address", dsn: "user:passwd@ws(:)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", DbName: "dbname", InterpolateParams: true}},
-		{name: "0 port", dsn: "user:passwd@ws(:0)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", DbName: "dbname", InterpolateParams: true}},
-		{name: "wss protocol", dsn: "user:passwd@wss(:0)/", want: &Config{User: "user", Passwd: "passwd", Net: "wss", InterpolateParams: true}},
-		{name: "params", dsn: "user:passwd@wss(:0)/?interpolateParams=false&test=1", want: &Config{User: "user", Passwd: "passwd", Net: "wss", Params: map[string]string{"test": "1"}}},
-		{name: "token", dsn: "user:passwd@wss(:0)/?interpolateParams=false&token=token", want: &Config{User: "user", Passwd: "passwd", Net: "wss", Token: "token"}},
-		{name: "readTimeout", dsn: "user:passwd@wss(:0)/?writeTimeout=8s&readTimeout=10m", want: &Config{User: "user", Passwd: "passwd", Net: "wss", ReadTimeout: 10 * time.Minute, WriteTimeout: 8 * time.Second, InterpolateParams: true}},
+		{name: "default address", dsn: "user:passwd@ws(:)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", DbName: "dbname", InterpolateParams: true, EnableStmt2: true}},
+		{name: "0 port", dsn: "user:passwd@ws(:0)/dbname", want: &Config{User: "user", Passwd: "passwd", Net: "ws", DbName: "dbname", InterpolateParams: true, EnableStmt2: true}},
+		{name: "wss protocol", dsn: "user:passwd@wss(:0)/", want: &Config{User: "user", Passwd: "passwd", Net: "wss", InterpolateParams: true, EnableStmt2: true}},
+		{name: "params", dsn: "user:passwd@wss(:0)/?interpolateParams=false&test=1", want: &Config{User: "user", Passwd: "passwd", Net: "wss", Params: map[string]string{"test": "1"}, EnableStmt2: true}},
+		{name: "token", dsn: "user:passwd@wss(:0)/?interpolateParams=false&token=token", want: &Config{User: "user", Passwd: "passwd", Net: "wss", Token: "token", EnableStmt2: true}},
+		{name: "readTimeout", dsn: "user:passwd@wss(:0)/?writeTimeout=8s&readTimeout=10m", want: &Config{User: "user", Passwd: "passwd", Net: "wss", ReadTimeout: 10 * time.Minute, WriteTimeout: 8 * time.Second, InterpolateParams: true, EnableStmt2: true}},
 		{name: "compression", dsn: "user:passwd@wss(:0)/?writeTimeout=8s&readTimeout=10m&enableCompression=true", want: &Config{
 			User:              "user",
 			Passwd:            "passwd",
@@ -36,6 +36,7 @@ func TestParseDsn(t *testing.T) {
 			WriteTimeout:      8 * time.Second,
 			InterpolateParams: true,
 			EnableCompression: true,
+			EnableStmt2:       true,
 		}},
 		// encodeURIComponent('!@#$%^&*()-_+=[]{}:;><?|~,.')
 		{
@@ -50,6 +51,7 @@ func TestParseDsn(t *testing.T) {
 				WriteTimeout:      8 * time.Second,
 				InterpolateParams: true,
 				EnableCompression: true,
+				EnableStmt2:       true,
 			},
 		},
 		//encodeURIComponent('!q@w#a$1%3^&*()-_+=[]{}:;><?|~,.')
@@ -65,6 +67,7 @@ func TestParseDsn(t *testing.T) {
 				WriteTimeout:      8 * time.Second,
 				InterpolateParams: true,
 				EnableCompression: true,
+				EnableStmt2:       true,
 			},
 		},
 		{
@@ -78,6 +81,7 @@ func TestParseDsn(t *testing.T) {
 				Port:              6041,
 				DbName:            "dbname",
 				InterpolateParams: true,
+				EnableStmt2:       true,
 			},
 		},
 		{
@@ -92,6 +96,7 @@ func TestParseDsn(t *testing.T) {
 				DbName:            "dbname",
 				InterpolateParams: true,
 				Timezone:          shangHaiTimezone,
+				EnableStmt2:       true,
 			},
 		},
 		{
@@ -106,6 +111,7 @@ func TestParseDsn(t *testing.T) {
 				DbName:            "dbname",
 				InterpolateParams: true,
 				Timezone:          time.UTC,
+				EnableStmt2:       true,
 			},
 		},
 		{
@@ -131,12 +137,73 @@ func TestParseDsn(t *testing.T) {
 		{
 			name: "totp",
 			dsn:  "user:passwd@ws(:0)/?totpCode=123456",
-			want: &Config{User: "user", Passwd: "passwd", Net: "ws", TotpCode: "123456", InterpolateParams: true},
+			want: &Config{User: "user", Passwd: "passwd", Net: "ws", TotpCode: "123456", InterpolateParams: true, EnableStmt2: true},
+		},
+		{
+			name: "multiple endpoints",
+			dsn:  "user:passwd@ws(host1:6041,host2:6042,[::1]:6043)/dbname?loadBalance=random",
+			want: &Config{
+				User:              "user",
+				Passwd:            "passwd",
+				Net:               "ws",
+				Addr:              "host1",
+				Port:              6041,
+				DbName:            "dbname",
+				InterpolateParams: true,
+				EnableStmt2:       true,
+				Endpoints:         []Endpoint{{Host: "host1", Port: 6041}, {Host: "host2", Port: 6042}, {Host: "::1", Port: 6043}},
+				LoadBalance:       LoadBalanceRandom,
+			},
+		},
+		{
+			name: "invalid endpoint",
+			dsn:  "user:passwd@ws(host1:6041,host2)/dbname",
+			errs: "invalid DSN: network address not terminated (missing closing brace)",
+		},
+		{
+			name: "invalid loadBalance",
+			dsn:  "user:passwd@ws(host1:6041,host2:6041)/dbname?loadBalance=abc",
+			errs: "invalid loadBalance value: abc",
+		},
+		{
+			name: "stmt2",
+			dsn:  "user:passwd@ws(:0)/?enableStmt2=false&txBatch=true",
+			want: &Config{User: "user", Passwd: "passwd", Net: "ws", InterpolateParams: true, TxBatch: true},
+		},
+		{
+			name: "invalid enableStmt2",
+			dsn:  "user:passwd@ws(:0)/?enableStmt2=abc",
+			errs: "invalid enableStmt2 value: abc",
+		},
+		{
+			name: "invalid txBatch",
+			dsn:  "user:passwd@ws(:0)/?txBatch=abc",
+			errs: "invalid txBatch value: abc",
+		},
+		{
+			name: "auto reconnect",
+			dsn:  "user:passwd@ws(:0)/?autoReconnect=true&reconnectRetryCount=5&reconnectIntervalMs=100",
+			want: &Config{User: "user", Passwd: "passwd", Net: "ws", InterpolateParams: true, EnableStmt2: true, AutoReconnect: true, ReconnectRetryCount: 5, ReconnectIntervalMs: 100},
+		},
+		{
+			name: "invalid autoReconnect",
+			dsn:  "user:passwd@ws(:0)/?autoReconnect=abc",
+			errs: "invalid autoReconnect value: abc",
+		},
+		{
+			name: "invalid reconnectRetryCount",
+			dsn:  "user:passwd@ws(:0)/?reconnectRetryCount=-1",
+			errs: "invalid reconnectRetryCount value: -1",
+		},
+		{
+			name: "invalid reconnectIntervalMs",
+			dsn:  "user:passwd@ws(:0)/?reconnectIntervalMs=abc",
+			errs: "invalid reconnectIntervalMs value: abc",
 		},
 		{
 			name: "bearer token",
 			dsn:  "@ws(:0)/?bearerToken=myBearerToken",
-			want: &Config{Net: "ws", BearerToken: "myBearerToken", InterpolateParams: true},
+			want: &Config{Net: "ws", BearerToken: "myBearerToken", InterpolateParams: true, EnableStmt2: true},
 		},
 	}
 	for _, tc := range tests {
@@ -0,0 +1,181 @@
+package taosWS
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/tdversion"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+const (
+	endpointMinBackoff = time.Second
+	endpointMaxBackoff = time.Minute
+)
+
+type endpointState struct {
+	host     string
+	port     int
+	failures int
+	retryAt  time.Time
+}
+
+func (e *endpointState) String() string {
+	return net.JoinHostPort(e.host, strconv.Itoa(e.port))
+}
+
+// endpointBalancer selects the endpoint for each new connection, endpoints that failed
+// are skipped until their backoff expires.
+type endpointBalancer struct {
+	lock      sync.Mutex
+	endpoints []*endpointState
+	next      int
+	random    bool
+	rand      *rand.Rand
+	now       func() time.Time
+}
+
+func newEndpointBalancer(cfg *Config) *endpointBalancer {
+	endpoints := cfg.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []Endpoint{{Host: cfg.Addr, Port: cfg.Port}}
+	}
+	b := &endpointBalancer{
+		endpoints: make([]*endpointState, len(endpoints)),
+		random:    cfg.LoadBalance == LoadBalanceRandom,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:       time.Now,
+	}
+	for i, endpoint := range endpoints {
+		host := endpoint.Host
+		if len(host) == 0 {
+			host = "127.0.0.1"
+		}
+		port := endpoint.Port
+		if port == 0 {
+			port = common.DefaultHttpPort
+		}
+		b.endpoints[i] = &endpointState{host: host, port: port}
+	}
+	return b
+}
+
+// candidates returns the endpoints in the order they should be tried,
+// healthy endpoints first, then endpoints in backoff ordered by their retry time.
+func (b *endpointBalancer) candidates() []*endpointState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	count := len(b.endpoints)
+	ordered := make([]*endpointState, 0, count)
+	if b.random {
+		for _, i := range b.rand.Perm(count) {
+			ordered = append(ordered, b.endpoints[i])
+		}
+	} else {
+		for i := 0; i < count; i++ {
+			ordered = append(ordered, b.endpoints[(b.next+i)%count])
+		}
+		b.next = (b.next + 1) % count
+	}
+	now := b.now()
+	var healthy, backoff []*endpointState
+	for _, endpoint := range ordered {
+		if endpoint.retryAt.After(now) {
+			backoff = append(backoff, endpoint)
+		} else {
+			healthy = append(healthy, endpoint)
+		}
+	}
+	sort.SliceStable(backoff, func(i, j int) bool {
+		return backoff[i].retryAt.Before(backoff[j].retryAt)
+	})
+	return append(healthy, backoff...)
+}
+
+func (b *endpointBalancer) markFailed(endpoint *endpointState) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	backoff := endpointMinBackoff << uint(endpoint.failures)
+	if backoff > endpointMaxBackoff || backoff <= 0 {
+		backoff = endpointMaxBackoff
+	} else {
+		endpoint.failures++
+	}
+	endpoint.retryAt = b.now().Add(backoff)
+}
+
+func (b *endpointBalancer) markSucceeded(endpoint *endpointState) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	endpoint.failures = 0
+	endpoint.retryAt = time.Time{}
+}
+
+// dial calls open with each candidate endpoint until one succeeds.
+func (b *endpointBalancer) dial(open func(host string, port int) error) error {
+	candidates := b.candidates()
+	connectErr := &ConnectError{}
+	for _, endpoint := range candidates {
+		err := open(endpoint.host, endpoint.port)
+		if err == nil {
+			b.markSucceeded(endpoint)
+			return nil
+		}
+		if isServerError(err) {
+			// the endpoint is reachable, other endpoints will reject the connection the same way
+			return err
+		}
+		b.markFailed(endpoint)
+		connectErr.Endpoints = append(connectErr.Endpoints, endpoint.String())
+		connectErr.Errors = append(connectErr.Errors, err)
+	}
+	if len(candidates) == 1 {
+		return connectErr.Errors[0]
+	}
+	return connectErr
+}
+
+// ConnectError is returned when none of the endpoints could be connected.
+type ConnectError struct {
+	Endpoints []string
+	Errors    []error
+}
+
+func (e *ConnectError) Error() string {
+	builder := &strings.Builder{}
+	builder.WriteString("connect to all endpoints failed: ")
+	for i := range e.Endpoints {
+		if i != 0 {
+			builder.WriteString(", ")
+		}
+		fmt.Fprintf(builder, "%s: %s", e.Endpoints[i], e.Errors[i])
+	}
+	return builder.String()
+}
+
+// Unwrap returns the error of the last attempted endpoint.
+func (e *ConnectError) Unwrap() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+func isServerError(err error) bool {
+	var badConnErr *BadConnError
+	if errors.As(err, &badConnErr) {
+		err = badConnErr.err
+	}
+	var taosErr *taosErrors.TaosError
+	var versionMismatchErr *tdversion.VersionMismatchError
+	var unknownVersionErr *tdversion.UnknownVersionError
+	return errors.As(err, &taosErr) || errors.As(err, &versionMismatchErr) || errors.As(err, &unknownVersionErr)
+}
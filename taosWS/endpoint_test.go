@@ -0,0 +1,84 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+func endpointNames(endpoints []*endpointState) []string {
+	names := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		names[i] = endpoint.String()
+	}
+	return names
+}
+
+func TestEndpointBalancerRoundRobin(t *testing.T) {
+	b := newEndpointBalancer(&Config{Endpoints: []Endpoint{{Host: "h1", Port: 1}, {Host: "h2", Port: 2}, {Host: "h3"}}})
+	assert.Equal(t, []string{"h1:1", "h2:2", "h3:6041"}, endpointNames(b.candidates()))
+	assert.Equal(t, []string{"h2:2", "h3:6041", "h1:1"}, endpointNames(b.candidates()))
+	assert.Equal(t, []string{"h3:6041", "h1:1", "h2:2"}, endpointNames(b.candidates()))
+	assert.Equal(t, []string{"h1:1", "h2:2", "h3:6041"}, endpointNames(b.candidates()))
+}
+
+func TestEndpointBalancerRandom(t *testing.T) {
+	b := newEndpointBalancer(&Config{
+		Endpoints:   []Endpoint{{Host: "h1", Port: 1}, {Host: "h2", Port: 2}, {Host: "h3", Port: 3}},
+		LoadBalance: LoadBalanceRandom,
+	})
+	for i := 0; i < 10; i++ {
+		assert.ElementsMatch(t, []string{"h1:1", "h2:2", "h3:3"}, endpointNames(b.candidates()))
+	}
+}
+
+func TestEndpointBalancerBackoff(t *testing.T) {
+	now := time.Now()
+	b := newEndpointBalancer(&Config{Endpoints: []Endpoint{{Host: "h1", Port: 1}, {Host: "h2", Port: 2}, {Host: "h3", Port: 3}}})
+	b.now = func() time.Time { return now }
+	b.markFailed(b.endpoints[0])
+	assert.Equal(t, now.Add(time.Second), b.endpoints[0].retryAt)
+	b.markFailed(b.endpoints[0])
+	assert.Equal(t, now.Add(2*time.Second), b.endpoints[0].retryAt)
+	b.markFailed(b.endpoints[1])
+	// failed endpoints are tried last, the one that recovers first before the other
+	assert.Equal(t, []string{"h3:3", "h2:2", "h1:1"}, endpointNames(b.candidates()))
+	now = now.Add(time.Second)
+	assert.Equal(t, []string{"h2:2", "h3:3", "h1:1"}, endpointNames(b.candidates()))
+	b.markSucceeded(b.endpoints[0])
+	assert.Equal(t, []string{"h3:3", "h1:1", "h2:2"}, endpointNames(b.candidates()))
+	for i := 0; i < 20; i++ {
+		b.markFailed(b.endpoints[2])
+	}
+	assert.Equal(t, now.Add(endpointMaxBackoff), b.endpoints[2].retryAt)
+}
+
+func TestConnectAllEndpointsFailed(t *testing.T) {
+	cfg, err := ParseDSN("root:taosdata@ws(127.0.0.1:1,127.0.0.1:2)/")
+	require.NoError(t, err)
+	cfg.ReadTimeout = common.DefaultMessageTimeout
+	cfg.WriteTimeout = common.DefaultWriteWait
+	c := &connector{cfg: cfg}
+	conn, err := c.Connect(context.Background())
+	assert.Nil(t, conn)
+	var connectErr *ConnectError
+	require.True(t, errors.As(err, &connectErr))
+	assert.ElementsMatch(t, []string{"127.0.0.1:1", "127.0.0.1:2"}, connectErr.Endpoints)
+	assert.Equal(t, 2, len(connectErr.Errors))
+	assert.Contains(t, err.Error(), "127.0.0.1:1")
+	assert.Contains(t, err.Error(), "127.0.0.1:2")
+}
+
+func TestIsServerError(t *testing.T) {
+	assert.True(t, isServerError(taosErrors.NewError(0x357, "auth failure")))
+	assert.True(t, isServerError(NewBadConnError(taosErrors.NewError(0x357, "auth failure"))))
+	assert.False(t, isServerError(NewBadConnError(errors.New("connection reset"))))
+	assert.False(t, isServerError(driver.ErrBadConn))
+}
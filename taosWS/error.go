@@ -2,6 +2,7 @@ package taosWS
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 )
 
@@ -28,3 +29,26 @@ func (e *BadConnError) Error() string {
 	}
 	return fmt.Sprintf("error %s: context: %s", e.err.Error(), e.ctx)
 }
+
+// ConnLostError is returned when the connection broke after a request that may modify data was sent.
+// The request may have been executed by the server, so it is neither replayed nor reported as driver.ErrBadConn.
+type ConnLostError struct {
+	err error
+}
+
+func (e *ConnLostError) Error() string {
+	return fmt.Sprintf("connection lost, the request may have been executed: %s", e.err.Error())
+}
+
+func (e *ConnLostError) Unwrap() error {
+	var badConnErr *BadConnError
+	if errors.As(e.err, &badConnErr) {
+		return badConnErr.err
+	}
+	// never unwrap to driver.ErrBadConn
+	return nil
+}
+
+func isBadConnError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
@@ -0,0 +1,73 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/gorilla/websocket"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	"github.com/taosdata/driver-go/v3/ws/client"
+)
+
+const TMQRawMessage uint64 = 3
+
+// RawWriter is implemented by the connections of the driver, it is used through sql.Conn.Raw:
+//
+//	err = conn.Raw(func(driverConn interface{}) error {
+//		return driverConn.(taosWS.RawWriter).WriteRaw(ctx, raw)
+//	})
+type RawWriter interface {
+	// WriteRaw writes the raw data of a consumed message, such as the payload of a ws/tmq RawMessage,
+	// to the database of the connection.
+	WriteRaw(ctx context.Context, raw *tmq.RawData) error
+}
+
+var _ RawWriter = (*taosConn)(nil)
+
+func (tc *taosConn) WriteRaw(ctx context.Context, raw *tmq.RawData) error {
+	if tc.isClosed() {
+		return driver.ErrBadConn
+	}
+	reqID, err := getReqID(ctx)
+	if err != nil {
+		return err
+	}
+	err = tc.ensureConnected()
+	if err != nil {
+		return err
+	}
+	sent, err := tc.sendWriteRaw(reqID, raw)
+	if err != nil && isBadConnError(err) {
+		if sent {
+			// the server may have written the data, replaying it could write twice
+			return &ConnLostError{err: err}
+		}
+		if !tc.cfg.AutoReconnect {
+			return err
+		}
+		err = tc.reconnect()
+		if err != nil {
+			return err
+		}
+		_, err = tc.sendWriteRaw(reqID, raw)
+		if err != nil && isBadConnError(err) {
+			return &ConnLostError{err: err}
+		}
+	}
+	return err
+}
+
+// sendWriteRaw reports whether the request has been written to the server.
+func (tc *taosConn) sendWriteRaw(reqID uint64, raw *tmq.RawData) (bool, error) {
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	WriteUint64(envelope.Msg, reqID) // req id
+	WriteUint64(envelope.Msg, 0)     // message id
+	WriteUint64(envelope.Msg, TMQRawMessage)
+	WriteUint32(envelope.Msg, uint32(len(raw.Data))) // raw data length
+	WriteUint16(envelope.Msg, raw.MetaType)
+	envelope.Msg.Write(raw.Data)
+	var resp WSWriteRawResp
+	sent, err := tc.requestTo(websocket.BinaryMessage, envelope, reqID, &resp)
+	return sent, handleResponseError(err, resp.Code, resp.Message)
+}
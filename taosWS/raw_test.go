@@ -0,0 +1,91 @@
+package taosWS
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+func TestWriteRaw(t *testing.T) {
+	received := make(chan []byte, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var resp map[string]interface{}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				resp = map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"}
+			} else {
+				received <- msg
+				resp = map[string]interface{}{"code": 0, "action": "write_raw", "req_id": binary.LittleEndian.Uint64(msg)}
+				if len(msg) == 30 {
+					resp["code"] = 0x2603
+					resp["message"] = "Table does not exist"
+				}
+			}
+			bs, _ := json.Marshal(resp)
+			if err = ws.WriteMessage(websocket.TextMessage, bs); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	cfg := &Config{Net: "ws", Addr: host, Port: portNum, ReadTimeout: time.Second * 5, WriteTimeout: time.Second * 5}
+	tc, err := newTaosConn(cfg)
+	require.NoError(t, err)
+	defer func() {
+		_ = tc.Close()
+	}()
+
+	var writer RawWriter = tc
+	err = writer.WriteRaw(context.Background(), &tmq.RawData{MetaType: 2, Data: []byte{1, 2, 3}})
+	require.NoError(t, err)
+	msg := <-received
+	require.Equal(t, 33, len(msg))
+	assert.Equal(t, uint64(0), binary.LittleEndian.Uint64(msg[8:]))
+	assert.Equal(t, TMQRawMessage, binary.LittleEndian.Uint64(msg[16:]))
+	assert.Equal(t, uint32(3), binary.LittleEndian.Uint32(msg[24:]))
+	assert.Equal(t, uint16(2), binary.LittleEndian.Uint16(msg[28:]))
+	assert.Equal(t, []byte{1, 2, 3}, msg[30:])
+
+	err = writer.WriteRaw(context.Background(), &tmq.RawData{MetaType: 2})
+	<-received
+	require.Error(t, err)
+	taosErr, ok := err.(*taosErrors.TaosError)
+	require.True(t, ok)
+	assert.Equal(t, int32(0x2603), taosErr.Code)
+}
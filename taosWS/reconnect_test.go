@@ -0,0 +1,68 @@
+package taosWS
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common/log"
+)
+
+func TestConnLostError(t *testing.T) {
+	err := &ConnLostError{err: NewBadConnError(io.EOF)}
+	assert.False(t, errors.Is(err, driver.ErrBadConn))
+	assert.True(t, errors.Is(err, io.EOF))
+	assert.Equal(t, "connection lost, the request may have been executed: EOF", err.Error())
+	err = &ConnLostError{err: driver.ErrBadConn}
+	assert.False(t, errors.Is(err, driver.ErrBadConn))
+}
+
+func TestResetSession(t *testing.T) {
+	s := &session{logger: log.Nop, sendChanList: list.New(), errCh: make(chan struct{})}
+	tc := &taosConn{cfg: &Config{}, logger: log.Nop, session: s}
+	assert.True(t, tc.IsValid())
+	assert.NoError(t, tc.ResetSession(context.Background()))
+
+	replaced := &session{logger: log.Nop, sendChanList: list.New(), errCh: make(chan struct{})}
+	replaced.fail(NewBadConnError(io.EOF))
+	assert.True(t, tc.IsValid(), "error of a replaced session must be ignored")
+
+	s.fail(NewBadConnError(io.EOF))
+	assert.False(t, tc.IsValid())
+	assert.Equal(t, driver.ErrBadConn, tc.ResetSession(context.Background()))
+	err := tc.ensureConnected()
+	assert.True(t, errors.Is(err, driver.ErrBadConn))
+
+	tc.cfg.AutoReconnect = true
+	assert.True(t, tc.IsValid())
+	assert.NoError(t, tc.ResetSession(context.Background()))
+
+	assert.NoError(t, tc.Close())
+	assert.False(t, tc.IsValid())
+	assert.Equal(t, driver.ErrBadConn, tc.ensureConnected())
+}
+
+func TestReconnectFailed(t *testing.T) {
+	cfg := &Config{Net: "ws", Addr: "127.0.0.1", Port: 1, ReconnectRetryCount: 2, ReconnectIntervalMs: 1}
+	tc := &taosConn{cfg: cfg, logger: log.Nop, balancer: newEndpointBalancer(cfg)}
+	err := tc.reconnect()
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, driver.ErrBadConn))
+	assert.Equal(t, uint64(0), tc.generation)
+}
+
+func TestFreeLateResultAfterReconnect(t *testing.T) {
+	replaced := &session{logger: log.Nop, sendChanList: list.New(), errCh: make(chan struct{})}
+	// writing on the current session panics, it has no client
+	current := &session{logger: log.Nop, sendChanList: list.New(), errCh: make(chan struct{})}
+	tc := &taosConn{cfg: &Config{}, logger: log.Nop, session: current}
+	msg := &message{mt: websocket.TextMessage, message: []byte(`{"code":0,"action":"binary_query","req_id":1,"id":1}`)}
+	assert.NotPanics(t, func() {
+		tc.freeLateResult(replaced, msg)
+	}, "result of a replaced session must not be freed on the current session")
+}
@@ -1,10 +1,9 @@
 package taosWS
 
 import (
-	"bytes"
+	"context"
 	"database/sql/driver"
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
@@ -12,12 +11,14 @@ import (
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
 type rows struct {
-	buf              *bytes.Buffer
 	blockPtr         unsafe.Pointer
 	blockOffset      int
 	blockSize        int
@@ -33,6 +34,10 @@ type rows struct {
 	precision        int
 	isStmt           bool
 	timezone         *time.Location
+	generation       uint64
+	// ctx of the query, fetching is canceled when it is done
+	ctx   context.Context
+	freed bool
 }
 
 func newRows(
@@ -49,7 +54,6 @@ func newRows(
 	timezone *time.Location,
 ) *rows {
 	return &rows{
-		buf:              &bytes.Buffer{},
 		resultID:         resultID,
 		conn:             conn,
 		fieldsCount:      fieldsCount,
@@ -61,6 +65,8 @@ func newRows(
 		precision:        precision,
 		isStmt:           isStmt,
 		timezone:         timezone,
+		generation:       conn.getGeneration(),
+		ctx:              context.Background(),
 	}
 }
 func (rs *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
@@ -137,75 +143,79 @@ func (rs *rows) FormatTime(ts int64, precision int) driver.Value {
 }
 
 func (rs *rows) taosFetchBlock() error {
-	reqID := uint64(common.GetReqID())
-	rs.buf.Reset()
-	WriteUint64(rs.buf, reqID)       // req id
-	WriteUint64(rs.buf, rs.resultID) // message id
-	WriteUint64(rs.buf, FetchRawBlockMessage)
-	WriteUint16(rs.buf, 1) // version
-	err := rs.conn.writeBinary(rs.buf.Bytes())
-	if err != nil {
-		return err
+	if rs.generation != rs.conn.getGeneration() {
+		return ResultLostError
+	}
+	span, reqID := startSpan(rs.ctx, trace.SpanFetch)
+	bytes, err := rs.fetchBlock(reqID)
+	if err == nil {
+		span.SetAttributes(trace.Rows(rs.blockSize), trace.Bytes(bytes))
+		metrics.GetMetrics().AddFetched(rs.blockSize, bytes)
 	}
-	respBytes, err := rs.conn.readBytes()
+	trace.End(span, err)
+	return err
+}
+
+// fetchBlock fetches the next raw block and returns the length of the response.
+func (rs *rows) fetchBlock(reqID uint64) (int, error) {
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	WriteUint64(envelope.Msg, reqID)       // req id
+	WriteUint64(envelope.Msg, rs.resultID) // message id
+	WriteUint64(envelope.Msg, FetchRawBlockMessage)
+	WriteUint16(envelope.Msg, 1) // version
+	respBytes, err := rs.conn.requestBytes(rs.ctx, envelope, reqID)
 	if err != nil {
-		return err
+		if rs.ctx.Err() != nil {
+			// stops the query on the server, the late fetch response is dropped
+			_ = rs.freeResult()
+		}
+		return 0, err
 	}
 	if len(respBytes) < 51 {
-		return taosErrors.NewError(0xffff, "invalid fetch raw block response")
+		return 0, taosErrors.NewError(0xffff, "invalid fetch raw block response")
 	}
 	version := binary.LittleEndian.Uint16(respBytes[16:])
 	if version != 1 {
-		return taosErrors.NewError(0xffff, fmt.Sprintf("unsupported fetch raw block version: %d", version))
+		return 0, taosErrors.NewError(0xffff, fmt.Sprintf("unsupported fetch raw block version: %d", version))
 	}
 	code := binary.LittleEndian.Uint32(respBytes[34:])
 	msgLen := int(binary.LittleEndian.Uint32(respBytes[38:]))
 	if len(respBytes) < 51+msgLen {
-		return taosErrors.NewError(0xffff, "invalid fetch raw block response")
+		return 0, taosErrors.NewError(0xffff, "invalid fetch raw block response")
 	}
 	errMsg := string(respBytes[42 : 42+msgLen])
 	if code != 0 {
-		return taosErrors.NewError(int(code), errMsg)
+		return 0, taosErrors.NewError(int(code), errMsg)
 	}
 	completed := respBytes[50+msgLen] == 1
 	if completed {
 		rs.blockSize = 0
-		return nil
+		return len(respBytes), nil
 	}
 	if len(respBytes) < 55+msgLen {
-		return taosErrors.NewError(0xffff, "invalid fetch raw block response")
+		return 0, taosErrors.NewError(0xffff, "invalid fetch raw block response")
 	}
 	blockLength := binary.LittleEndian.Uint32(respBytes[51+msgLen:])
 	if len(respBytes) < 55+msgLen+int(blockLength) {
-		return taosErrors.NewError(0xffff, "invalid fetch raw block response")
+		return 0, taosErrors.NewError(0xffff, "invalid fetch raw block response")
 	}
 	rawBlock := respBytes[55+msgLen : 55+msgLen+int(blockLength)]
 	rs.block = rawBlock
 	rs.blockPtr = unsafe.Pointer(&rs.block[0])
 	rs.blockSize = int(parser.RawBlockGetNumOfRows(rs.blockPtr))
 	rs.blockOffset = 0
-	return nil
+	return len(respBytes), nil
 }
 
 func (rs *rows) freeResult() error {
-	tc := rs.conn
-	reqID := uint64(common.GetReqID())
-	req := &WSFreeResultReq{
-		ReqID: reqID,
-		ID:    rs.resultID,
-	}
-	args, err := json.Marshal(req)
-	if err != nil {
-		return err
-	}
-	action := &WSAction{
-		Action: WSFreeResult,
-		Args:   args,
+	if rs.freed {
+		return nil
 	}
-	rs.buf.Reset()
-	err = jsonI.NewEncoder(rs.buf).Encode(action)
-	if err != nil {
-		return err
+	rs.freed = true
+	if rs.generation != rs.conn.getGeneration() {
+		// released by the server with the broken session
+		return nil
 	}
-	return tc.writeText(rs.buf.Bytes())
+	return rs.conn.freeResult(rs.resultID)
 }
@@ -0,0 +1,222 @@
+package taosWS
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/ws/client"
+)
+
+// sendChanLength is the number of messages queued for the write pump of a session
+const sendChanLength = 10
+
+// session is a websocket session of a connection. Responses are matched with their requests by req_id,
+// so the requests of many queries can be in flight at once. A broken session is replaced by reconnect.
+type session struct {
+	client       *client.Client
+	logger       log.Logger
+	lock         sync.Mutex
+	sendChanList *list.List
+	err          error
+	// errCh is closed when err is set
+	errCh chan struct{}
+}
+
+type indexedChan struct {
+	index   uint64
+	channel chan *message
+}
+
+type message struct {
+	mt      int
+	message []byte
+}
+
+func newSession(ws *websocket.Conn, writeTimeout time.Duration, logger log.Logger) *session {
+	s := &session{
+		logger:       logger,
+		sendChanList: list.New(),
+		errCh:        make(chan struct{}),
+	}
+	wsClient := client.NewClient(ws, sendChanLength)
+	wsClient.Logger = logger
+	if writeTimeout > 0 {
+		wsClient.WriteWait = writeTimeout
+	}
+	wsClient.TextMessageHandler = s.handleTextMessage
+	wsClient.BinaryMessageHandler = s.handleBinaryMessage
+	wsClient.ErrorHandler = func(err error) {
+		s.fail(NewBadConnError(err))
+	}
+	s.client = wsClient
+	go wsClient.WritePump()
+	go func() {
+		wsClient.ReadPump()
+		// ReadPump returns without calling the error handler on abnormal closure
+		s.fail(NewBadConnError(client.ClosedError))
+		wsClient.Close()
+	}()
+	return s
+}
+
+func (s *session) handleTextMessage(msg []byte) {
+	iter := client.JsonI.BorrowIterator(msg)
+	var reqID uint64
+	iter.ReadObjectCB(func(iter *jsoniter.Iterator, field string) bool {
+		switch field {
+		case "req_id":
+			reqID = iter.ReadUint64()
+			return false
+		default:
+			iter.Skip()
+		}
+		return iter.Error == nil
+	})
+	client.JsonI.ReturnIterator(iter)
+	s.deliver(reqID, &message{mt: websocket.TextMessage, message: msg})
+}
+
+// handleBinaryMessage handles binary responses, the req_id follows the timing
+func (s *session) handleBinaryMessage(msg []byte) {
+	if len(msg) < 16 {
+		s.logger.Warn("drop binary response", "reason", "message too short", "length", len(msg))
+		return
+	}
+	reqID := binary.LittleEndian.Uint64(msg[8:16])
+	s.deliver(reqID, &message{mt: websocket.BinaryMessage, message: msg})
+}
+
+// deliver passes a response to the waiting request, a response without request, such as the late
+// response of a timed out request, is dropped.
+func (s *session) deliver(reqID uint64, msg *message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for item := s.sendChanList.Front(); item != nil; item = item.Next() {
+		channel := item.Value.(*indexedChan)
+		if channel.index == reqID {
+			channel.channel <- msg
+			s.sendChanList.Remove(item)
+			return
+		}
+	}
+	s.logger.Debug("drop response", "req_id", reqID, "message_type", msg.mt)
+}
+
+func (s *session) addMessageOutChan(outChan *indexedChan) *list.Element {
+	s.lock.Lock()
+	element := s.sendChanList.PushBack(outChan)
+	s.lock.Unlock()
+	return element
+}
+
+func (s *session) removeMessageOutChan(element *list.Element) {
+	s.lock.Lock()
+	s.sendChanList.Remove(element)
+	s.lock.Unlock()
+}
+
+// request writes a message and waits for the response with reqID, it reports whether the message has been written.
+// A timed out request does not break the session. When ctx is done before the response, request returns ctx.Err().
+// If the request times out or ctx is done, discard, if not nil, is called with the session and the late response so
+// that its server side resources can be released on the session that owns them.
+func (s *session) request(ctx context.Context, envelope *client.Envelope, reqID uint64, timeout time.Duration, discard func(*session, *message)) (*message, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	channel := &indexedChan{
+		index:   reqID,
+		channel: make(chan *message, 1),
+	}
+	element := s.addMessageOutChan(channel)
+	err := s.write(envelope)
+	if err != nil {
+		s.removeMessageOutChan(element)
+		return nil, false, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case msg := <-channel.channel:
+		return msg, true, nil
+	case <-s.errCh:
+		s.removeMessageOutChan(element)
+		return nil, true, s.getError()
+	case <-timer.C:
+		s.abandon(channel, element, discard)
+		return nil, true, ReadTimeoutError
+	case <-ctx.Done():
+		s.abandon(channel, element, discard)
+		return nil, true, ctx.Err()
+	}
+}
+
+// abandon stops waiting for the response of a request, the late response is passed to discard if it is not nil.
+func (s *session) abandon(channel *indexedChan, element *list.Element, discard func(*session, *message)) {
+	if discard == nil {
+		s.removeMessageOutChan(element)
+		return
+	}
+	go s.awaitLateResponse(channel, element, discard)
+}
+
+// awaitLateResponse waits for the response of an abandoned request until the session breaks, a long-running
+// query keeps its goroutine until the server responds. The resources of a broken session are released by the server.
+func (s *session) awaitLateResponse(channel *indexedChan, element *list.Element, discard func(*session, *message)) {
+	select {
+	case msg := <-channel.channel:
+		discard(s, msg)
+	case <-s.errCh:
+		s.removeMessageOutChan(element)
+	}
+}
+
+// write writes a message without waiting for a response, a write error breaks the session.
+func (s *session) write(envelope *client.Envelope) error {
+	err := s.getError()
+	if err != nil {
+		return err
+	}
+	err = s.client.Send(envelope)
+	if err == nil {
+		err = <-envelope.ErrorChan
+	}
+	if err != nil {
+		badConnErr := NewBadConnErrorWithCtx(err, envelope.Msg.String())
+		s.fail(badConnErr)
+		return badConnErr
+	}
+	return nil
+}
+
+func (s *session) getError() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+// fail breaks the session with err, the requests waiting for a response return err.
+func (s *session) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err == nil {
+		s.err = err
+		close(s.errCh)
+		if err != driver.ErrBadConn {
+			s.logger.Warn("websocket session broken", "error", err)
+		}
+	}
+}
+
+func (s *session) close() {
+	s.fail(driver.ErrBadConn)
+	if s.client != nil {
+		s.client.Close()
+	}
+}
@@ -0,0 +1,568 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/common/trace"
+	"github.com/taosdata/driver-go/v3/testutil/mockadapter"
+)
+
+func TestConcurrentRequests(t *testing.T) {
+	slowReceived := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		writeResp := func(resp map[string]interface{}) error {
+			bs, _ := json.Marshal(resp)
+			return ws.WriteMessage(websocket.TextMessage, bs)
+		}
+		var slowReqID uint64
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				err = writeResp(map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"})
+				if err != nil {
+					return
+				}
+				continue
+			}
+			reqID := binary.LittleEndian.Uint64(msg)
+			switch string(msg[30:]) {
+			case "insert slow":
+				slowReqID = reqID
+				close(slowReceived)
+			case "insert fast":
+				// the response of the slow request follows the response of the fast one
+				for i, id := range []uint64{reqID, slowReqID} {
+					err = writeResp(map[string]interface{}{"code": 0, "action": "binary_query", "req_id": id, "is_update": true, "affected_rows": 2 - i})
+					if err != nil {
+						return
+					}
+				}
+			}
+			// "insert lost" is never answered
+		}
+	}))
+	defer server.Close()
+	tc, err := newTaosConn(testServerConfig(t, server, time.Millisecond*500))
+	require.NoError(t, err)
+	defer func() {
+		_ = tc.Close()
+	}()
+
+	slow := make(chan int64, 1)
+	go func() {
+		result, err := tc.ExecContext(context.Background(), "insert slow", nil)
+		assert.NoError(t, err)
+		if err != nil {
+			slow <- 0
+			return
+		}
+		affected, _ := result.RowsAffected()
+		slow <- affected
+	}()
+	<-slowReceived
+	result, err := tc.ExecContext(context.Background(), "insert fast", nil)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	assert.Equal(t, int64(1), <-slow)
+
+	// a timed out request does not break the session
+	_, err = tc.ExecContext(context.Background(), "insert lost", nil)
+	assert.Equal(t, ReadTimeoutError, err)
+	assert.True(t, tc.IsValid())
+	result, err = tc.ExecContext(context.Background(), "insert fast", nil)
+	require.NoError(t, err)
+	affected, err = result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+}
+
+func testServerConfig(t *testing.T, server *httptest.Server, readTimeout time.Duration) *Config {
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return &Config{Net: "ws", Addr: host, Port: portNum, ReadTimeout: readTimeout, WriteTimeout: time.Second * 5}
+}
+
+func TestCancelQuery(t *testing.T) {
+	freed := make(chan uint64, 2)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		writeResp := func(resp map[string]interface{}) error {
+			bs, _ := json.Marshal(resp)
+			return ws.WriteMessage(websocket.TextMessage, bs)
+		}
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+						ID    uint64 `json:"id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				if action.Action == WSFreeResult {
+					freed <- action.Args.ID
+					continue
+				}
+				err = writeResp(map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"})
+				if err != nil {
+					return
+				}
+				continue
+			}
+			reqID := binary.LittleEndian.Uint64(msg)
+			if binary.LittleEndian.Uint64(msg[16:]) == FetchRawBlockMessage {
+				// fetch is never answered
+				continue
+			}
+			resp := map[string]interface{}{"code": 0, "action": "binary_query", "req_id": reqID, "fields_count": 1, "fields_names": []string{"v"}, "fields_types": []uint8{4}, "fields_lengths": []int64{4}}
+			switch string(msg[30:]) {
+			case "select slow":
+				time.Sleep(time.Millisecond * 300)
+				resp["id"] = 1
+			case "select fetch":
+				resp["id"] = 2
+			case "select timeout":
+				time.Sleep(time.Millisecond * 300)
+				resp["id"] = 3
+			}
+			if err = writeResp(resp); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	tc, err := newTaosConn(testServerConfig(t, server, time.Second*5))
+	require.NoError(t, err)
+	defer func() {
+		_ = tc.Close()
+	}()
+
+	// canceled while the query is executed, the result is freed when the response arrives
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	start := time.Now()
+	_, err = tc.QueryContext(ctx, "select slow", nil)
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Millisecond*300)
+	select {
+	case id := <-freed:
+		assert.Equal(t, uint64(1), id)
+	case <-time.After(time.Second * 5):
+		t.Fatal("result of the canceled query is not freed")
+	}
+
+	// canceled while fetching
+	ctx, cancel = context.WithCancel(context.Background())
+	rs, err := tc.QueryContext(ctx, "select fetch", nil)
+	require.NoError(t, err)
+	time.AfterFunc(time.Millisecond*100, cancel)
+	err = rs.Next(make([]driver.Value, 1))
+	assert.Equal(t, context.Canceled, err)
+	select {
+	case id := <-freed:
+		assert.Equal(t, uint64(2), id)
+	case <-time.After(time.Second * 5):
+		t.Fatal("result of the canceled fetch is not freed")
+	}
+	assert.NoError(t, rs.Close())
+	assert.Equal(t, 0, len(freed), "result must be freed once")
+	assert.True(t, tc.IsValid())
+
+	// timed out, the result is freed when the response arrives
+	timeoutConn, err := newTaosConn(testServerConfig(t, server, time.Millisecond*100))
+	require.NoError(t, err)
+	defer func() {
+		_ = timeoutConn.Close()
+	}()
+	_, err = timeoutConn.QueryContext(context.Background(), "select timeout", nil)
+	assert.Equal(t, ReadTimeoutError, err)
+	select {
+	case id := <-freed:
+		assert.Equal(t, uint64(3), id)
+	case <-time.After(time.Second * 5):
+		t.Fatal("result of the timed out query is not freed")
+	}
+}
+
+type recordLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *recordLogger) record(msg string) {
+	l.lock.Lock()
+	l.messages = append(l.messages, msg)
+	l.lock.Unlock()
+}
+
+func (l *recordLogger) Debug(msg string, _ ...interface{}) { l.record(msg) }
+func (l *recordLogger) Info(msg string, _ ...interface{})  { l.record(msg) }
+func (l *recordLogger) Warn(msg string, _ ...interface{})  { l.record(msg) }
+func (l *recordLogger) Error(msg string, _ ...interface{}) { l.record(msg) }
+
+func (l *recordLogger) getMessages() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+func TestLogger(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		writeResp := func(resp map[string]interface{}) error {
+			bs, _ := json.Marshal(resp)
+			return ws.WriteMessage(websocket.TextMessage, bs)
+		}
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				err = writeResp(map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"})
+				if err != nil {
+					return
+				}
+				continue
+			}
+			if string(msg[30:]) == "insert break" {
+				return
+			}
+			reqID := binary.LittleEndian.Uint64(msg)
+			err = writeResp(map[string]interface{}{"code": 0, "action": "binary_query", "req_id": reqID, "is_update": true, "affected_rows": 1})
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	log.SetSlowThreshold(time.Nanosecond)
+	defer log.SetSlowThreshold(0)
+	logger := &recordLogger{}
+	cfg := testServerConfig(t, server, time.Second*5)
+	cfg.AutoReconnect = true
+	cfg.ReconnectRetryCount = 1
+	cfg.Logger = logger
+	tc, err := newTaosConn(cfg)
+	require.NoError(t, err)
+	_, err = tc.ExecContext(context.Background(), "insert ok", nil)
+	require.NoError(t, err)
+	_, err = tc.ExecContext(context.Background(), "insert break", nil)
+	assert.IsType(t, &ConnLostError{}, err)
+	_, err = tc.ExecContext(context.Background(), "insert ok", nil)
+	require.NoError(t, err)
+	require.NoError(t, tc.Close())
+
+	messages := logger.getMessages()
+	for _, msg := range []string{"connection opened", "query", "slow request", "websocket session broken", "reconnecting", "reconnected", "connection closed"} {
+		assert.Contains(t, messages, msg)
+	}
+}
+
+type spanKey struct{}
+
+type recordSpan struct {
+	name   string
+	parent [8]byte
+	attrs  map[string]interface{}
+	err    error
+}
+
+func (s *recordSpan) SetAttributes(attrs ...trace.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) End(err error) {
+	s.err = err
+}
+
+type recordTracer struct {
+	lock  sync.Mutex
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.parent, _ = ctx.Value(spanKey{}).([8]byte)
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, [8]byte{0, 0, 0, 0, 0, 0, 1, byte(len(t.spans))}), span
+}
+
+func (t *recordTracer) SpanID(ctx context.Context) ([8]byte, bool) {
+	spanID, ok := ctx.Value(spanKey{}).([8]byte)
+	return spanID, ok
+}
+
+func TestTracer(t *testing.T) {
+	reqIDs := make(chan uint64, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		writeResp := func(resp map[string]interface{}) error {
+			bs, _ := json.Marshal(resp)
+			return ws.WriteMessage(websocket.TextMessage, bs)
+		}
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				err = writeResp(map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"})
+				if err != nil {
+					return
+				}
+				continue
+			}
+			reqID := binary.LittleEndian.Uint64(msg)
+			reqIDs <- reqID
+			resp := map[string]interface{}{"code": 0, "action": "binary_query", "req_id": reqID, "is_update": true, "affected_rows": 3}
+			if string(msg[30:]) == "insert into t1 values(now, 'secret')" {
+				resp = map[string]interface{}{"code": 0x2603, "message": "Table does not exist", "action": "binary_query", "req_id": reqID}
+			}
+			if err = writeResp(resp); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	tracer := &recordTracer{}
+	trace.SetTracer(tracer, trace.RedactLiterals)
+	defer trace.SetTracer(nil, nil)
+	tc, err := newTaosConn(testServerConfig(t, server, time.Second*5))
+	require.NoError(t, err)
+	defer func() {
+		_ = tc.Close()
+	}()
+
+	result, err := tc.ExecContext(context.Background(), "insert into t0 values(now, 1)", nil)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+	// the req_id is derived from the span
+	assert.Equal(t, uint64(0x0101), <-reqIDs)
+	_, err = tc.ExecContext(context.Background(), "insert into t1 values(now, 'secret')", nil)
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0x0102), <-reqIDs)
+
+	require.Equal(t, 2, len(tracer.spans))
+	assert.Equal(t, trace.SpanQuery, tracer.spans[0].name)
+	assert.Equal(t, map[string]interface{}{
+		trace.AttrSQL:   "insert into t0 values(now, ?)",
+		trace.AttrReqID: int64(0x0101),
+		trace.AttrRows:  3,
+	}, tracer.spans[0].attrs)
+	assert.NoError(t, tracer.spans[0].err)
+	assert.Equal(t, map[string]interface{}{
+		trace.AttrSQL:       "insert into t1 values(now, ?)",
+		trace.AttrReqID:     int64(0x0102),
+		trace.AttrErrorCode: int32(0x2603),
+	}, tracer.spans[1].attrs)
+	assert.Error(t, tracer.spans[1].err)
+}
+
+func TestStmtTracer(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	s.Script(`^insert into \? values`, &mockadapter.Result{
+		AffectedRows: 1,
+		Fields: []*stmtCommon.Stmt2AllField{
+			{Name: "tbname", FieldType: common.TSDB_DATA_TYPE_BINARY, BindType: stmtCommon.TAOS_FIELD_TBNAME},
+			{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+			{Name: "v", FieldType: common.TSDB_DATA_TYPE_INT, Bytes: 4, BindType: stmtCommon.TAOS_FIELD_COL},
+		},
+	})
+	conn, err := TDengineDriver{}.Open("root:taosdata@ws(" + s.Addr() + ")/")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+	stmt, err := conn.Prepare("insert into ? values(?, ?)")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, stmt.Close())
+	}()
+	tracer := &recordTracer{}
+	trace.SetTracer(tracer, nil)
+	defer trace.SetTracer(nil, nil)
+	ctx, span := trace.Start(context.Background(), "caller")
+	result, err := stmt.(driver.StmtExecContext).ExecContext(ctx, []driver.NamedValue{
+		{Ordinal: 1, Value: "t"},
+		{Ordinal: 2, Value: time.Now()},
+		{Ordinal: 3, Value: int32(1)},
+	})
+	span.End(err)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	require.Equal(t, 3, len(tracer.spans))
+	callerID := [8]byte{0, 0, 0, 0, 0, 0, 1, 1}
+	for i, name := range []string{trace.SpanStmtBind, trace.SpanStmtExec} {
+		assert.Equal(t, name, tracer.spans[i+1].name)
+		assert.Equal(t, callerID, tracer.spans[i+1].parent)
+	}
+	// the req_id of the bind is derived from its span
+	binds := s.Requests(mockadapter.ActionStmt2Bind)
+	require.Equal(t, 1, len(binds))
+	assert.Equal(t, tracer.spans[1].attrs[trace.AttrReqID], int64(binds[0].ReqID))
+}
+
+func TestMetrics(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = ws.Close()
+		}()
+		writeResp := func(resp map[string]interface{}) error {
+			bs, _ := json.Marshal(resp)
+			return ws.WriteMessage(websocket.TextMessage, bs)
+		}
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if mt == websocket.TextMessage {
+				var action struct {
+					Action string `json:"action"`
+					Args   struct {
+						ReqID uint64 `json:"req_id"`
+					} `json:"args"`
+				}
+				_ = json.Unmarshal(msg, &action)
+				err = writeResp(map[string]interface{}{"code": 0, "action": action.Action, "req_id": action.Args.ReqID, "version": "3.3.6.0"})
+				if err != nil {
+					return
+				}
+				continue
+			}
+			reqID := binary.LittleEndian.Uint64(msg)
+			resp := map[string]interface{}{"code": 0, "action": "binary_query", "req_id": reqID, "is_update": true, "affected_rows": 1}
+			if string(msg[30:]) == "insert into t1 values(now, 1)" {
+				resp = map[string]interface{}{"code": 0x2603, "message": "Table does not exist", "action": "binary_query", "req_id": reqID}
+			}
+			if err = writeResp(resp); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	m := metrics.NewMemory()
+	metrics.SetMetrics(m)
+	defer metrics.SetMetrics(nil)
+	tc, err := newTaosConn(testServerConfig(t, server, time.Second*5))
+	require.NoError(t, err)
+	defer func() {
+		_ = tc.Close()
+	}()
+
+	_, err = tc.ExecContext(context.Background(), "insert into t0 values(now, 1)", nil)
+	require.NoError(t, err)
+	_, err = tc.ExecContext(context.Background(), "insert into t1 values(now, 1)", nil)
+	assert.Error(t, err)
+
+	snapshot := m.Snapshot()
+	query := snapshot.Requests[metrics.KindQuery]
+	assert.Equal(t, uint64(2), query.Count)
+	assert.Equal(t, uint64(1), query.Errors)
+	var count uint64
+	for _, c := range query.Latency.Counts {
+		count += c
+	}
+	assert.Equal(t, uint64(2), count)
+	assert.Equal(t, map[int32]uint64{0x2603: 1}, snapshot.ErrorsByCode)
+	// at least the queries are sent through the send queue
+	assert.GreaterOrEqual(t, snapshot.SentMessages, uint64(2))
+	assert.Equal(t, int64(0), snapshot.SendQueue)
+}
@@ -2,11 +2,13 @@ package taosWS
 
 import (
 	"bytes"
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
@@ -25,77 +27,257 @@ type Stmt struct {
 	cols          []*stmtCommon.StmtField
 	colTypes      *param.ColumnType
 	queryColTypes []*types.ColumnType
+	isStmt2       bool
+	stmt2Fields   []*stmtCommon.Stmt2AllField
+	// generation of the connection session the statement was prepared on
+	generation uint64
+}
+
+// prepare creates the statement on the server.
+func (stmt *Stmt) prepare(reqID uint64) error {
+	tc := stmt.conn
+	if stmt.isStmt2 {
+		stmtID, err := tc.stmt2Init(reqID)
+		if err != nil && isServerError(err) {
+			// servers without stmt2 reject the action, fall back to stmt1 for this connection
+			tc.logger.Warn("stmt2 is not supported, using stmt1", "error", err)
+			atomic.StoreUint32(&tc.stmt2Unsupported, 1)
+			stmt.isStmt2 = false
+			return stmt.prepare(reqID)
+		}
+		if err != nil {
+			return err
+		}
+		isInsert, fields, err := tc.stmt2Prepare(stmtID, stmt.pSql)
+		if err != nil {
+			_ = tc.stmt2Close(stmtID)
+			return err
+		}
+		stmt.stmtID = stmtID
+		stmt.isInsert = isInsert
+		if isInsert {
+			stmt.setStmt2Fields(fields)
+		}
+	} else {
+		stmtID, err := tc.stmtInit(reqID)
+		if err != nil {
+			return err
+		}
+		isInsert, err := tc.stmtPrepare(stmtID, stmt.pSql)
+		if err != nil {
+			_ = tc.stmtClose(stmtID)
+			return err
+		}
+		stmt.stmtID = stmtID
+		stmt.isInsert = isInsert
+	}
+	stmt.generation = tc.getGeneration()
+	return nil
+}
+
+// ensurePrepared reconnects a broken connection and prepares the statement again
+// if the session it was prepared on has been replaced.
+func (stmt *Stmt) ensurePrepared() error {
+	err := stmt.conn.ensureConnected()
+	if err != nil {
+		return err
+	}
+	if stmt.generation == stmt.conn.getGeneration() {
+		return nil
+	}
+	return stmt.prepare(uint64(common.GetReqID()))
+}
+
+// do runs a request of the statement, read only statements are replayed once if the connection broke.
+func (stmt *Stmt) do(request func() error) error {
+	err := stmt.ensurePrepared()
+	if err != nil {
+		return err
+	}
+	err = request()
+	if err == nil || !isBadConnError(err) {
+		return err
+	}
+	if stmt.isInsert || !common.IsReadOnlySQL(stmt.pSql) {
+		// the server may have executed the request, replaying it could write twice
+		return &ConnLostError{err: err}
+	}
+	if !stmt.conn.cfg.AutoReconnect {
+		return err
+	}
+	err = stmt.ensurePrepared()
+	if err != nil {
+		return err
+	}
+	return request()
 }
 
 func (stmt *Stmt) Close() error {
-	if stmt.conn == nil || stmt.conn.isClosed() || stmt.conn.messageError != nil {
+	if stmt.conn == nil || stmt.conn.isClosed() {
 		return driver.ErrBadConn
 	}
-	err := stmt.conn.stmtClose(stmt.stmtID)
+	if stmt.conn.tx != nil && stmt.conn.tx.hasRows(stmt) {
+		// closed after the pending rows are flushed, the commit prepares it again if the session was replaced
+		stmt.conn.tx.closeAfterFlush(stmt)
+		return nil
+	}
+	if stmt.generation != stmt.conn.getGeneration() {
+		// released by the server with the broken session
+		stmt.buffer.Reset()
+		stmt.conn = nil
+		return nil
+	}
+	if stmt.conn.getMessageError() != nil {
+		return driver.ErrBadConn
+	}
+	err := stmt.closeOnServer()
 	stmt.buffer.Reset()
 	stmt.conn = nil
 	return err
 }
 
+func (stmt *Stmt) closeOnServer() error {
+	if stmt.generation != stmt.conn.getGeneration() {
+		return nil
+	}
+	if stmt.isStmt2 {
+		return stmt.conn.stmt2Close(stmt.stmtID)
+	}
+	return stmt.conn.stmtClose(stmt.stmtID)
+}
+
 func (stmt *Stmt) NumInput() int {
-	if stmt.colTypes != nil {
+	if stmt.colTypes != nil || stmt.stmt2Fields != nil {
 		return len(stmt.cols)
 	}
 	return -1
 }
 
 func (stmt *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.exec(context.Background(), args)
+}
+
+// ExecContext implements driver.StmtExecContext, the spans of the bind and exec requests are children of the span
+// of ctx.
+func (stmt *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values, err := namedValueToValue(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.exec(ctx, values)
+}
+
+func (stmt *Stmt) exec(ctx context.Context, args []driver.Value) (driver.Result, error) {
 	if stmt.conn.isClosed() {
 		return nil, driver.ErrBadConn
 	}
+	if stmt.isStmt2 {
+		return stmt.stmt2Exec(ctx, args)
+	}
 	if len(args) != len(stmt.cols) {
 		return nil, fmt.Errorf("stmt exec error: wrong number of parameters")
 	}
-	block, err := serializer.SerializeRawBlock(param.NewParamsWithRowValue(args), stmt.colTypes)
-	if err != nil {
-		return nil, err
+	if stmt.conn.tx != nil {
+		// executed when the transaction commits, the caller may reuse the slice
+		stmt.conn.tx.addRow(stmt, append([]driver.Value(nil), args...))
+		return driver.RowsAffected(0), nil
 	}
-	err = stmt.conn.stmtBindParam(stmt.stmtID, block)
+	var affected int
+	err := stmt.do(func() (err error) {
+		affected, err = stmt.stmtExecRows(ctx, [][]driver.Value{args})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = stmt.conn.stmtAddBatch(stmt.stmtID)
-	if err != nil {
-		return nil, err
+	return driver.RowsAffected(affected), nil
+}
+
+// execRows writes the rows of an insert statement with a single exec.
+func (stmt *Stmt) execRows(ctx context.Context, rows [][]driver.Value) (int, error) {
+	if stmt.isStmt2 {
+		return stmt.stmt2ExecRows(ctx, rows)
 	}
-	affected, err := stmt.conn.stmtExec(stmt.stmtID)
+	return stmt.stmtExecRows(ctx, rows)
+}
+
+// stmtExecRows binds every row as one batch and executes the statement once.
+func (stmt *Stmt) stmtExecRows(ctx context.Context, rows [][]driver.Value) (int, error) {
+	for _, row := range rows {
+		block, err := serializer.SerializeRawBlock(param.NewParamsWithRowValue(row), stmt.colTypes)
+		if err != nil {
+			return 0, err
+		}
+		err = stmt.conn.stmtBindParam(ctx, stmt.stmtID, block)
+		if err != nil {
+			return 0, err
+		}
+		err = stmt.conn.stmtAddBatch(stmt.stmtID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return stmt.conn.stmtExec(ctx, stmt.stmtID)
+}
+
+func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.query(context.Background(), args)
+}
+
+// QueryContext implements driver.StmtQueryContext, the spans of the bind and exec requests are children of the span
+// of ctx.
+func (stmt *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
-	return driver.RowsAffected(affected), nil
+	return stmt.query(ctx, values)
 }
 
-func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+func (stmt *Stmt) query(ctx context.Context, args []driver.Value) (driver.Rows, error) {
 	if stmt.conn.isClosed() {
 		return nil, driver.ErrBadConn
 	}
-	block, err := serializer.SerializeRawBlock(param.NewParamsWithRowValue(args), param.NewColumnTypeWithValue(stmt.queryColTypes))
-	if err != nil {
-		return nil, err
+	if stmt.conn.tx != nil {
+		return nil, errTxBatchNotInsert()
 	}
-	err = stmt.conn.stmtBindParam(stmt.stmtID, block)
-	if err != nil {
-		return nil, err
+	if stmt.isStmt2 {
+		return stmt.stmt2Query(ctx, args)
 	}
-	err = stmt.conn.stmtAddBatch(stmt.stmtID)
+	block, err := serializer.SerializeRawBlock(param.NewParamsWithRowValue(args), param.NewColumnTypeWithValue(stmt.queryColTypes))
 	if err != nil {
 		return nil, err
 	}
-	_, err = stmt.conn.stmtExec(stmt.stmtID)
+	var rs *rows
+	err = stmt.do(func() error {
+		err := stmt.conn.stmtBindParam(ctx, stmt.stmtID, block)
+		if err != nil {
+			return err
+		}
+		err = stmt.conn.stmtAddBatch(stmt.stmtID)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.conn.stmtExec(ctx, stmt.stmtID)
+		if err != nil {
+			return err
+		}
+		rs, err = stmt.conn.stmtUseResult(stmt.stmtID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return stmt.conn.stmtUseResult(stmt.stmtID)
+	return rs, nil
 }
 
 func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
 	if stmt.isInsert {
 		if stmt.cols == nil {
+			err := stmt.ensurePrepared()
+			if err != nil {
+				return err
+			}
 			cols, err := stmt.conn.stmtGetColFields(stmt.stmtID)
 			if err != nil {
 				return err
@@ -312,6 +494,15 @@ func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
 			default:
 				return fmt.Errorf("CheckNamedValue:%v can not convert to geometry", v)
 			}
+		case common.TSDB_DATA_TYPE_JSON:
+			switch v.Value.(type) {
+			case string:
+				v.Value = types.TaosJson(v.Value.(string))
+			case []byte:
+				v.Value = types.TaosJson(v.Value.([]byte))
+			default:
+				return fmt.Errorf("CheckNamedValue:%v can not convert to json", v)
+			}
 		case common.TSDB_DATA_TYPE_TIMESTAMP:
 			t, is := v.Value.(time.Time)
 			if is {
@@ -518,3 +709,14 @@ func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
 	}
 	return nil
 }
+
+func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		args[i] = arg.Value
+	}
+	return args, nil
+}
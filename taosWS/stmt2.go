@@ -0,0 +1,212 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/taosdata/driver-go/v3/common"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+// setStmt2Fields records the bind fields of a stmt2 insert statement, the fields are in placeholder order
+// and include the table name and tags, so CheckNamedValue can convert every argument.
+func (stmt *Stmt) setStmt2Fields(fields []*stmtCommon.Stmt2AllField) {
+	stmt.stmt2Fields = fields
+	stmt.cols = make([]*stmtCommon.StmtField, len(fields))
+	for i, field := range fields {
+		fieldType := field.FieldType
+		if field.BindType == stmtCommon.TAOS_FIELD_TBNAME {
+			fieldType = common.TSDB_DATA_TYPE_BINARY
+		}
+		stmt.cols[i] = &stmtCommon.StmtField{
+			Name:      field.Name,
+			FieldType: fieldType,
+			Precision: field.Precision,
+			Scale:     field.Scale,
+			Bytes:     field.Bytes,
+		}
+	}
+}
+
+func (stmt *Stmt) stmt2Exec(ctx context.Context, args []driver.Value) (driver.Result, error) {
+	if !stmt.isInsert {
+		if stmt.conn.tx != nil {
+			return nil, errTxBatchNotInsert()
+		}
+		var affected int
+		err := stmt.do(func() (err error) {
+			affected, err = stmt.stmt2BindQueryAndExec(ctx, args)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(affected), nil
+	}
+	if len(args) != len(stmt.stmt2Fields) {
+		return nil, fmt.Errorf("stmt exec error: wrong number of parameters")
+	}
+	row := make([]driver.Value, len(args))
+	for i, arg := range args {
+		row[i] = stmt2Value(arg)
+	}
+	if stmt.conn.tx != nil {
+		// executed when the transaction commits
+		stmt.conn.tx.addRow(stmt, row)
+		return driver.RowsAffected(0), nil
+	}
+	var affected int
+	err := stmt.do(func() (err error) {
+		affected, err = stmt.stmt2ExecRows(ctx, [][]driver.Value{row})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (stmt *Stmt) stmt2Query(ctx context.Context, args []driver.Value) (driver.Rows, error) {
+	var rs *rows
+	err := stmt.do(func() error {
+		_, err := stmt.stmt2BindQueryAndExec(ctx, args)
+		if err != nil {
+			return err
+		}
+		rs, err = stmt.conn.stmt2UseResult(stmt.stmtID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (stmt *Stmt) stmt2BindQueryAndExec(ctx context.Context, args []driver.Value) (int, error) {
+	if len(args) != 0 {
+		cols := make([][]driver.Value, len(args))
+		for i, arg := range args {
+			cols[i] = []driver.Value{stmt2Value(arg)}
+		}
+		data, err := stmtCommon.MarshalStmt2Binary([]*stmtCommon.TaosStmt2BindData{{Cols: cols}}, false, nil)
+		if err != nil {
+			return 0, err
+		}
+		err = stmt.conn.stmt2Bind(ctx, stmt.stmtID, data)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return stmt.conn.stmt2Exec(ctx, stmt.stmtID)
+}
+
+// stmt2ExecRows binds all rows in a single request and executes them,
+// rows with the same table name are merged into one subtable bind.
+func (stmt *Stmt) stmt2ExecRows(ctx context.Context, rows [][]driver.Value) (int, error) {
+	bindData, err := stmt.buildStmt2BindData(rows)
+	if err != nil {
+		return 0, err
+	}
+	data, err := stmtCommon.MarshalStmt2Binary(bindData, true, stmt.stmt2Fields)
+	if err != nil {
+		return 0, err
+	}
+	err = stmt.conn.stmt2Bind(ctx, stmt.stmtID, data)
+	if err != nil {
+		return 0, err
+	}
+	return stmt.conn.stmt2Exec(ctx, stmt.stmtID)
+}
+
+func (stmt *Stmt) buildStmt2BindData(rows [][]driver.Value) ([]*stmtCommon.TaosStmt2BindData, error) {
+	var bindData []*stmtCommon.TaosStmt2BindData
+	tables := make(map[string]*stmtCommon.TaosStmt2BindData)
+	for _, row := range rows {
+		tableName := ""
+		for i, field := range stmt.stmt2Fields {
+			if field.BindType != stmtCommon.TAOS_FIELD_TBNAME {
+				continue
+			}
+			switch v := row[i].(type) {
+			case string:
+				tableName = v
+			case []byte:
+				tableName = string(v)
+			default:
+				return nil, fmt.Errorf("stmt exec error: invalid table name %v", row[i])
+			}
+		}
+		var tags []driver.Value
+		for i, field := range stmt.stmt2Fields {
+			if field.BindType == stmtCommon.TAOS_FIELD_TAG {
+				tags = append(tags, row[i])
+			}
+		}
+		data, exist := tables[tableName]
+		if !exist {
+			data = &stmtCommon.TaosStmt2BindData{TableName: tableName, Tags: tags}
+			for _, field := range stmt.stmt2Fields {
+				if field.BindType == stmtCommon.TAOS_FIELD_COL {
+					data.Cols = append(data.Cols, nil)
+				}
+			}
+			tables[tableName] = data
+			bindData = append(bindData, data)
+		} else if !reflect.DeepEqual(data.Tags, tags) {
+			// a subtable is bound once with the tags of its first row
+			return nil, fmt.Errorf("stmt exec error: table %s is bound with different tags %v and %v", tableName, data.Tags, tags)
+		}
+		colIndex := 0
+		for i, field := range stmt.stmt2Fields {
+			if field.BindType == stmtCommon.TAOS_FIELD_COL {
+				data.Cols[colIndex] = append(data.Cols[colIndex], row[i])
+				colIndex++
+			}
+		}
+	}
+	return bindData, nil
+}
+
+// stmt2Value converts the value produced by CheckNamedValue to the go type expected by stmt2.
+func stmt2Value(v driver.Value) driver.Value {
+	switch v := v.(type) {
+	case types.TaosBool:
+		return bool(v)
+	case types.TaosTinyint:
+		return int8(v)
+	case types.TaosSmallint:
+		return int16(v)
+	case types.TaosInt:
+		return int32(v)
+	case types.TaosBigint:
+		return int64(v)
+	case types.TaosUTinyint:
+		return uint8(v)
+	case types.TaosUSmallint:
+		return uint16(v)
+	case types.TaosUInt:
+		return uint32(v)
+	case types.TaosUBigint:
+		return uint64(v)
+	case types.TaosFloat:
+		return float32(v)
+	case types.TaosDouble:
+		return float64(v)
+	case types.TaosBinary:
+		return []byte(v)
+	case types.TaosVarBinary:
+		return []byte(v)
+	case types.TaosNchar:
+		return string(v)
+	case types.TaosJson:
+		return []byte(v)
+	case types.TaosGeometry:
+		return []byte(v)
+	case types.TaosTimestamp:
+		return v.T
+	}
+	return v
+}
@@ -0,0 +1,217 @@
+package taosWS
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/testutil/mockadapter"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+func TestStmt2Value(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		in   driver.Value
+		want driver.Value
+	}{
+		{in: types.TaosBool(true), want: true},
+		{in: types.TaosTinyint(1), want: int8(1)},
+		{in: types.TaosSmallint(1), want: int16(1)},
+		{in: types.TaosInt(1), want: int32(1)},
+		{in: types.TaosBigint(1), want: int64(1)},
+		{in: types.TaosUTinyint(1), want: uint8(1)},
+		{in: types.TaosUSmallint(1), want: uint16(1)},
+		{in: types.TaosUInt(1), want: uint32(1)},
+		{in: types.TaosUBigint(1), want: uint64(1)},
+		{in: types.TaosFloat(1), want: float32(1)},
+		{in: types.TaosDouble(1), want: float64(1)},
+		{in: types.TaosBinary("a"), want: []byte("a")},
+		{in: types.TaosVarBinary("a"), want: []byte("a")},
+		{in: types.TaosNchar("a"), want: "a"},
+		{in: types.TaosJson(`{"a":1}`), want: []byte(`{"a":1}`)},
+		{in: types.TaosGeometry{0x01}, want: []byte{0x01}},
+		{in: types.TaosTimestamp{T: now, Precision: common.PrecisionMilliSecond}, want: now},
+		{in: nil, want: nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, stmt2Value(tt.in))
+	}
+}
+
+func TestBuildStmt2BindData(t *testing.T) {
+	stmt := &Stmt{}
+	stmt.setStmt2Fields([]*stmtCommon.Stmt2AllField{
+		{Name: "tbname", FieldType: common.TSDB_DATA_TYPE_BINARY, BindType: stmtCommon.TAOS_FIELD_TBNAME},
+		{Name: "t1", FieldType: common.TSDB_DATA_TYPE_INT, BindType: stmtCommon.TAOS_FIELD_TAG},
+		{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+		{Name: "c1", FieldType: common.TSDB_DATA_TYPE_INT, BindType: stmtCommon.TAOS_FIELD_COL},
+	})
+	assert.Equal(t, 4, stmt.NumInput())
+	now := time.Now()
+	data, err := stmt.buildStmt2BindData([][]driver.Value{
+		{[]byte("d1"), int32(1), now, int32(1)},
+		{[]byte("d2"), int32(2), now, int32(2)},
+		{[]byte("d1"), int32(1), now.Add(time.Second), nil},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*stmtCommon.TaosStmt2BindData{
+		{
+			TableName: "d1",
+			Tags:      []driver.Value{int32(1)},
+			Cols:      [][]driver.Value{{now, now.Add(time.Second)}, {int32(1), nil}},
+		},
+		{
+			TableName: "d2",
+			Tags:      []driver.Value{int32(2)},
+			Cols:      [][]driver.Value{{now}, {int32(2)}},
+		},
+	}, data)
+	_, err = stmt.buildStmt2BindData([][]driver.Value{{int32(1), int32(1), now, int32(1)}})
+	assert.Error(t, err)
+	_, err = stmt.buildStmt2BindData([][]driver.Value{
+		{[]byte("d1"), int32(1), now, int32(1)},
+		{[]byte("d1"), int32(2), now.Add(time.Second), int32(2)},
+	})
+	assert.Error(t, err)
+}
+
+func TestStmt2Unsupported(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	s.Script(`^insert into t values`, &mockadapter.Result{
+		AffectedRows: 1,
+		Fields: []*stmtCommon.Stmt2AllField{
+			{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+			{Name: "v", FieldType: common.TSDB_DATA_TYPE_INT, Bytes: 4, BindType: stmtCommon.TAOS_FIELD_COL},
+		},
+	})
+	s.Inject(mockadapter.Fault{Action: mockadapter.ActionStmt2Init, Code: 0xffff, Message: "unknown action stmt2_init"})
+	db, err := sql.Open(driverName, "root:taosdata@ws("+s.Addr()+")/?txBatch=true")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	db.SetMaxOpenConns(1)
+	for i := 0; i < 2; i++ {
+		stmt, err := db.Prepare("insert into t values(?, ?)")
+		require.NoError(t, err)
+		result, err := stmt.Exec(time.Now(), i)
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+		assert.NoError(t, stmt.Close())
+	}
+	// stmt2_init is tried once per connection
+	assert.Equal(t, 1, len(s.Requests(mockadapter.ActionStmt2Init)))
+	assert.Equal(t, 2, len(s.Requests(mockadapter.ActionStmtInit)))
+	assert.Equal(t, 2, len(s.Requests(mockadapter.ActionStmtExec)))
+
+	// the rows of a stmt1 statement are bound in one batch when the transaction commits
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("insert into t values(?, ?)")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		result, err := stmt.Exec(time.Now(), i)
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), affected)
+	}
+	assert.Equal(t, 2, len(s.Requests(mockadapter.ActionStmtBind)))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 4, len(s.Requests(mockadapter.ActionStmtBind)))
+	assert.Equal(t, 3, len(s.Requests(mockadapter.ActionStmtExec)))
+}
+
+func TestStmt2TxBatch(t *testing.T) {
+	db, err := sql.Open(driverName, dataSourceName+"?txBatch=true")
+	require.NoError(t, err)
+	defer func() {
+		err = db.Close()
+		assert.NoError(t, err)
+	}()
+	testDbName := "test_ws_stmt2_tx_batch"
+	_, err = exec(db, fmt.Sprintf("drop database if exists %s", testDbName))
+	require.NoError(t, err)
+	_, err = exec(db, fmt.Sprintf("create database %s", testDbName))
+	require.NoError(t, err)
+	defer func() {
+		_, err = exec(db, fmt.Sprintf("drop database if exists %s", testDbName))
+		assert.NoError(t, err)
+	}()
+	_, err = exec(db, fmt.Sprintf("create stable %s.stb(ts timestamp, v int) tags(t int)", testDbName))
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	stmt, err := tx.Prepare(fmt.Sprintf("insert into ? using %s.stb tags(?) values(?,?)", testDbName))
+	require.NoError(t, err)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		tableName := fmt.Sprintf("%s.d%d", testDbName, i%3)
+		result, err := stmt.Exec(tableName, i%3, now.Add(time.Duration(i)*time.Second), i)
+		require.NoError(t, err)
+		affected, err := result.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), affected)
+	}
+	var count int
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.stb", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	err = tx.Commit()
+	require.NoError(t, err)
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.stb", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	stmt, err = tx.Prepare(fmt.Sprintf("insert into ? using %s.stb tags(?) values(?,?)", testDbName))
+	require.NoError(t, err)
+	_, err = stmt.Exec(testDbName+".d9", 9, now, 9)
+	require.NoError(t, err)
+	err = tx.Rollback()
+	require.NoError(t, err)
+	err = db.QueryRow(fmt.Sprintf("select count(*) from %s.stb", testDbName)).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+}
+
+func TestStmt1Fallback(t *testing.T) {
+	db, err := sql.Open(driverName, dataSourceName+"?enableStmt2=false")
+	require.NoError(t, err)
+	defer func() {
+		err = db.Close()
+		assert.NoError(t, err)
+	}()
+	testDbName := "test_ws_stmt1_fallback"
+	_, err = exec(db, fmt.Sprintf("create database if not exists %s", testDbName))
+	require.NoError(t, err)
+	defer func() {
+		_, err = exec(db, fmt.Sprintf("drop database if exists %s", testDbName))
+		assert.NoError(t, err)
+	}()
+	_, err = exec(db, fmt.Sprintf("create table %s.t(ts timestamp, v int)", testDbName))
+	require.NoError(t, err)
+	stmt, err := db.Prepare(fmt.Sprintf("insert into %s.t values(?,?)", testDbName))
+	require.NoError(t, err)
+	result, err := stmt.Exec(time.Now(), 1)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	err = stmt.Close()
+	assert.NoError(t, err)
+	_, err = db.Begin()
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/testutil/mockadapter"
+)
+
+func TestTxBatchBuffer(t *testing.T) {
+	ctx := context.Background()
+	tc := &taosConn{cfg: &Config{InterpolateParams: true}, logger: log.Nop}
+	_, err := tc.Begin()
+	assert.Error(t, err)
+
+	tc.cfg.TxBatch = true
+	_, err = tc.BeginTx(ctx, driver.TxOptions{ReadOnly: true})
+	assert.Error(t, err)
+	tx, err := tc.Begin()
+	require.NoError(t, err)
+	_, err = tc.Begin()
+	assert.Error(t, err)
+	result, err := tc.ExecContext(ctx, "insert into t1 values(now, ?)", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}})
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+	_, err = tc.ExecContext(ctx, "insert into t2 select * from t1", nil)
+	assert.Error(t, err)
+	_, err = tc.ExecContext(ctx, "drop table t1", nil)
+	assert.Error(t, err)
+	_, err = tc.QueryContext(ctx, "select * from t1", nil)
+	assert.Error(t, err)
+	assert.Equal(t, []*txOp{{inserts: []string{"t1 values(now, 1)"}}}, tc.tx.ops)
+	err = tx.Rollback()
+	require.NoError(t, err)
+	assert.Nil(t, tc.tx)
+}
+
+func TestTxBatchReconnect(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	s.Script(`^insert into \? values`, &mockadapter.Result{
+		AffectedRows: 1,
+		Fields: []*stmtCommon.Stmt2AllField{
+			{Name: "tbname", FieldType: common.TSDB_DATA_TYPE_BINARY, BindType: stmtCommon.TAOS_FIELD_TBNAME},
+			{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+			{Name: "v", FieldType: common.TSDB_DATA_TYPE_INT, Bytes: 4, BindType: stmtCommon.TAOS_FIELD_COL},
+		},
+	})
+	conn, err := TDengineDriver{}.Open("root:taosdata@ws(" + s.Addr() + ")/?txBatch=true&autoReconnect=true&readTimeout=1s")
+	require.NoError(t, err)
+	tc := conn.(*taosConn)
+	defer func() {
+		assert.NoError(t, tc.Close())
+	}()
+	tx, err := tc.Begin()
+	require.NoError(t, err)
+	stmt, err := tc.Prepare("insert into ? values(?, ?)")
+	require.NoError(t, err)
+	_, err = stmt.Exec([]driver.Value{"t", time.Now(), int32(1)})
+	require.NoError(t, err)
+
+	s.DisconnectAll()
+	require.Eventually(t, func() bool { return tc.getMessageError() != nil }, time.Second, 10*time.Millisecond)
+	// preparing another statement replaces the broken session
+	other, err := tc.Prepare("insert into ? values(?, ?)")
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+	require.NoError(t, stmt.Close())
+	require.NoError(t, tx.Commit())
+	binds := s.Requests(mockadapter.ActionStmt2Bind)
+	require.Equal(t, 1, len(binds))
+	assert.Equal(t, "insert into ? values(?, ?)", binds[0].SQL)
+	assert.Equal(t, 2, len(s.Requests(mockadapter.ActionStmt2Close)))
+}
+
+func TestTxBatchOrder(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	s.Script(`^insert into \? values`, &mockadapter.Result{
+		AffectedRows: 1,
+		Fields: []*stmtCommon.Stmt2AllField{
+			{Name: "tbname", FieldType: common.TSDB_DATA_TYPE_BINARY, BindType: stmtCommon.TAOS_FIELD_TBNAME},
+			{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+			{Name: "v", FieldType: common.TSDB_DATA_TYPE_INT, Bytes: 4, BindType: stmtCommon.TAOS_FIELD_COL},
+		},
+	})
+	s.Script(`^insert into t values`, &mockadapter.Result{AffectedRows: 2})
+	conn, err := TDengineDriver{}.Open("root:taosdata@ws(" + s.Addr() + ")/?txBatch=true")
+	require.NoError(t, err)
+	tc := conn.(*taosConn)
+	defer func() {
+		assert.NoError(t, tc.Close())
+	}()
+	stmt, err := tc.Prepare("insert into ? values(?, ?)")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, stmt.Close())
+	}()
+	ts := time.Now()
+	write := func() {
+		_, err = stmt.Exec([]driver.Value{"t", ts, int32(1)})
+		require.NoError(t, err)
+		_, err = tc.ExecContext(context.Background(), "insert into t values(now, 2)", nil)
+		require.NoError(t, err)
+		_, err = tc.ExecContext(context.Background(), "insert into t values(now, 3)", nil)
+		require.NoError(t, err)
+		_, err = stmt.Exec([]driver.Value{"t", ts, int32(4)})
+		require.NoError(t, err)
+		_, err = stmt.Exec([]driver.Value{"t", ts, int32(5)})
+		require.NoError(t, err)
+	}
+
+	tx, err := tc.Begin()
+	require.NoError(t, err)
+	write()
+	require.NoError(t, tx.Commit())
+	var actions []string
+	for _, request := range s.Requests(mockadapter.ActionStmt2Bind, mockadapter.ActionQuery) {
+		actions = append(actions, request.Action)
+	}
+	assert.Equal(t, []string{mockadapter.ActionStmt2Bind, mockadapter.ActionQuery, mockadapter.ActionStmt2Bind}, actions)
+
+	// the rows written before the failed insert stay written
+	s.Inject(mockadapter.Fault{Action: mockadapter.ActionQuery, Times: 1, Code: 0x2603, Message: "Table does not exist"})
+	tx, err = tc.Begin()
+	require.NoError(t, err)
+	write()
+	err = tx.Commit()
+	var commitErr *common.TxBatchCommitError
+	require.True(t, errors.As(err, &commitErr), err)
+	assert.Equal(t, 1, commitErr.Applied)
+	assert.Equal(t, 5, commitErr.Total)
+	assert.Equal(t, 3, len(s.Requests(mockadapter.ActionStmt2Bind)))
+}
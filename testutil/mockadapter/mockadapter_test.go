@@ -0,0 +1,255 @@
+package mockadapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/serializer"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	_ "github.com/taosdata/driver-go/v3/taosRestful"
+	_ "github.com/taosdata/driver-go/v3/taosWS"
+	"github.com/taosdata/driver-go/v3/ws/schemaless"
+	"github.com/taosdata/driver-go/v3/ws/stmt"
+	wstmq "github.com/taosdata/driver-go/v3/ws/tmq"
+)
+
+var now = time.Now().Round(time.Millisecond)
+
+func testBlock(t *testing.T) []byte {
+	block, err := serializer.SerializeRawBlock(
+		[]*param.Param{
+			param.NewParam(2).AddTimestamp(now, common.PrecisionMilliSecond).AddTimestamp(now.Add(time.Second), common.PrecisionMilliSecond),
+			param.NewParam(2).AddInt(1).AddNull(),
+			param.NewParam(2).AddBinary([]byte("v1")).AddBinary([]byte("v2")),
+		},
+		param.NewColumnType(3).AddTimestamp().AddInt().AddBinary(10),
+	)
+	require.NoError(t, err)
+	return block
+}
+
+func newTestServer(t *testing.T) *Server {
+	s := New()
+	t.Cleanup(s.Close)
+	s.Script(`^select \* from t`, &Result{Names: []string{"ts", "v", "s"}, Blocks: [][]byte{testBlock(t)}})
+	s.Script(`^insert`, &Result{AffectedRows: 2})
+	return s
+}
+
+func queryAll(t *testing.T, db *sql.DB, query string) [][]interface{} {
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, rows.Close())
+	}()
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(types))
+	assert.Equal(t, "TIMESTAMP", types[0].DatabaseTypeName())
+	assert.Equal(t, "INT", types[1].DatabaseTypeName())
+	assert.Equal(t, "VARCHAR", types[2].DatabaseTypeName())
+	var result [][]interface{}
+	for rows.Next() {
+		var ts time.Time
+		var v sql.NullInt32
+		var s string
+		require.NoError(t, rows.Scan(&ts, &v, &s))
+		result = append(result, []interface{}{ts.UnixNano() / 1e6, v, s})
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestSQLDrivers(t *testing.T) {
+	s := newTestServer(t)
+	for _, dsn := range []struct {
+		driverName string
+		dsn        string
+	}{
+		{driverName: "taosWS", dsn: "root:taosdata@ws(" + s.Addr() + ")/"},
+		{driverName: "taosRestful", dsn: "root:taosdata@http(" + s.Addr() + ")/"},
+	} {
+		t.Run(dsn.driverName, func(t *testing.T) {
+			db, err := sql.Open(dsn.driverName, dsn.dsn)
+			require.NoError(t, err)
+			defer func() {
+				assert.NoError(t, db.Close())
+			}()
+			assert.Equal(t, [][]interface{}{
+				{now.UnixNano() / 1e6, sql.NullInt32{Int32: 1, Valid: true}, "v1"},
+				{now.Add(time.Second).UnixNano() / 1e6, sql.NullInt32{}, "v2"},
+			}, queryAll(t, db, "select * from t"))
+			result, err := db.Exec("insert into t values(now, 1, 'v')")
+			require.NoError(t, err)
+			affected, err := result.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), affected)
+			_, err = db.Exec("drop table t")
+			var taosErr *taosErrors.TaosError
+			require.True(t, errors.As(err, &taosErr))
+			assert.Equal(t, int32(UnscriptedCode), taosErr.Code)
+		})
+	}
+	assert.Equal(t, 3, len(s.Requests(ActionQuery)))
+	assert.Equal(t, 3, len(s.Requests(ActionRESTSQL)))
+	assert.Equal(t, "select * from t", s.Requests(ActionRESTSQL)[0].SQL)
+}
+
+func TestFault(t *testing.T) {
+	s := newTestServer(t)
+	db, err := sql.Open("taosWS", "root:taosdata@ws("+s.Addr()+")/?readTimeout=500ms")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	s.Inject(Fault{Action: ActionQuery, SQL: "^insert", Times: 1, Code: 0x2603, Message: "Table does not exist"})
+	_, err = db.Exec("insert into t values(now, 1, 'v')")
+	assert.Equal(t, &taosErrors.TaosError{Code: 0x2603, ErrStr: "Table does not exist"}, err)
+	_, err = db.Exec("insert into t values(now, 1, 'v')")
+	assert.NoError(t, err)
+
+	s.Inject(Fault{Action: ActionQuery, Times: 1, Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = db.ExecContext(ctx, "insert into t values(now, 1, 'v')")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	s.Inject(Fault{Action: ActionFetchRawBlock, Times: 1, Drop: true})
+	rows, err := db.Query("select * from t")
+	require.NoError(t, err)
+	assert.False(t, rows.Next())
+	assert.Error(t, rows.Err())
+	assert.NoError(t, rows.Close())
+}
+
+func TestStmt2(t *testing.T) {
+	s := newTestServer(t)
+	s.Script(`^insert into \? values`, &Result{
+		AffectedRows: 2,
+		Fields: []*stmtCommon.Stmt2AllField{
+			{Name: "tbname", FieldType: common.TSDB_DATA_TYPE_BINARY, BindType: stmtCommon.TAOS_FIELD_TBNAME},
+			{Name: "ts", FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, BindType: stmtCommon.TAOS_FIELD_COL},
+			{Name: "v", FieldType: common.TSDB_DATA_TYPE_INT, Bytes: 4, BindType: stmtCommon.TAOS_FIELD_COL},
+		},
+	})
+	config := stmt.NewConfig(s.WSURL(), 0)
+	require.NoError(t, config.SetConnectUser("root"))
+	require.NoError(t, config.SetConnectPass("taosdata"))
+	connector, err := stmt.NewConnector(config)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, connector.Close())
+	}()
+	stmt2, err := connector.InitStmt2()
+	require.NoError(t, err)
+	require.NoError(t, stmt2.Prepare("insert into ? values(?, ?)"))
+	err = stmt2.Bind([]*stmtCommon.TaosStmt2BindData{{
+		TableName: "t",
+		Cols:      [][]driver.Value{{now, now.Add(time.Second)}, {int32(1), nil}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, stmt2.Exec())
+	assert.Equal(t, 2, stmt2.GetAffectedRows())
+	require.NoError(t, stmt2.Close())
+	binds := s.Requests(ActionStmt2Bind)
+	require.Equal(t, 1, len(binds))
+	assert.Equal(t, "insert into ? values(?, ?)", binds[0].SQL)
+
+	stmt2, err = connector.InitStmt2()
+	require.NoError(t, err)
+	require.NoError(t, stmt2.Prepare("select * from t where v = ?"))
+	require.NoError(t, stmt2.Bind([]*stmtCommon.TaosStmt2BindData{{Cols: [][]driver.Value{{int32(1)}}}}))
+	require.NoError(t, stmt2.Exec())
+	rows, err := stmt2.UseResult()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ts", "v", "s"}, rows.Columns())
+	var result [][]driver.Value
+	for {
+		values := make([]driver.Value, 3)
+		err = rows.Next(values)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		result = append(result, values)
+	}
+	require.Equal(t, 2, len(result))
+	assert.Equal(t, now.UnixNano()/1e6, result[0][0].(time.Time).UnixNano()/1e6)
+	assert.Equal(t, int32(1), result[0][1])
+	assert.Nil(t, result[1][1])
+	assert.NoError(t, rows.Close())
+	require.NoError(t, stmt2.Close())
+}
+
+func TestSchemaless(t *testing.T) {
+	s := newTestServer(t)
+	sml, err := schemaless.NewSchemaless(schemaless.NewConfig(s.WSURL(), 1,
+		schemaless.SetReadTimeout(time.Second),
+		schemaless.SetWriteTimeout(time.Second),
+		schemaless.SetUser("root"),
+		schemaless.SetPassword("taosdata"),
+		schemaless.SetDb("db"),
+	))
+	require.NoError(t, err)
+	defer sml.Close()
+	lines := "measurement,host=host1 field1=2i 1626006833639000000"
+	require.NoError(t, sml.Insert(lines, schemaless.InfluxDBLineProtocol, "ns", 0, 0))
+	inserts := s.Requests(ActionSchemalessInsert)
+	require.Equal(t, 1, len(inserts))
+	assert.Contains(t, string(inserts[0].Args), "field1=2i")
+
+	s.Inject(Fault{Action: ActionSchemalessInsert, Code: 0x3002, Message: "Invalid data format"})
+	err = sml.Insert(lines, schemaless.InfluxDBLineProtocol, "ns", 0, 0)
+	assert.Equal(t, &taosErrors.TaosError{Code: 0x3002, ErrStr: "Invalid data format"}, err)
+}
+
+func TestTMQ(t *testing.T) {
+	s := newTestServer(t)
+	s.Produce(&Message{
+		Topic:    "topic",
+		Database: "db",
+		VgroupID: 2,
+		Tables:   []Table{{Name: "t", Names: []string{"ts", "v", "s"}, Block: testBlock(t)}},
+	})
+	consumer, err := wstmq.NewConsumer(&tmq.ConfigMap{
+		"ws.url":          s.WSURL(),
+		"td.connect.user": "root",
+		"td.connect.pass": "taosdata",
+		"group.id":        "group",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	require.NoError(t, consumer.Subscribe("topic", nil))
+	event := consumer.Poll(500)
+	message, ok := event.(*tmq.DataMessage)
+	require.True(t, ok, "%v", event)
+	assert.Equal(t, "db", message.DBName())
+	assert.Equal(t, int32(2), message.TopicPartition.Partition)
+	assert.Equal(t, tmq.Offset(0), message.Offset())
+	data := message.Value().([]*tmq.Data)
+	require.Equal(t, 1, len(data))
+	assert.Equal(t, "t", data[0].TableName)
+	require.Equal(t, 3, len(data[0].Fields))
+	assert.Equal(t, "v", data[0].Fields[1].Name)
+	assert.Equal(t, 2, len(data[0].Data))
+	assert.Equal(t, "v2", data[0].Data[1][2])
+
+	assert.Nil(t, consumer.Poll(10))
+	committed, err := consumer.Commit()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(committed))
+	assert.Equal(t, tmq.Offset(1), committed[0].Offset)
+}
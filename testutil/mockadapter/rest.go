@@ -0,0 +1,126 @@
+package mockadapter
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/parser"
+)
+
+type restResp struct {
+	Code       int             `json:"code"`
+	Desc       string          `json:"desc,omitempty"`
+	ColumnMeta [][]interface{} `json:"column_meta,omitempty"`
+	Data       [][]interface{} `json:"data,omitempty"`
+	Rows       int             `json:"rows"`
+}
+
+// handleSQL serves /rest/sql, the body of the request is the SQL.
+func (s *Server) handleSQL(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sql := string(body)
+	reqID, _ := strconv.ParseUint(r.URL.Query().Get("req_id"), 10, 64)
+	s.record(&Request{Action: ActionRESTSQL, ReqID: reqID, SQL: sql})
+	var result *Result
+	if f := s.findFault(ActionRESTSQL, sql); f != nil {
+		if f.Disconnect {
+			if hijacker, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					_ = conn.Close()
+				}
+			}
+			return
+		}
+		if f.Drop {
+			select {
+			case <-r.Context().Done():
+			case <-s.closed:
+			}
+			return
+		}
+		if f.Code != 0 {
+			result = &Result{Code: f.Code, Message: f.Message}
+		}
+		if f.Delay > 0 && !s.sleep(f.Delay) {
+			return
+		}
+	}
+	if result == nil {
+		result = s.findResult(sql)
+	}
+	resp, err := restResponse(result)
+	if err != nil {
+		resp = &restResp{Code: 0xffff, Desc: err.Error()}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(&restResp{Code: 0xffff, Desc: err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func restResponse(result *Result) (*restResp, error) {
+	if result.Code != 0 {
+		return &restResp{Code: result.Code, Desc: result.Message}, nil
+	}
+	if result.isUpdate() {
+		return &restResp{
+			ColumnMeta: [][]interface{}{{"affected_rows", common.TSDB_DATA_TYPE_INT_Str, 4}},
+			Data:       [][]interface{}{{result.AffectedRows}},
+			Rows:       1,
+		}, nil
+	}
+	cols, err := result.columns()
+	if err != nil {
+		return nil, err
+	}
+	resp := &restResp{ColumnMeta: make([][]interface{}, len(cols.names)), Data: [][]interface{}{}}
+	for i, name := range cols.names {
+		typeName := common.GetTypeName(int(cols.types[i]))
+		if cols.types[i] == common.TSDB_DATA_TYPE_DECIMAL || cols.types[i] == common.TSDB_DATA_TYPE_DECIMAL64 {
+			typeName = fmt.Sprintf("DECIMAL(%d,%d)", cols.precisions[i], cols.scales[i])
+		}
+		resp.ColumnMeta[i] = []interface{}{name, typeName, cols.lengths[i]}
+	}
+	for _, block := range result.Blocks {
+		values, err := parser.ReadBlockSimple(unsafePointer(block), result.Precision)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range values {
+			data := make([]interface{}, len(row))
+			for i, value := range row {
+				data[i] = restValue(cols.types[i], value)
+			}
+			resp.Data = append(resp.Data, data)
+		}
+	}
+	resp.Rows = len(resp.Data)
+	return resp, nil
+}
+
+// restValue converts a value to its JSON form in the REST response.
+func restValue(colType uint8, value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		if colType == common.TSDB_DATA_TYPE_JSON {
+			return json.RawMessage(v)
+		}
+		return hex.EncodeToString(v)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,218 @@
+package mockadapter
+
+import (
+	"fmt"
+	"regexp"
+	"unsafe"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/parser"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+)
+
+// UnscriptedCode is the error code of a statement that matches no script.
+const UnscriptedCode = 0xffff
+
+// Result is the scripted result of a statement.
+type Result struct {
+	// Code and Message are returned when Code is not 0
+	Code    int
+	Message string
+	// AffectedRows of a statement without columns
+	AffectedRows int
+	// Names are the column names of a query, a statement without columns is an insert or another
+	// statement that returns no rows
+	Names []string
+	// Precision of the timestamps, common.PrecisionMilliSecond by default
+	Precision int
+	// Blocks are the raw blocks of the rows, built with serializer.SerializeRawBlock. The types of the
+	// columns are those of the first block, a query without rows still needs a block of zero rows.
+	Blocks [][]byte
+	// Fields are returned by stmt2_prepare and get_col_fields for a prepared insert
+	Fields []*stmtCommon.Stmt2AllField
+}
+
+func (r *Result) isUpdate() bool {
+	return len(r.Names) == 0
+}
+
+// columns describes the columns of a result.
+type columns struct {
+	names      []string
+	types      []uint8
+	lengths    []int64
+	precisions []int64
+	scales     []int64
+}
+
+func (r *Result) columns() (*columns, error) {
+	if len(r.Blocks) == 0 {
+		return nil, fmt.Errorf("mockadapter: result of %d columns has no block", len(r.Names))
+	}
+	block := unsafePointer(r.Blocks[0])
+	count := int(parser.RawBlockGetNumOfCols(block))
+	if count != len(r.Names) {
+		return nil, fmt.Errorf("mockadapter: %d names for %d columns", len(r.Names), count)
+	}
+	infos := make([]parser.RawBlockColInfo, count)
+	parser.RawBlockGetColInfo(block, infos)
+	cols := &columns{
+		names:      r.Names,
+		types:      make([]uint8, count),
+		lengths:    make([]int64, count),
+		precisions: make([]int64, count),
+		scales:     make([]int64, count),
+	}
+	for i, info := range infos {
+		cols.types[i] = uint8(info.ColType)
+		cols.lengths[i] = int64(info.Bytes)
+		if cols.types[i] == common.TSDB_DATA_TYPE_DECIMAL || cols.types[i] == common.TSDB_DATA_TYPE_DECIMAL64 {
+			bytes, precision, scale := parser.RawBlockGetDecimalInfo(block, i)
+			cols.lengths[i] = int64(bytes)
+			cols.precisions[i] = int64(precision)
+			cols.scales[i] = int64(scale)
+		}
+	}
+	return cols, nil
+}
+
+type script struct {
+	pattern *regexp.Regexp
+	result  *Result
+}
+
+// Script sets the result of the statements matching the regular expression pattern, a statement matching
+// several scripts gets the result of the last one. It is used for queries, prepared statements and REST
+// requests. It panics if pattern is not a valid regular expression.
+func (s *Server) Script(pattern string, result *Result) {
+	compiled := regexp.MustCompile(pattern)
+	s.lock.Lock()
+	s.scripts = append(s.scripts, &script{pattern: compiled, result: result})
+	s.lock.Unlock()
+}
+
+// findResult returns the result of a statement, an error result if no script matches.
+func (s *Server) findResult(sql string) *Result {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := len(s.scripts) - 1; i >= 0; i-- {
+		if s.scripts[i].pattern.MatchString(sql) {
+			return s.scripts[i].result
+		}
+	}
+	return &Result{Code: UnscriptedCode, Message: "mockadapter: no script for " + sql}
+}
+
+// openResult is a result being fetched.
+type openResult struct {
+	result *Result
+	// next is the index of the next block to fetch
+	next int
+	// block is the block returned by the last fetch of ws/stmt
+	block []byte
+}
+
+// openResult registers a result for fetching and returns its id.
+func (s *Server) openResult(result *Result) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := s.newID()
+	s.results[id] = &openResult{result: result}
+	return id
+}
+
+// nextBlock returns the next block of a result, nil when all blocks have been fetched.
+func (s *Server) nextBlock(id uint64) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	result, exist := s.results[id]
+	if !exist {
+		return nil, fmt.Errorf("result %d not found", id)
+	}
+	if result.next >= len(result.result.Blocks) {
+		result.block = nil
+		return nil, nil
+	}
+	result.block = result.result.Blocks[result.next]
+	result.next++
+	return result.block, nil
+}
+
+func (s *Server) currentBlock(id uint64) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	result, exist := s.results[id]
+	if !exist || result.block == nil {
+		return nil, fmt.Errorf("no block of result %d", id)
+	}
+	return result.block, nil
+}
+
+func (s *Server) freeResult(id uint64) {
+	s.lock.Lock()
+	delete(s.results, id)
+	s.lock.Unlock()
+}
+
+// preparedStmt is a prepared statement.
+type preparedStmt struct {
+	sql    string
+	result *Result
+}
+
+func (s *Server) initStmt() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := s.newID()
+	s.stmts[id] = &preparedStmt{}
+	return id
+}
+
+// prepareStmt prepares a statement with the result of sql.
+func (s *Server) prepareStmt(id uint64, sql string) (*Result, error) {
+	result := s.findResult(sql)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, exist := s.stmts[id]
+	if !exist {
+		return nil, fmt.Errorf("stmt %d not found", id)
+	}
+	if result.Code == 0 {
+		st.sql = sql
+		st.result = result
+	}
+	return result, nil
+}
+
+// stmtResult returns the result of a prepared statement.
+func (s *Server) stmtResult(id uint64) (*Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, exist := s.stmts[id]
+	if !exist {
+		return nil, fmt.Errorf("stmt %d not found", id)
+	}
+	if st.result == nil {
+		return nil, fmt.Errorf("stmt %d is not prepared", id)
+	}
+	return st.result, nil
+}
+
+func (s *Server) stmtSQL(id uint64) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if st, exist := s.stmts[id]; exist {
+		return st.sql
+	}
+	return ""
+}
+
+func (s *Server) closeStmt(id uint64) {
+	s.lock.Lock()
+	delete(s.stmts, id)
+	s.lock.Unlock()
+}
+
+func unsafePointer(block []byte) unsafe.Pointer {
+	return unsafe.Pointer(&block[0])
+}
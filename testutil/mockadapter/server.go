@@ -0,0 +1,281 @@
+// Package mockadapter is an in-process fake taosAdapter. It serves the websocket protocols of taosWS, ws/stmt,
+// ws/schemaless and ws/tmq and the REST protocol of taosRestful from scripted responses, so that tests of code
+// using these connectors can run without taosd and taosAdapter.
+package mockadapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Version is the server version reported to the connectors.
+const Version = "3.3.6.0"
+
+// Actions of the requests, they are recorded in Request.Action and matched by Fault.Action.
+// Binary messages and REST requests are named like the text actions.
+const (
+	ActionVersion       = "version"
+	ActionConn          = "conn"
+	ActionQuery         = "query"
+	ActionFetchRawBlock = "fetch_raw_block"
+	ActionFetch         = "fetch"
+	ActionFetchBlock    = "fetch_block"
+	ActionFreeResult    = "free_result"
+
+	ActionStmtInit         = "init"
+	ActionStmtPrepare      = "prepare"
+	ActionStmtSetTableName = "set_table_name"
+	ActionStmtSetTags      = "set_tags"
+	ActionStmtBind         = "bind"
+	ActionStmtAddBatch     = "add_batch"
+	ActionStmtExec         = "exec"
+	ActionStmtUseResult    = "use_result"
+	ActionStmtGetColFields = "get_col_fields"
+	ActionStmtClose        = "close"
+	ActionStmt2Init        = "stmt2_init"
+	ActionStmt2Prepare     = "stmt2_prepare"
+	ActionStmt2Bind        = "stmt2_bind"
+	ActionStmt2Exec        = "stmt2_exec"
+	ActionStmt2Result      = "stmt2_result"
+	ActionStmt2Close       = "stmt2_close"
+
+	ActionSchemalessInsert = "insert"
+
+	ActionTMQSubscribe    = "subscribe"
+	ActionTMQPoll         = "poll"
+	ActionTMQFetchRaw     = "fetch_raw"
+	ActionTMQCommit       = "commit"
+	ActionTMQUnsubscribe  = "unsubscribe"
+	ActionTMQAssignment   = "assignment"
+	ActionTMQSeek         = "seek"
+	ActionTMQCommitOffset = "commit_offset"
+	ActionTMQCommitted    = "committed"
+	ActionTMQPosition     = "position"
+
+	ActionRESTSQL = "rest_sql"
+)
+
+// Binary message types of the requests.
+const (
+	setTagsMessage       = 1
+	bindMessage          = 2
+	binaryQueryMessage   = 6
+	fetchRawBlockMessage = 7
+	stmt2BindMessage     = 9
+)
+
+// Request is a request received by the server.
+type Request struct {
+	Action string
+	ReqID  uint64
+	// SQL of a query, a prepare or a REST request
+	SQL string
+	// Args are the arguments of a text request
+	Args json.RawMessage
+	// Data is the payload of a binary request
+	Data []byte
+}
+
+// Server is a fake taosAdapter listening on a local port.
+type Server struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+	closed   chan struct{}
+
+	lock     sync.Mutex
+	wsConns  map[*wsConn]struct{}
+	scripts  []*script
+	faults   []*fault
+	requests []*Request
+	nextID   uint64
+	results  map[uint64]*openResult
+	stmts    map[uint64]*preparedStmt
+	// tmq state shared by all consumers
+	partitions []partition
+	logs       map[partition][]*Message
+	positions  map[partition]int64
+	committed  map[partition]int64
+	polled     map[uint64]*Message
+	produced   chan struct{}
+}
+
+// New starts a server, it must be closed with Close.
+func New() *Server {
+	s := &Server{
+		closed:    make(chan struct{}),
+		wsConns:   map[*wsConn]struct{}{},
+		results:   map[uint64]*openResult{},
+		stmts:     map[uint64]*preparedStmt{},
+		logs:      map[partition][]*Message{},
+		positions: map[partition]int64{},
+		committed: map[partition]int64{},
+		polled:    map[uint64]*Message{},
+		produced:  make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleWS)
+	mux.HandleFunc("/rest/tmq", s.handleWS)
+	mux.HandleFunc("/rest/sql", s.handleSQL)
+	mux.HandleFunc("/rest/sql/", s.handleSQL)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// Addr returns the host:port of the server, for the DSN of taosWS and taosRestful.
+func (s *Server) Addr() string {
+	return strings.TrimPrefix(s.server.URL, "http://")
+}
+
+// WSURL returns the websocket url of the server, for the configs of ws/stmt, ws/schemaless and ws/tmq.
+func (s *Server) WSURL() string {
+	return "ws://" + s.Addr()
+}
+
+// Close closes the websocket connections and stops the server.
+func (s *Server) Close() {
+	s.lock.Lock()
+	select {
+	case <-s.closed:
+		s.lock.Unlock()
+		return
+	default:
+	}
+	close(s.closed)
+	conns := make([]*wsConn, 0, len(s.wsConns))
+	for conn := range s.wsConns {
+		conns = append(conns, conn)
+	}
+	s.lock.Unlock()
+	for _, conn := range conns {
+		conn.close()
+	}
+	s.server.Close()
+}
+
+// DisconnectAll closes the websocket connections, the connectors see a broken connection.
+func (s *Server) DisconnectAll() {
+	s.lock.Lock()
+	conns := make([]*wsConn, 0, len(s.wsConns))
+	for conn := range s.wsConns {
+		conns = append(conns, conn)
+	}
+	s.lock.Unlock()
+	for _, conn := range conns {
+		conn.close()
+	}
+}
+
+// Requests returns the received requests of actions, or all the received requests if actions is empty.
+func (s *Server) Requests(actions ...string) []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var requests []Request
+	for _, request := range s.requests {
+		if len(actions) == 0 || containsString(actions, request.Action) {
+			requests = append(requests, *request)
+		}
+	}
+	return requests
+}
+
+func (s *Server) record(request *Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, request)
+	s.lock.Unlock()
+}
+
+func (s *Server) newID() uint64 {
+	s.nextID++
+	return s.nextID
+}
+
+// sleep waits for d, it reports false if the server is closed first.
+func (s *Server) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Fault changes the response to the requests of Action.
+type Fault struct {
+	Action string
+	// SQL, if not empty, is a regular expression that the SQL of the affected requests must match
+	SQL string
+	// Times is the number of affected requests, 0 affects all the matching requests
+	Times int
+	// Delay delays the response
+	Delay time.Duration
+	// Drop executes the request without responding
+	Drop bool
+	// Disconnect closes the connection without executing the request
+	Disconnect bool
+	// Code and Message are returned, without executing the request, when Code is not 0
+	Code    int
+	Message string
+}
+
+type fault struct {
+	Fault
+	sql       *regexp.Regexp
+	remaining int
+}
+
+// Inject adds a fault, the first matching fault of a request is applied. It panics if f.SQL is not a valid
+// regular expression.
+func (s *Server) Inject(f Fault) {
+	injected := &fault{Fault: f, remaining: f.Times}
+	if f.SQL != "" {
+		injected.sql = regexp.MustCompile(f.SQL)
+	}
+	s.lock.Lock()
+	s.faults = append(s.faults, injected)
+	s.lock.Unlock()
+}
+
+// ClearFaults removes the injected faults.
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	s.faults = nil
+	s.lock.Unlock()
+}
+
+// findFault returns the fault applied to a request, or nil if there is none.
+func (s *Server) findFault(action string, sql string) *Fault {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, f := range s.faults {
+		if f.Action != action || (f.sql != nil && !f.sql.MatchString(sql)) {
+			continue
+		}
+		if f.Times > 0 {
+			f.remaining--
+			if f.remaining == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		found := f.Fault
+		return &found
+	}
+	return nil
+}
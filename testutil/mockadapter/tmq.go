@@ -0,0 +1,258 @@
+package mockadapter
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+)
+
+// Message is a data message of a topic.
+type Message struct {
+	Topic    string
+	Database string
+	VgroupID int32
+	Tables   []Table
+	// offset in the vgroup, set by Produce
+	offset int64
+}
+
+// Table is the data of a table in a message.
+type Table struct {
+	Name string
+	// Names are the column names of Block
+	Names []string
+	// Precision of the timestamps, common.PrecisionMilliSecond by default
+	Precision int
+	// Block is a raw block built with serializer.SerializeRawBlock
+	Block []byte
+}
+
+type partition struct {
+	topic    string
+	vgroupID int32
+}
+
+// Produce appends messages to the log of their vgroups, the offset of a message is the number of messages
+// produced before it to the same topic and vgroup. The consumers share the positions and committed offsets
+// of the vgroups, as if they were the only consumer of one group.
+func (s *Server) Produce(messages ...*Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, message := range messages {
+		p := partition{topic: message.Topic, vgroupID: message.VgroupID}
+		if _, exist := s.logs[p]; !exist {
+			s.partitions = append(s.partitions, p)
+		}
+		produced := *message
+		produced.offset = int64(len(s.logs[p]))
+		s.logs[p] = append(s.logs[p], &produced)
+	}
+	close(s.produced)
+	s.produced = make(chan struct{})
+}
+
+func (c *wsConn) handleTMQ(request *Request, a *args) *response {
+	s := c.server
+	action := request.Action
+	switch action {
+	case ActionTMQSubscribe:
+		c.topics = a.Topics
+		return textResponse(action, a.ReqID, nil)
+	case ActionTMQUnsubscribe:
+		c.topics = nil
+		return textResponse(action, a.ReqID, nil)
+	case ActionTMQPoll:
+		message, messageID := s.poll(c.topics, time.Duration(a.BlockingTime)*time.Millisecond)
+		if message == nil {
+			return textResponse(action, a.ReqID, map[string]interface{}{"have_message": false})
+		}
+		return textResponse(action, a.ReqID, map[string]interface{}{
+			"have_message": true,
+			"topic":        message.Topic,
+			"database":     message.Database,
+			"vgroup_id":    message.VgroupID,
+			"message_type": common.TMQ_RES_DATA,
+			"message_id":   messageID,
+			"offset":       message.offset,
+		})
+	case ActionTMQFetchRaw:
+		s.lock.Lock()
+		message, exist := s.polled[a.MessageID]
+		s.lock.Unlock()
+		if !exist {
+			return errorResponse(action, a.ReqID, 0xffff, fmt.Sprintf("message %d not found", a.MessageID))
+		}
+		raw, err := encodeRawData(message.Tables)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		// timing, req_id, message_id, message type, raw data length, meta type and the raw data
+		buf := &bytes.Buffer{}
+		writeUint64(buf, 0)
+		writeUint64(buf, a.ReqID)
+		writeUint64(buf, a.MessageID)
+		writeUint64(buf, common.TMQ_RES_DATA)
+		writeUint32(buf, uint32(len(raw)))
+		writeUint16(buf, 0)
+		buf.Write(raw)
+		return &response{messageType: websocket.BinaryMessage, data: buf.Bytes()}
+	case ActionTMQCommit:
+		s.lock.Lock()
+		for _, p := range s.partitions {
+			if containsString(c.topics, p.topic) {
+				s.committed[p] = s.positions[p]
+			}
+		}
+		s.lock.Unlock()
+		return textResponse(action, a.ReqID, map[string]interface{}{"message_id": a.MessageID})
+	case ActionTMQAssignment:
+		assignment := []tmq.Assignment{}
+		s.lock.Lock()
+		for _, p := range s.partitions {
+			if p.topic == a.Topic {
+				assignment = append(assignment, tmq.Assignment{
+					VGroupID: p.vgroupID,
+					Offset:   s.positions[p],
+					End:      int64(len(s.logs[p])),
+				})
+			}
+		}
+		s.lock.Unlock()
+		return textResponse(action, a.ReqID, map[string]interface{}{"assignment": assignment})
+	case ActionTMQSeek, ActionTMQCommitOffset:
+		p := partition{topic: a.Topic, vgroupID: a.VgroupID}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if _, exist := s.logs[p]; !exist {
+			return errorResponse(action, a.ReqID, 0xffff, fmt.Sprintf("vgroup %d of topic %s not found", a.VgroupID, a.Topic))
+		}
+		if action == ActionTMQSeek {
+			s.positions[p] = a.Offset
+			return textResponse(action, a.ReqID, nil)
+		}
+		s.committed[p] = a.Offset
+		return textResponse(action, a.ReqID, map[string]interface{}{"topic": a.Topic, "vgroup_id": a.VgroupID, "offset": a.Offset})
+	case ActionTMQCommitted, ActionTMQPosition:
+		offsets := make([]int64, len(a.TopicVgroupIDs))
+		s.lock.Lock()
+		for i, id := range a.TopicVgroupIDs {
+			p := partition{topic: id.Topic, vgroupID: id.VgroupID}
+			offsets[i] = int64(tmq.OffsetInvalid)
+			if action == ActionTMQCommitted {
+				if offset, exist := s.committed[p]; exist {
+					offsets[i] = offset
+				}
+			} else if _, exist := s.logs[p]; exist {
+				offsets[i] = s.positions[p]
+			}
+		}
+		s.lock.Unlock()
+		if action == ActionTMQCommitted {
+			return textResponse(action, a.ReqID, map[string]interface{}{"committed": offsets})
+		}
+		return textResponse(action, a.ReqID, map[string]interface{}{"position": offsets})
+	}
+	return nil
+}
+
+// poll returns the next message of topics and its message id, it waits up to blockingTime for a message.
+func (s *Server) poll(topics []string, blockingTime time.Duration) (*Message, uint64) {
+	timer := time.NewTimer(blockingTime)
+	defer timer.Stop()
+	for {
+		s.lock.Lock()
+		produced := s.produced
+		for _, p := range s.partitions {
+			if !containsString(topics, p.topic) {
+				continue
+			}
+			position := s.positions[p]
+			if position < int64(len(s.logs[p])) {
+				message := s.logs[p][position]
+				s.positions[p] = position + 1
+				messageID := s.newID()
+				s.polled[messageID] = message
+				s.lock.Unlock()
+				return message, messageID
+			}
+		}
+		s.lock.Unlock()
+		select {
+		case <-produced:
+		case <-timer.C:
+			return nil, 0
+		case <-s.closed:
+			return nil, 0
+		}
+	}
+}
+
+// encodeRawData encodes tables in the raw data format parsed by parser.TMQRawDataParser, with table names
+// and schemas.
+func encodeRawData(tables []Table) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	// head of version 100 and no skipped bytes
+	buf.WriteByte(100)
+	writeUint32(buf, 0)
+	writeUint32(buf, uint32(len(tables)))
+	// with table name and with schema
+	buf.WriteByte(1)
+	buf.WriteByte(1)
+	for _, table := range tables {
+		if len(table.Block) == 0 {
+			return nil, fmt.Errorf("mockadapter: table %s has no block", table.Name)
+		}
+		block := unsafePointer(table.Block)
+		count := int(parser.RawBlockGetNumOfCols(block))
+		if count != len(table.Names) {
+			return nil, fmt.Errorf("mockadapter: %d names for %d columns of table %s", len(table.Names), count, table.Name)
+		}
+		infos := make([]parser.RawBlockColInfo, count)
+		parser.RawBlockGetColInfo(block, infos)
+		writeVariableByteInteger(buf, 18+len(table.Block))
+		buf.Write(make([]byte, 17))
+		buf.WriteByte(uint8(table.Precision))
+		buf.Write(table.Block)
+		writeVariableByteInteger(buf, zigzagEncode(count))
+		// schema version
+		writeVariableByteInteger(buf, zigzagEncode(1))
+		for i, info := range infos {
+			buf.WriteByte(uint8(info.ColType))
+			// flag
+			buf.WriteByte(0)
+			writeVariableByteInteger(buf, zigzagEncode(int(info.Bytes)))
+			writeVariableByteInteger(buf, zigzagEncode(i+1))
+			writeName(buf, table.Names[i])
+		}
+		writeName(buf, table.Name)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeName writes the length of name with the terminating NUL, name and NUL.
+func writeName(buf *bytes.Buffer, name string) {
+	writeVariableByteInteger(buf, len(name)+1)
+	buf.WriteString(name)
+	buf.WriteByte(0)
+}
+
+func writeVariableByteInteger(buf *bytes.Buffer, v int) {
+	for {
+		b := byte(v & 127)
+		v >>= 7
+		if v == 0 {
+			buf.WriteByte(b)
+			return
+		}
+		buf.WriteByte(b | 128)
+	}
+}
+
+func zigzagEncode(n int) int {
+	return (n << 1) ^ (n >> 63)
+}
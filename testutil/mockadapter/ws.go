@@ -0,0 +1,419 @@
+package mockadapter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/taosdata/driver-go/v3/common/parser"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+)
+
+// wsConn is a websocket connection of /ws or /rest/tmq, the requests of a connection are executed in order.
+type wsConn struct {
+	server    *Server
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+	// topics subscribed by a consumer
+	topics []string
+}
+
+type response struct {
+	messageType int
+	data        []byte
+}
+
+// args holds the arguments of all text actions.
+type args struct {
+	ReqID          uint64          `json:"req_id"`
+	ID             uint64          `json:"id"`
+	StmtID         uint64          `json:"stmt_id"`
+	SQL            string          `json:"sql"`
+	Topics         []string        `json:"topics"`
+	Topic          string          `json:"topic"`
+	VgroupID       int32           `json:"vgroup_id"`
+	Offset         int64           `json:"offset"`
+	MessageID      uint64          `json:"message_id"`
+	BlockingTime   int64           `json:"blocking_time"`
+	TopicVgroupIDs []topicVgroupID `json:"topic_vgroup_ids"`
+}
+
+type topicVgroupID struct {
+	Topic    string `json:"topic"`
+	VgroupID int32  `json:"vgroup_id"`
+}
+
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &wsConn{server: s, ws: ws}
+	s.lock.Lock()
+	select {
+	case <-s.closed:
+		s.lock.Unlock()
+		_ = ws.Close()
+		return
+	default:
+	}
+	s.wsConns[conn] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.wsConns, conn)
+		s.lock.Unlock()
+		conn.close()
+	}()
+	for {
+		messageType, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		switch messageType {
+		case websocket.TextMessage:
+			conn.handleText(message)
+		case websocket.BinaryMessage:
+			conn.handleBinary(message)
+		}
+	}
+}
+
+func (c *wsConn) write(resp *response) {
+	if resp == nil {
+		return
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_ = c.ws.WriteMessage(resp.messageType, resp.data)
+}
+
+func (c *wsConn) close() {
+	_ = c.ws.Close()
+}
+
+// serve records a request and writes the response of handle, or the response changed by a fault.
+// A nil response is not written.
+func (c *wsConn) serve(request *Request, handle func() *response) {
+	s := c.server
+	s.record(request)
+	f := s.findFault(request.Action, request.SQL)
+	if f == nil {
+		c.write(handle())
+		return
+	}
+	if f.Disconnect {
+		c.close()
+		return
+	}
+	var resp *response
+	if f.Code != 0 {
+		if request.Action == ActionFetchRawBlock {
+			resp = fetchRawBlockResponse(request.ReqID, 0, f.Code, f.Message, nil)
+		} else {
+			resp = errorResponse(request.Action, request.ReqID, f.Code, f.Message)
+		}
+	} else {
+		resp = handle()
+	}
+	if f.Drop {
+		return
+	}
+	if f.Delay > 0 {
+		go func() {
+			if s.sleep(f.Delay) {
+				c.write(resp)
+			}
+		}()
+		return
+	}
+	c.write(resp)
+}
+
+func (c *wsConn) handleText(message []byte) {
+	var action struct {
+		Action string          `json:"action"`
+		Args   json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(message, &action); err != nil {
+		return
+	}
+	var a args
+	if len(action.Args) != 0 {
+		if err := json.Unmarshal(action.Args, &a); err != nil {
+			c.write(errorResponse(action.Action, 0, 0xffff, err.Error()))
+			return
+		}
+	}
+	request := &Request{Action: action.Action, ReqID: a.ReqID, SQL: a.SQL, Args: action.Args}
+	if request.SQL == "" && a.StmtID != 0 {
+		request.SQL = c.server.stmtSQL(a.StmtID)
+	}
+	c.serve(request, func() *response {
+		return c.handleAction(request, &a)
+	})
+}
+
+func (c *wsConn) handleAction(request *Request, a *args) *response {
+	s := c.server
+	action := request.Action
+	switch action {
+	case ActionVersion:
+		return textResponse(action, 0, map[string]interface{}{"version": Version})
+	case ActionConn, ActionSchemalessInsert:
+		return textResponse(action, a.ReqID, nil)
+	case ActionFetch:
+		block, err := s.nextBlock(a.ID)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		if block == nil {
+			return textResponse(action, a.ReqID, map[string]interface{}{"id": a.ID, "completed": true})
+		}
+		rows := parser.RawBlockGetNumOfRows(unsafePointer(block))
+		return textResponse(action, a.ReqID, map[string]interface{}{"id": a.ID, "rows": rows})
+	case ActionFetchBlock:
+		block, err := s.currentBlock(a.ID)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		// timing, id and the block
+		data := make([]byte, 16, 16+len(block))
+		binary.LittleEndian.PutUint64(data[8:], a.ID)
+		return &response{messageType: websocket.BinaryMessage, data: append(data, block...)}
+	case ActionFreeResult:
+		s.freeResult(a.ID)
+		return nil
+	case ActionStmtInit, ActionStmt2Init:
+		return textResponse(action, a.ReqID, map[string]interface{}{"stmt_id": s.initStmt()})
+	case ActionStmtPrepare, ActionStmt2Prepare:
+		result, err := s.prepareStmt(a.StmtID, a.SQL)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		if result.Code != 0 {
+			return errorResponse(action, a.ReqID, result.Code, result.Message)
+		}
+		values := map[string]interface{}{"stmt_id": a.StmtID, "is_insert": result.isUpdate()}
+		if action == ActionStmt2Prepare {
+			fieldsCount := len(result.Fields)
+			if !result.isUpdate() {
+				fieldsCount = strings.Count(a.SQL, "?")
+			}
+			values["fields"] = result.Fields
+			values["fields_count"] = fieldsCount
+		}
+		return textResponse(action, a.ReqID, values)
+	case ActionStmtSetTableName, ActionStmtAddBatch:
+		if _, err := s.stmtResult(a.StmtID); err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		return textResponse(action, a.ReqID, map[string]interface{}{"stmt_id": a.StmtID})
+	case ActionStmtGetColFields:
+		result, err := s.stmtResult(a.StmtID)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		fields := make([]*stmtCommon.StmtField, 0, len(result.Fields))
+		for _, field := range result.Fields {
+			if field.BindType != stmtCommon.TAOS_FIELD_COL {
+				continue
+			}
+			fields = append(fields, &stmtCommon.StmtField{
+				Name:      field.Name,
+				FieldType: field.FieldType,
+				Precision: field.Precision,
+				Scale:     field.Scale,
+				Bytes:     field.Bytes,
+			})
+		}
+		return textResponse(action, a.ReqID, map[string]interface{}{"stmt_id": a.StmtID, "fields": fields})
+	case ActionStmtExec, ActionStmt2Exec:
+		result, err := s.stmtResult(a.StmtID)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		return textResponse(action, a.ReqID, map[string]interface{}{"stmt_id": a.StmtID, "affected": result.AffectedRows})
+	case ActionStmtUseResult, ActionStmt2Result:
+		result, err := s.stmtResult(a.StmtID)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		values, err := s.resultValues(result)
+		if err != nil {
+			return errorResponse(action, a.ReqID, 0xffff, err.Error())
+		}
+		values["stmt_id"] = a.StmtID
+		values["result_id"] = values["id"]
+		return textResponse(action, a.ReqID, values)
+	case ActionStmtClose:
+		s.closeStmt(a.StmtID)
+		return nil
+	case ActionStmt2Close:
+		s.closeStmt(a.StmtID)
+		return textResponse(action, a.ReqID, map[string]interface{}{"stmt_id": a.StmtID})
+	case ActionTMQSubscribe, ActionTMQPoll, ActionTMQFetchRaw, ActionTMQCommit, ActionTMQUnsubscribe,
+		ActionTMQAssignment, ActionTMQSeek, ActionTMQCommitOffset, ActionTMQCommitted, ActionTMQPosition:
+		return c.handleTMQ(request, a)
+	default:
+		return errorResponse(action, a.ReqID, 0xffff, "mockadapter: unknown action "+action)
+	}
+}
+
+// handleBinary handles binary requests, they start with req_id, an id and the message type.
+func (c *wsConn) handleBinary(message []byte) {
+	if len(message) < 24 {
+		return
+	}
+	s := c.server
+	reqID := binary.LittleEndian.Uint64(message)
+	id := binary.LittleEndian.Uint64(message[8:])
+	request := &Request{ReqID: reqID, Data: message[24:]}
+	switch binary.LittleEndian.Uint64(message[16:]) {
+	case binaryQueryMessage:
+		// version (uint16), sql length (uint32) and sql
+		if len(message) < 30 {
+			return
+		}
+		request.Action = ActionQuery
+		request.SQL = string(message[30:])
+		request.Data = nil
+		c.serve(request, func() *response {
+			return s.query(request)
+		})
+	case fetchRawBlockMessage:
+		request.Action = ActionFetchRawBlock
+		c.serve(request, func() *response {
+			block, err := s.nextBlock(id)
+			if err != nil {
+				return fetchRawBlockResponse(reqID, id, 0xffff, err.Error(), nil)
+			}
+			return fetchRawBlockResponse(reqID, id, 0, "", block)
+		})
+	case setTagsMessage, bindMessage, stmt2BindMessage:
+		switch binary.LittleEndian.Uint64(message[16:]) {
+		case setTagsMessage:
+			request.Action = ActionStmtSetTags
+		case bindMessage:
+			request.Action = ActionStmtBind
+		default:
+			// version (uint16) and column index (uint32)
+			request.Action = ActionStmt2Bind
+			if len(message) >= 30 {
+				request.Data = message[30:]
+			}
+		}
+		request.SQL = s.stmtSQL(id)
+		c.serve(request, func() *response {
+			if _, err := s.stmtResult(id); err != nil {
+				return errorResponse(request.Action, reqID, 0xffff, err.Error())
+			}
+			return textResponse(request.Action, reqID, map[string]interface{}{"stmt_id": id})
+		})
+	}
+}
+
+func (s *Server) query(request *Request) *response {
+	result := s.findResult(request.SQL)
+	if result.Code != 0 {
+		return errorResponse(request.Action, request.ReqID, result.Code, result.Message)
+	}
+	if result.isUpdate() {
+		return textResponse(request.Action, request.ReqID, map[string]interface{}{
+			"is_update":     true,
+			"affected_rows": result.AffectedRows,
+		})
+	}
+	values, err := s.resultValues(result)
+	if err != nil {
+		return errorResponse(request.Action, request.ReqID, 0xffff, err.Error())
+	}
+	return textResponse(request.Action, request.ReqID, values)
+}
+
+// resultValues opens a result for fetching and returns the fields of its response.
+func (s *Server) resultValues(result *Result) (map[string]interface{}, error) {
+	cols, err := result.columns()
+	if err != nil {
+		return nil, err
+	}
+	types := make([]int, len(cols.types))
+	for i, t := range cols.types {
+		types[i] = int(t)
+	}
+	return map[string]interface{}{
+		"id":                s.openResult(result),
+		"fields_count":      len(cols.names),
+		"fields_names":      cols.names,
+		"fields_types":      types,
+		"fields_lengths":    cols.lengths,
+		"precision":         result.Precision,
+		"fields_precisions": cols.precisions,
+		"fields_scales":     cols.scales,
+	}, nil
+}
+
+func textResponse(action string, reqID uint64, values map[string]interface{}) *response {
+	resp := map[string]interface{}{
+		"code":    0,
+		"message": "",
+		"action":  action,
+		"req_id":  reqID,
+		"timing":  0,
+	}
+	for key, value := range values {
+		resp[key] = value
+	}
+	data, _ := json.Marshal(resp)
+	return &response{messageType: websocket.TextMessage, data: data}
+}
+
+func errorResponse(action string, reqID uint64, code int, message string) *response {
+	return textResponse(action, reqID, map[string]interface{}{"code": code, "message": message})
+}
+
+// fetchRawBlockResponse is timing (uint64), req_id (uint64), version (uint16), message type (uint64),
+// result id (uint64), code (uint32), message length (uint32), message, result id (uint64), completed (bool)
+// and, if not completed, the block length (uint32) and the block.
+func fetchRawBlockResponse(reqID uint64, resultID uint64, code int, message string, block []byte) *response {
+	buf := &bytes.Buffer{}
+	writeUint64(buf, 0)
+	writeUint64(buf, reqID)
+	writeUint16(buf, 1)
+	writeUint64(buf, fetchRawBlockMessage)
+	writeUint64(buf, resultID)
+	writeUint32(buf, uint32(code))
+	writeUint32(buf, uint32(len(message)))
+	buf.WriteString(message)
+	writeUint64(buf, resultID)
+	if code != 0 || block == nil {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+		writeUint32(buf, uint32(len(block)))
+		buf.Write(block)
+	}
+	return &response{messageType: websocket.BinaryMessage, data: buf.Bytes()}
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint16(buf *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
@@ -0,0 +1,112 @@
+package taosmock
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+type conn struct {
+	mock *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement whose executions are matched like those of the connection.
+func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "taosmock does not support transaction"}
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e, err := c.mock.match(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	exec := e.(*ExpectedExec)
+	if exec.err != nil {
+		return nil, exec.err
+	}
+	if exec.result == nil {
+		return driver.RowsAffected(0), nil
+	}
+	return exec.result, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e, err := c.mock.match(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	q := e.(*ExpectedQuery)
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.rows == nil {
+		return nil, errors.New("taosmock: " + q.String() + " has no rows, set them with WillReturnRows")
+	}
+	return &rows{Rows: q.rows}, nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1, the arguments are checked by the expectations.
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
@@ -0,0 +1,273 @@
+// Package taosmock is a sqlmock-style driver.Connector for tests of code using database/sql with the TDengine
+// connectors. Tests declare the expected statements with regular expressions and their arguments, and the
+// returned rows with TDengine column types and timestamp precision. The connections are served in memory and
+// the rows are introspected like the rows of taosWS.
+//
+//	connector, mock := taosmock.NewConnector()
+//	db := sql.OpenDB(connector)
+//	mock.ExpectQuery(`^select \* from t where v = \?`).WithArgs(1).WillReturnRows(
+//		taosmock.NewRows(
+//			taosmock.Column{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP},
+//			taosmock.Column{Name: "d", Type: common.TSDB_DATA_TYPE_DECIMAL, Precision: 10, Scale: 2},
+//		).AddRow(time.Now(), "1.50"),
+//	)
+package taosmock
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Connector is a driver.Connector whose connections are served by a Mock.
+type Connector struct {
+	mock *Mock
+}
+
+// NewConnector returns a connector and the mock of its connections, the connector is opened with sql.OpenDB.
+func NewConnector() (*Connector, *Mock) {
+	mock := &Mock{ordered: true}
+	return &Connector{mock: mock}, mock
+}
+
+// Connect returns a new connection, all the connections share the expectations of the mock.
+func (c *Connector) Connect(_ context.Context) (driver.Conn, error) {
+	return &conn{mock: c.mock}, nil
+}
+
+// Driver returns a driver whose Open ignores the DSN and returns a connection of the connector.
+func (c *Connector) Driver() driver.Driver {
+	return &mockDriver{connector: c}
+}
+
+type mockDriver struct {
+	connector *Connector
+}
+
+func (d *mockDriver) Open(_ string) (driver.Conn, error) {
+	return d.connector.Connect(context.Background())
+}
+
+// Mock holds the expected statements of the connections of a Connector.
+type Mock struct {
+	lock     sync.Mutex
+	ordered  bool
+	expected []expectation
+}
+
+// MatchExpectationsInOrder sets whether the statements must be executed in the order of the expectations,
+// which is the default. Unordered expectations are matched by the first unfulfilled one.
+func (m *Mock) MatchExpectationsInOrder(ordered bool) {
+	m.lock.Lock()
+	m.ordered = ordered
+	m.lock.Unlock()
+}
+
+// ExpectQuery expects a query whose SQL matches the regular expression sqlRegex. It panics if sqlRegex is not
+// a valid regular expression.
+func (m *Mock) ExpectQuery(sqlRegex string) *ExpectedQuery {
+	e := &ExpectedQuery{base: newBase(kindQuery, sqlRegex)}
+	m.expect(e)
+	return e
+}
+
+// ExpectExec expects a statement that returns no rows, such as an insert, whose SQL matches the regular
+// expression sqlRegex. It panics if sqlRegex is not a valid regular expression.
+func (m *Mock) ExpectExec(sqlRegex string) *ExpectedExec {
+	e := &ExpectedExec{base: newBase(kindExec, sqlRegex)}
+	m.expect(e)
+	return e
+}
+
+func (m *Mock) expect(e expectation) {
+	m.lock.Lock()
+	m.expected = append(m.expected, e)
+	m.lock.Unlock()
+}
+
+// ExpectationsWereMet returns an error describing the first expectation that has not been fulfilled.
+func (m *Mock) ExpectationsWereMet() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, e := range m.expected {
+		if !e.common().triggered {
+			return fmt.Errorf("taosmock: there is a remaining expectation which was not matched: %s", e)
+		}
+	}
+	return nil
+}
+
+// match marks the expectation of a statement as fulfilled and returns it.
+func (m *Mock) match(kind string, query string, args []driver.NamedValue) (expectation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, e := range m.expected {
+		b := e.common()
+		if b.triggered {
+			continue
+		}
+		if b.kind == kind && b.sql.MatchString(query) && b.argsMatch(args) {
+			b.triggered = true
+			return e, nil
+		}
+		if m.ordered {
+			return nil, fmt.Errorf("taosmock: %s %q with args %s was not expected, next expectation is: %s", kind, query, formatArgs(args), e)
+		}
+	}
+	return nil, fmt.Errorf("taosmock: %s %q with args %s was not expected, all expectations were already fulfilled", kind, query, formatArgs(args))
+}
+
+const (
+	kindQuery = "query"
+	kindExec  = "exec"
+)
+
+type expectation interface {
+	fmt.Stringer
+	common() *base
+}
+
+// base is the part of the expectations shared by queries and execs.
+type base struct {
+	kind      string
+	sql       *regexp.Regexp
+	args      []interface{}
+	hasArgs   bool
+	err       error
+	triggered bool
+}
+
+func newBase(kind string, sqlRegex string) base {
+	return base{kind: kind, sql: regexp.MustCompile(sqlRegex)}
+}
+
+func (b *base) common() *base {
+	return b
+}
+
+// withArgs sets the expected arguments, the values are converted like database/sql converts the arguments.
+func (b *base) withArgs(args []interface{}) {
+	b.args = make([]interface{}, len(args))
+	for i, arg := range args {
+		if _, ok := arg.(Argument); ok {
+			b.args[i] = arg
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			panic(fmt.Sprintf("taosmock: invalid argument %d of %s: %s", i, b.sql, err))
+		}
+		b.args[i] = v
+	}
+	b.hasArgs = true
+}
+
+// argsMatch reports whether args are the expected arguments, any arguments match if none are expected.
+func (b *base) argsMatch(args []driver.NamedValue) bool {
+	if !b.hasArgs {
+		return true
+	}
+	if len(args) != len(b.args) {
+		return false
+	}
+	for i, arg := range args {
+		if matcher, ok := b.args[i].(Argument); ok {
+			if !matcher.Match(arg.Value) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(b.args[i], arg.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *base) String() string {
+	s := fmt.Sprintf("%s matching %q", b.kind, b.sql.String())
+	if b.hasArgs {
+		s += fmt.Sprintf(" with args %v", b.args)
+	}
+	return s
+}
+
+// ExpectedQuery is an expected query.
+type ExpectedQuery struct {
+	base
+	rows *Rows
+}
+
+// WithArgs sets the expected arguments of the query, an Argument matches any argument it accepts.
+func (e *ExpectedQuery) WithArgs(args ...interface{}) *ExpectedQuery {
+	e.withArgs(args)
+	return e
+}
+
+// WillReturnRows sets the rows returned by the query.
+func (e *ExpectedQuery) WillReturnRows(rows *Rows) *ExpectedQuery {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError sets the error returned by the query, such as a *errors.TaosError.
+func (e *ExpectedQuery) WillReturnError(err error) *ExpectedQuery {
+	e.err = err
+	return e
+}
+
+// ExpectedExec is an expected statement that returns no rows.
+type ExpectedExec struct {
+	base
+	result driver.Result
+}
+
+// WithArgs sets the expected arguments of the statement, an Argument matches any argument it accepts.
+func (e *ExpectedExec) WithArgs(args ...interface{}) *ExpectedExec {
+	e.withArgs(args)
+	return e
+}
+
+// WillReturnResult sets the result of the statement, such as driver.RowsAffected(1) as returned by taosWS.
+func (e *ExpectedExec) WillReturnResult(result driver.Result) *ExpectedExec {
+	e.result = result
+	return e
+}
+
+// WillReturnError sets the error returned by the statement, such as a *errors.TaosError.
+func (e *ExpectedExec) WillReturnError(err error) *ExpectedExec {
+	e.err = err
+	return e
+}
+
+// Argument matches an argument of a statement.
+type Argument interface {
+	Match(driver.Value) bool
+}
+
+type anyArgument struct{}
+
+func (anyArgument) Match(_ driver.Value) bool {
+	return true
+}
+
+func (anyArgument) String() string {
+	return "<any>"
+}
+
+// AnyArg returns an Argument that matches any argument.
+func AnyArg() Argument {
+	return anyArgument{}
+}
+
+func formatArgs(args []driver.NamedValue) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = fmt.Sprintf("%v", arg.Value)
+	}
+	return "[" + strings.Join(values, " ") + "]"
+}
@@ -0,0 +1,243 @@
+package taosmock
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// Column describes a column of the rows returned by a query.
+type Column struct {
+	Name string
+	// Type is one of common.TSDB_DATA_TYPE_*
+	Type uint8
+	// Length is the length of a variable length column
+	Length int64
+	// Precision and Scale of a DECIMAL or DECIMAL64 column
+	Precision int64
+	Scale     int64
+}
+
+// Rows are the rows returned by an expected query.
+type Rows struct {
+	columns   []Column
+	names     []string
+	precision int
+	location  *time.Location
+	values    [][]driver.Value
+}
+
+// NewRows returns empty rows of columns, the timestamps have common.PrecisionMilliSecond.
+func NewRows(columns ...Column) *Rows {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return &Rows{columns: columns, names: names, precision: common.PrecisionMilliSecond}
+}
+
+// WithPrecision sets the precision of the timestamps, one of common.PrecisionMilliSecond,
+// common.PrecisionMicroSecond and common.PrecisionNanoSecond. It must be set before the rows are added.
+func (r *Rows) WithPrecision(precision int) *Rows {
+	r.precision = precision
+	return r
+}
+
+// WithTimezone sets the location of the timestamps, like the timezone of the DSN. It must be set before the
+// rows are added.
+func (r *Rows) WithTimezone(location *time.Location) *Rows {
+	r.location = location
+	return r
+}
+
+// AddRow adds a row. The values are converted to the values returned by the connectors for the column types:
+// time.Time truncated to the precision for TIMESTAMP, given as a time.Time or a timestamp of the precision,
+// string for VARCHAR, NCHAR and DECIMAL, []byte for JSON, VARBINARY, GEOMETRY and BLOB, and the Go type of
+// the size of the numeric types. nil is NULL. It panics if a value does not fit its column.
+func (r *Rows) AddRow(values ...driver.Value) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf("taosmock: %d values for %d columns", len(values), len(r.columns)))
+	}
+	row := make([]driver.Value, len(values))
+	for i, value := range values {
+		v, err := r.convert(r.columns[i].Type, value)
+		if err != nil {
+			panic(fmt.Sprintf("taosmock: value %d of row %d: %s", i, len(r.values), err))
+		}
+		row[i] = v
+	}
+	r.values = append(r.values, row)
+	return r
+}
+
+func (r *Rows) convert(colType uint8, value driver.Value) (driver.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch colType {
+	case common.TSDB_DATA_TYPE_BOOL:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case common.TSDB_DATA_TYPE_TINYINT:
+		v, err := signed(value, 8)
+		return int8(v), err
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		v, err := signed(value, 16)
+		return int16(v), err
+	case common.TSDB_DATA_TYPE_INT:
+		v, err := signed(value, 32)
+		return int32(v), err
+	case common.TSDB_DATA_TYPE_BIGINT:
+		return signed(value, 64)
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		v, err := unsigned(value, 8)
+		return uint8(v), err
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		v, err := unsigned(value, 16)
+		return uint16(v), err
+	case common.TSDB_DATA_TYPE_UINT:
+		v, err := unsigned(value, 32)
+		return uint32(v), err
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		return unsigned(value, 64)
+	case common.TSDB_DATA_TYPE_FLOAT:
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			return float32(v.Float()), nil
+		}
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+			return v.Float(), nil
+		}
+	case common.TSDB_DATA_TYPE_BINARY, common.TSDB_DATA_TYPE_NCHAR:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	case common.TSDB_DATA_TYPE_JSON, common.TSDB_DATA_TYPE_VARBINARY, common.TSDB_DATA_TYPE_GEOMETRY,
+		common.TSDB_DATA_TYPE_BLOB:
+		switch v := value.(type) {
+		case string:
+			return []byte(v), nil
+		case []byte:
+			return append([]byte{}, v...), nil
+		}
+	case common.TSDB_DATA_TYPE_DECIMAL, common.TSDB_DATA_TYPE_DECIMAL64:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		switch v := value.(type) {
+		case time.Time:
+			return r.formatTime(common.TimeToTimestamp(v, r.precision)), nil
+		case int64:
+			return r.formatTime(v), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported column type %d", colType)
+	}
+	return nil, fmt.Errorf("%T is not a valid value of %s", value, common.GetTypeName(int(colType)))
+}
+
+func (r *Rows) formatTime(ts int64) time.Time {
+	if r.location == nil {
+		return common.TimestampConvertToTime(ts, r.precision)
+	}
+	return common.TimestampConvertToTimeWithLocation(ts, r.precision, r.location)
+}
+
+// signed converts an integer to an int64 that fits in bits.
+func signed(value driver.Value, bits uint) (int64, error) {
+	v := reflect.ValueOf(value)
+	var n int64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > 1<<63-1 {
+			return 0, fmt.Errorf("%d overflows int%d", v.Uint(), bits)
+		}
+		n = int64(v.Uint())
+	default:
+		return 0, fmt.Errorf("%T is not an integer", value)
+	}
+	if bits < 64 && (n < -1<<(bits-1) || n > 1<<(bits-1)-1) {
+		return 0, fmt.Errorf("%d overflows int%d", n, bits)
+	}
+	return n, nil
+}
+
+// unsigned converts an integer to a uint64 that fits in bits.
+func unsigned(value driver.Value, bits uint) (uint64, error) {
+	v := reflect.ValueOf(value)
+	var n uint64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, fmt.Errorf("%d overflows uint%d", v.Int(), bits)
+		}
+		n = uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = v.Uint()
+	default:
+		return 0, fmt.Errorf("%T is not an integer", value)
+	}
+	if bits < 64 && n > 1<<bits-1 {
+		return 0, fmt.Errorf("%d overflows uint%d", n, bits)
+	}
+	return n, nil
+}
+
+// rows iterates the Rows of a query, the column types are introspected like the rows of taosWS.
+type rows struct {
+	*Rows
+	next int
+}
+
+func (rs *rows) Columns() []string {
+	return rs.names
+}
+
+func (rs *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	column := rs.columns[index]
+	if column.Type == common.TSDB_DATA_TYPE_DECIMAL || column.Type == common.TSDB_DATA_TYPE_DECIMAL64 {
+		return column.Precision, column.Scale, true
+	}
+	return 0, 0, false
+}
+
+func (rs *rows) ColumnTypeDatabaseTypeName(i int) string {
+	return common.GetTypeName(int(rs.columns[i].Type))
+}
+
+func (rs *rows) ColumnTypeLength(i int) (length int64, ok bool) {
+	return rs.columns[i].Length, ok
+}
+
+func (rs *rows) ColumnTypeScanType(i int) reflect.Type {
+	t, exist := common.ColumnTypeMap[int(rs.columns[i].Type)]
+	if !exist {
+		return common.UnknownType
+	}
+	return t
+}
+
+func (rs *rows) Close() error {
+	rs.next = len(rs.values)
+	return nil
+}
+
+func (rs *rows) Next(dest []driver.Value) error {
+	if rs.next >= len(rs.values) {
+		return io.EOF
+	}
+	copy(dest, rs.values[rs.next])
+	rs.next++
+	return nil
+}
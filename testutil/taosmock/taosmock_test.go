@@ -0,0 +1,158 @@
+package taosmock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+func newTestDB(t *testing.T) (*sql.DB, *Mock) {
+	connector, mock := NewConnector()
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db, mock
+}
+
+func TestQuery(t *testing.T) {
+	db, mock := newTestDB(t)
+	now := time.Now()
+	point := []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40}
+	mock.ExpectQuery(`^select \* from t where v > \?$`).WithArgs(1).WillReturnRows(
+		NewRows(
+			Column{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+			Column{Name: "v", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+			Column{Name: "d", Type: common.TSDB_DATA_TYPE_DECIMAL64, Length: 8, Precision: 10, Scale: 2},
+			Column{Name: "g", Type: common.TSDB_DATA_TYPE_GEOMETRY, Length: 100},
+			Column{Name: "b", Type: common.TSDB_DATA_TYPE_VARBINARY, Length: 10},
+			Column{Name: "u", Type: common.TSDB_DATA_TYPE_UTINYINT, Length: 1},
+		).
+			WithPrecision(common.PrecisionMicroSecond).
+			AddRow(now, 2, "1.50", point, "\x00\x01", 255).
+			AddRow(now.UnixNano()/1e3+1, nil, nil, nil, nil, nil),
+	)
+	rows, err := db.Query("select * from t where v > ?", 1)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, rows.Close())
+	}()
+	names, err := rows.Columns()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ts", "v", "d", "g", "b", "u"}, names)
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, "TIMESTAMP", types[0].DatabaseTypeName())
+	assert.Equal(t, "DECIMAL", types[2].DatabaseTypeName())
+	assert.Equal(t, "GEOMETRY", types[3].DatabaseTypeName())
+	assert.Equal(t, "VARBINARY", types[4].DatabaseTypeName())
+	assert.Equal(t, "TINYINT UNSIGNED", types[5].DatabaseTypeName())
+	precision, scale, ok := types[2].DecimalSize()
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), precision)
+	assert.Equal(t, int64(2), scale)
+	_, _, ok = types[1].DecimalSize()
+	assert.False(t, ok)
+	_, ok = types[4].Length()
+	assert.False(t, ok)
+	assert.Equal(t, common.NullInt32, types[1].ScanType())
+	assert.Equal(t, common.NullString, types[2].ScanType())
+
+	var values [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, 6)
+		dest := make([]interface{}, 6)
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		values = append(values, row)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, 2, len(values))
+	ts := now.Truncate(time.Microsecond)
+	assert.Equal(t, []interface{}{ts, int32(2), "1.50", point, []byte{0, 1}, uint8(255)}, values[0])
+	assert.Equal(t, []interface{}{ts.Add(time.Microsecond), nil, nil, nil, nil, nil}, values[1])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExec(t *testing.T) {
+	db, mock := newTestDB(t)
+	mock.ExpectExec(`^insert into t values`).WithArgs(AnyArg(), int32(1)).WillReturnResult(driver.RowsAffected(2))
+	mock.ExpectExec(`^insert into t values`).WillReturnError(&taosErrors.TaosError{Code: 0x2603, ErrStr: "Table does not exist"})
+	result, err := db.Exec("insert into t values(?, ?)", time.Now(), 1)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	_, err = db.Exec("insert into t values(now, 1)")
+	assert.Equal(t, &taosErrors.TaosError{Code: 0x2603, ErrStr: "Table does not exist"}, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrepare(t *testing.T) {
+	db, mock := newTestDB(t)
+	mock.ExpectExec(`^insert into t values\(\?\)$`).WithArgs("v1").WillReturnResult(driver.RowsAffected(1))
+	mock.ExpectQuery(`^select v from t$`).WillReturnRows(NewRows(Column{Name: "v", Type: common.TSDB_DATA_TYPE_NCHAR}).AddRow("v1"))
+	stmt, err := db.Prepare("insert into t values(?)")
+	require.NoError(t, err)
+	_, err = stmt.Exec("v1")
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+	var v string
+	require.NoError(t, db.QueryRow("select v from t").Scan(&v))
+	assert.Equal(t, "v1", v)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnexpected(t *testing.T) {
+	db, mock := newTestDB(t)
+	mock.ExpectExec(`^insert`).WithArgs(1)
+	mock.ExpectQuery(`^select`).WillReturnRows(NewRows(Column{Name: "v", Type: common.TSDB_DATA_TYPE_INT}))
+	_, err := db.Query("select * from t")
+	assert.Error(t, err)
+	_, err = db.Exec("insert into t values(?)", 2)
+	assert.Error(t, err)
+	assert.Error(t, mock.ExpectationsWereMet())
+
+	mock.MatchExpectationsInOrder(false)
+	rows, err := db.Query("select * from t")
+	require.NoError(t, err)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Close())
+	_, err = db.Exec("insert into t values(?)", 1)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	_, err = db.Exec("insert into t values(?)", 1)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.ExecContext(ctx, "insert into t values(?)", 1)
+	assert.Equal(t, context.Canceled, err)
+	_, err = db.Begin()
+	assert.Error(t, err)
+}
+
+func TestAddRow(t *testing.T) {
+	rows := NewRows(
+		Column{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP},
+		Column{Name: "v", Type: common.TSDB_DATA_TYPE_SMALLINT},
+	).WithPrecision(common.PrecisionNanoSecond).WithTimezone(time.UTC)
+	rows.AddRow(int64(1626006833639000001), int8(-1))
+	assert.Equal(t, time.Unix(0, 1626006833639000001).UTC(), rows.values[0][0])
+	assert.Equal(t, int16(-1), rows.values[0][1])
+	assert.Panics(t, func() { rows.AddRow(time.Now()) })
+	assert.Panics(t, func() { rows.AddRow(time.Now(), 1<<15) })
+	assert.Panics(t, func() { rows.AddRow(time.Now(), "1") })
+	assert.Panics(t, func() {
+		NewRows(Column{Name: "v", Type: common.TSDB_DATA_TYPE_UINT}).AddRow(-1)
+	})
+}
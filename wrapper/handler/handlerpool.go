@@ -3,8 +3,10 @@ package handler
 import (
 	"container/list"
 	"sync"
+	"time"
 	"unsafe"
 
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/wrapper/cgo"
 )
 
@@ -76,11 +78,13 @@ func (c *HandlerPool) Get() *Handler {
 		case wrapConn := <-c.handlers:
 			return wrapConn
 		default:
+			start := time.Now()
 			c.mu.Lock()
 			req := make(chan poolReq, 1)
 			c.reqList.PushBack(req)
 			c.mu.Unlock()
 			ret := <-req
+			metrics.GetMetrics().ObservePoolWait(metrics.PoolHandler, time.Since(start))
 			return ret.idleHandler
 		}
 	}
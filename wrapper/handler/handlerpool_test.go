@@ -3,6 +3,9 @@ package handler
 import (
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 )
 
 func BenchmarkName(b *testing.B) {
@@ -74,3 +77,20 @@ func TestCaller_FetchCall(t *testing.T) {
 	caller := NewCaller()
 	caller.FetchCall(nil, 0)
 }
+
+func TestHandlerPoolWaitMetrics(t *testing.T) {
+	m := metrics.NewMemory()
+	metrics.SetMetrics(m)
+	defer metrics.SetMetrics(nil)
+	pool := NewHandlerPool(1)
+	h := pool.Get()
+	assert.Empty(t, m.Snapshot().PoolWaits)
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		pool.Put(h)
+	}()
+	pool.Put(pool.Get())
+	wait := m.Snapshot().PoolWaits[metrics.PoolHandler]
+	assert.Equal(t, uint64(1), wait.Count)
+	assert.True(t, wait.Max >= time.Millisecond*10)
+}
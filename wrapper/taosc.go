@@ -46,6 +46,16 @@ func TaosFreeResult(res unsafe.Pointer) {
 	C.taos_free_result(res)
 }
 
+// TaosStopQuery void taos_stop_query(TAOS_RES *res);
+func TaosStopQuery(res unsafe.Pointer) {
+	C.taos_stop_query(res)
+}
+
+// TaosKillQuery void taos_kill_query(TAOS *taos);
+func TaosKillQuery(taosConnect unsafe.Pointer) {
+	C.taos_kill_query(taosConnect)
+}
+
 // TaosConnect TAOS *taos_connect(const char *ip, const char *user, const char *pass, const char *db, uint16_t port);
 func TaosConnect(host, user, pass, db string, port int) (taos unsafe.Pointer, err error) {
 	cUser := C.CString(user)
@@ -1,5 +1,11 @@
 package thread
 
+import (
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common/metrics"
+)
+
 type Locker struct {
 	c chan struct{}
 }
@@ -11,7 +17,15 @@ func NewLocker(count int) *Locker {
 }
 
 func (l *Locker) Lock() {
+	select {
+	case l.c <- nothing:
+		return
+	default:
+	}
+	// all threads are busy
+	start := time.Now()
 	l.c <- nothing
+	metrics.GetMetrics().ObservePoolWait(metrics.PoolLocker, time.Since(start))
 }
 
 func (l *Locker) Unlock() {
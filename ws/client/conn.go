@@ -12,6 +12,8 @@ import (
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	errors2 "github.com/taosdata/driver-go/v3/errors"
 )
 
@@ -77,6 +79,7 @@ type Client struct {
 	TextMessageHandler   func(message []byte)
 	BinaryMessageHandler func(message []byte)
 	ErrorHandler         func(err error)
+	Logger               log.Logger
 	// SendMessageHandler   func(envelope *Envelope)
 	once           sync.Once
 	errHandlerOnce sync.Once
@@ -94,6 +97,7 @@ func NewClient(conn *websocket.Conn, sendChanLength uint) *Client {
 		TextMessageHandler:   func(message []byte) {},
 		BinaryMessageHandler: func(message []byte) {},
 		ErrorHandler:         func(err error) {},
+		Logger:               log.GetLogger(),
 	}
 }
 
@@ -109,8 +113,12 @@ func (c *Client) ReadPump() {
 		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if e, ok := err.(*websocket.CloseError); ok && e.Code == websocket.CloseAbnormalClosure {
+				c.Logger.Debug("websocket closed", "remote_addr", c.remoteAddr(), "error", err)
 				break
 			}
+			if c.IsRunning() {
+				c.Logger.Warn("websocket read error", "remote_addr", c.remoteAddr(), "error", err)
+			}
 			c.handleError(err)
 			break
 		}
@@ -119,6 +127,8 @@ func (c *Client) ReadPump() {
 			go c.TextMessageHandler(message)
 		case websocket.BinaryMessage:
 			go c.BinaryMessageHandler(message)
+		default:
+			c.Logger.Debug("drop websocket frame", "remote_addr", c.remoteAddr(), "message_type", messageType, "length", len(message))
 		}
 	}
 }
@@ -139,9 +149,11 @@ func (c *Client) WritePump() {
 				message.ErrorChan <- ClosedError
 				continue
 			}
+			metrics.GetMetrics().AddSendQueue(-1)
 			_ = c.conn.SetWriteDeadline(time.Now().Add(c.WriteWait))
 			err := c.conn.WriteMessage(message.Type, message.Msg.Bytes())
 			if err != nil {
+				c.Logger.Warn("websocket write error", "remote_addr", c.remoteAddr(), "error", err)
 				message.ErrorChan <- err
 				c.handleError(err)
 				c.Close()
@@ -149,6 +161,7 @@ func (c *Client) WritePump() {
 					if message == nil {
 						return
 					}
+					metrics.GetMetrics().AddSendQueue(-1)
 					message.ErrorChan <- ClosedError
 				}
 			}
@@ -156,12 +169,14 @@ func (c *Client) WritePump() {
 		case <-ticker.C:
 			_ = c.conn.SetWriteDeadline(time.Now().Add(c.WriteWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Logger.Warn("websocket ping error", "remote_addr", c.remoteAddr(), "error", err)
 				c.handleError(err)
 				c.Close()
 				for message := range c.sendChan {
 					if message == nil {
 						return
 					}
+					metrics.GetMetrics().AddSendQueue(-1)
 					message.ErrorChan <- ClosedError
 				}
 			}
@@ -173,9 +188,12 @@ func (c *Client) Send(envelope *Envelope) (err error) {
 	if !c.IsRunning() {
 		return ClosedError
 	}
+	// counted before it is queued so that the write pump never takes it out first
+	metrics.GetMetrics().AddSendQueue(1)
 	defer func() {
 		// maybe closed
 		if recover() != nil {
+			metrics.GetMetrics().AddSendQueue(-1)
 			err = ClosedError
 			return
 		}
@@ -206,6 +224,13 @@ func (c *Client) Close() {
 	})
 }
 
+func (c *Client) remoteAddr() string {
+	if c.conn == nil || c.conn.RemoteAddr() == nil {
+		return ""
+	}
+	return c.conn.RemoteAddr().String()
+}
+
 func (c *Client) handleError(err error) {
 	c.errHandlerOnce.Do(func() { c.ErrorHandler(err) })
 }
@@ -2,6 +2,8 @@ package schemaless
 
 import (
 	"time"
+
+	"github.com/taosdata/driver-go/v3/common/log"
 )
 
 const (
@@ -24,6 +26,7 @@ type Config struct {
 	autoReconnect       bool
 	reconnectIntervalMs int
 	reconnectRetryCount int
+	logger              log.Logger
 }
 
 func NewConfig(url string, chanLength uint, opts ...func(*Config)) *Config {
@@ -106,3 +109,10 @@ func SetBearerToken(bearerToken string) func(*Config) {
 		c.bearerToken = bearerToken
 	}
 }
+
+// SetLogger sets the logger of the schemaless connection, the package-level logger of common/log is used if it is not set.
+func SetLogger(logger log.Logger) func(*Config) {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
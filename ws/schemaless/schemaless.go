@@ -13,7 +13,11 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	schemalessCommon "github.com/taosdata/driver-go/v3/common/schemaless"
 	"github.com/taosdata/driver-go/v3/common/tdversion"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
@@ -25,6 +29,7 @@ const (
 
 type Schemaless struct {
 	client              *client.Client
+	logger              log.Logger
 	sendList            *list.List
 	url                 string
 	user                string
@@ -54,10 +59,12 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 		return nil, errors.New("config url scheme error")
 	}
 	wsUrl.Path = "/ws"
+	logger := log.Or(config.logger)
 	dialer := common.DefaultDialer
 	dialer.EnableCompression = config.enableCompression
 	conn, _, err := dialer.Dial(wsUrl.String(), nil)
 	if err != nil {
+		logger.Warn("dial failed", "url", wsUrl.Host, "error", err)
 		return nil, fmt.Errorf("dial ws error: %s", err)
 	}
 	conn.EnableWriteCompression(config.enableCompression)
@@ -67,6 +74,7 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 	}
 	s := Schemaless{
 		client:       client.NewClient(conn, config.chanLength),
+		logger:       logger,
 		sendList:     list.New(),
 		url:          wsUrl.String(),
 		user:         config.user,
@@ -95,9 +103,11 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 	}
 
 	if err = connect(conn, s.user, s.password, s.db, s.totpCode, s.bearerToken, s.writeTimeout, s.readTimeout); err != nil {
+		logger.Warn("connect failed", "url", wsUrl.Host, "error", err)
 		return nil, fmt.Errorf("connect ws error: %s", err)
 	}
 	s.initClient(s.client)
+	logger.Info("connection opened", "url", wsUrl.Host)
 
 	return &s, nil
 }
@@ -108,6 +118,7 @@ func (s *Schemaless) initClient(c *client.Client) {
 	}
 	c.ErrorHandler = s.handleError
 	c.TextMessageHandler = s.handleTextMessage
+	c.Logger = s.logger
 
 	go c.ReadPump()
 	go c.WritePump()
@@ -115,18 +126,22 @@ func (s *Schemaless) initClient(c *client.Client) {
 
 func (s *Schemaless) reconnect() error {
 	reconnected := false
+	s.logger.Warn("reconnecting", "url", s.url)
 	for i := 0; i < s.reconnectRetryCount; i++ {
 		time.Sleep(time.Duration(s.reconnectIntervalMs) * time.Millisecond)
 		conn, _, err := s.dialer.Dial(s.url, nil)
 		if err != nil {
+			s.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			continue
 		}
 		conn.EnableWriteCompression(s.dialer.EnableCompression)
 		if err = connect(conn, s.user, s.password, s.db, s.totpCode, s.bearerToken, s.writeTimeout, s.readTimeout); err != nil {
+			s.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			_ = conn.Close()
 			continue
 		}
-		if tdversion.WSCheckVersion(conn) != nil {
+		if err = tdversion.WSCheckVersion(conn); err != nil {
+			s.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			_ = conn.Close()
 			continue
 		}
@@ -136,10 +151,13 @@ func (s *Schemaless) reconnect() error {
 		c := client.NewClient(conn, s.chanLength)
 		s.initClient(c)
 		s.client = c
+		s.logger.Info("reconnected", "attempts", i+1)
+		metrics.GetMetrics().AddReconnect()
 		reconnected = true
 		break
 	}
 	if !reconnected {
+		s.logger.Error("reconnect failed", "url", s.url)
 		if s.client != nil {
 			s.client.Close()
 		}
@@ -148,10 +166,15 @@ func (s *Schemaless) reconnect() error {
 	return nil
 }
 
-func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl int, reqID int64) error {
+func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl int, reqID int64) (err error) {
+	ctx, span := trace.Start(context.Background(), trace.SpanSchemalessInsert, trace.Bytes(len(lines)))
+	defer func() {
+		trace.End(span, err)
+	}()
 	if reqID == 0 {
-		reqID = common.GetReqID()
+		reqID = trace.NewReqID(ctx)
 	}
+	span.SetAttributes(trace.ReqID(reqID))
 	req := &schemalessReq{
 		ReqID:     uint64(reqID),
 		DB:        s.db,
@@ -172,6 +195,9 @@ func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl in
 	if err != nil {
 		return err
 	}
+	s.logger.Debug("schemaless insert", "req_id", uint64(reqID), "protocol", protocol)
+	start := time.Now()
+	defer log.Slow(s.logger, "schemaless insert", uint64(reqID), start)
 	respBytes, err := s.sendText(uint64(reqID), envelope)
 	if err != nil {
 		if !s.autoReconnect {
@@ -193,9 +219,31 @@ func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl in
 	}
 	var resp schemalessResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
+	if err != nil {
+		s.logger.Error("protocol error", "req_id", uint64(reqID), "error", err)
+	}
 	return client.HandleResponseError(err, resp.Code, resp.Message)
 }
 
+// InsertPoints serializes points with protocol and inserts them.
+func (s *Schemaless) InsertPoints(points []*schemalessCommon.Point, protocol int, precision string, ttl int, reqID int64) error {
+	lines, err := schemalessCommon.MarshalPoints(points, protocol, precision)
+	if err != nil {
+		return err
+	}
+	return s.Insert(lines, protocol, precision, ttl, reqID)
+}
+
+// NewAsyncWriter creates an AsyncWriter which batches schemaless lines of protocol and inserts them with Insert,
+// the points written with WritePoint are serialized with precision.
+func (s *Schemaless) NewAsyncWriter(protocol int, precision string, ttl int, opts ...func(*schemalessCommon.AsyncConfig)) *schemalessCommon.AsyncWriter {
+	// the precision of the inserts overrides the one of opts
+	opts = append(opts[:len(opts):len(opts)], schemalessCommon.SetPrecision(precision))
+	return schemalessCommon.NewAsyncWriter(func(lines string, reqID int64) error {
+		return s.Insert(lines, protocol, precision, ttl, reqID)
+	}, protocol, opts...)
+}
+
 func (s *Schemaless) Close() {
 	s.once.Do(func() {
 		close(s.closeChan)
@@ -203,6 +251,7 @@ func (s *Schemaless) Close() {
 			s.client.Close()
 		}
 		s.client = nil
+		s.logger.Info("connection closed", "url", s.url)
 	})
 }
 
@@ -334,7 +383,9 @@ func (s *Schemaless) handleTextMessage(message []byte) {
 	if element != nil {
 		element.Value.(*IndexedChan).channel <- message
 		s.sendList.Remove(element)
+		return
 	}
+	s.logger.Debug("drop response", "req_id", reqID)
 }
 
 func (s *Schemaless) findOutChanByID(index uint64) *list.Element {
@@ -359,6 +410,7 @@ func (s *Schemaless) findOutChanByID(index uint64) *list.Element {
 }
 
 func (s *Schemaless) handleError(err error) {
+	s.logger.Warn("websocket error", "url", s.url, "error", err)
 	if s.errorHandler != nil {
 		s.errorHandler(err)
 	}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"strings"
 	"time"
+
+	"github.com/taosdata/driver-go/v3/common/log"
 )
 
 type Config struct {
@@ -23,6 +25,7 @@ type Config struct {
 	ReconnectRetryCount int
 	TotpCode            string
 	BearerToken         string
+	Logger              log.Logger // the package-level logger of common/log is used if nil
 }
 
 func NewConfig(url string, chanLength uint) *Config {
@@ -10,12 +10,15 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/tdversion"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
 type Connector struct {
 	client              *WSConn
+	logger              log.Logger
 	writeTimeout        time.Duration
 	readTimeout         time.Duration
 	config              *Config
@@ -60,8 +63,10 @@ func NewConnector(config *Config) (*Connector, error) {
 		return nil, err
 	}
 	u.Path = "/ws"
+	logger := log.Or(config.Logger)
 	ws, _, err := dialer.Dial(u.String(), nil)
 	if err != nil {
+		logger.Warn("dial failed", "url", u.Host, "error", err)
 		return nil, err
 	}
 	ws.EnableWriteCompression(config.EnableCompression)
@@ -79,12 +84,15 @@ func NewConnector(config *Config) (*Connector, error) {
 	}
 	err = connect(ws, config.User, config.Password, config.DB, config.TotpCode, config.BearerToken, writeTimeout, readTimeout, config.Timezone)
 	if err != nil {
+		logger.Warn("connect failed", "url", u.Host, "error", err)
 		return nil, err
 	}
 	wsClient := client.NewClient(ws, config.ChanLength)
+	wsClient.Logger = logger
 	wsConn := NewWSConn(wsClient, writeTimeout, readTimeout)
 	connector = &Connector{
 		client:              wsConn,
+		logger:              logger,
 		writeTimeout:        writeTimeout,
 		readTimeout:         readTimeout,
 		config:              config,
@@ -105,6 +113,7 @@ func NewConnector(config *Config) (*Connector, error) {
 	}
 	wsClient.ErrorHandler = connector.handleError
 	wsConn.initClient()
+	logger.Info("connection opened", "url", u.Host)
 	return connector, nil
 }
 
@@ -162,6 +171,7 @@ func connect(ws *websocket.Conn, user string, password string, db string, totpCo
 }
 
 func (c *Connector) handleError(err error) {
+	c.logger.Warn("websocket error", "url", c.url, "error", err)
 	if c.customErrorHandler != nil {
 		c.customErrorHandler(c, err)
 	}
@@ -174,19 +184,23 @@ func (c *Connector) generateReqID() uint64 {
 
 func (c *Connector) reconnect() error {
 	reconnected := false
+	c.logger.Warn("reconnecting", "url", c.url)
 	for i := 0; i < c.reconnectRetryCount; i++ {
 		time.Sleep(time.Duration(c.reconnectIntervalMs) * time.Millisecond)
 		conn, _, err := c.dialer.Dial(c.url, nil)
 		if err != nil {
+			c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			continue
 		}
 		conn.EnableWriteCompression(c.dialer.EnableCompression)
 		err = connect(conn, c.user, c.password, c.db, c.totpCode, c.bearerToken, c.writeTimeout, c.readTimeout, c.timezone)
 		if err != nil {
+			c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			_ = conn.Close()
 			continue
 		}
 		if err = tdversion.WSCheckVersion(conn); err != nil {
+			c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			_ = conn.Close()
 			continue
 		}
@@ -195,13 +209,17 @@ func (c *Connector) reconnect() error {
 		}
 		cl := client.NewClient(conn, c.chanLength)
 		cl.ErrorHandler = c.handleError
+		cl.Logger = c.logger
 		wsConn := NewWSConn(cl, c.writeTimeout, c.readTimeout)
 		wsConn.initClient()
+		c.logger.Info("reconnected", "attempts", i+1)
+		metrics.GetMetrics().AddReconnect()
 		reconnected = true
 		c.client = wsConn
 		break
 	}
 	if !reconnected {
+		c.logger.Error("reconnect failed", "url", c.url)
 		if c.client != nil {
 			c.client.Close()
 		}
@@ -234,25 +252,9 @@ func (c *Connector) Init() (*Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	respBytes, err := c.client.sendText(reqID, envelope)
+	respBytes, err := c.sendTextWithReconnect(reqID, envelope)
 	if err != nil {
-		if !c.autoReconnect {
-			return nil, err
-		}
-
-		var opError *net.OpError
-		if !c.client.client.IsRunning() || errors.Is(err, client.ClosedError) || errors.As(err, &opError) {
-			err = c.reconnect()
-			if err != nil {
-				return nil, err
-			}
-			respBytes, err = c.client.sendText(reqID, envelope)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 	var resp InitResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
@@ -268,6 +270,70 @@ func (c *Connector) Init() (*Stmt, error) {
 	return s, nil
 }
 
+// InitStmt2 creates a stmt2 object, which binds multiple subtables in a single request.
+func (c *Connector) InitStmt2() (*Stmt2, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, ErrConnIsClosed
+	}
+	reqID := c.generateReqID()
+	req := &Stmt2InitReq{
+		ReqID: reqID,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	action := &client.WSAction{
+		Action: STMT2Init,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := c.sendTextWithReconnect(reqID, envelope)
+	if err != nil {
+		return nil, err
+	}
+	var resp Stmt2InitResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	err = client.HandleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stmt2{
+		id:        resp.StmtID,
+		connector: c.client,
+		timezone:  c.timezone,
+	}
+	return s, nil
+}
+
+// sendTextWithReconnect sends the message and, if autoReconnect is enabled and the connection is broken,
+// reconnects and sends it again once. Must be called with the connector locked.
+func (c *Connector) sendTextWithReconnect(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+	respBytes, err := c.client.sendText(reqID, envelope)
+	if err == nil {
+		return respBytes, nil
+	}
+	if !c.autoReconnect {
+		return nil, err
+	}
+	var opError *net.OpError
+	if !c.client.client.IsRunning() || errors.Is(err, client.ClosedError) || errors.As(err, &opError) {
+		err = c.reconnect()
+		if err != nil {
+			return nil, err
+		}
+		return c.client.sendText(reqID, envelope)
+	}
+	return nil, err
+}
+
 func (c *Connector) Close() error {
 	c.Lock()
 	defer c.Unlock()
@@ -276,6 +342,7 @@ func (c *Connector) Close() error {
 	}
 	c.closed = true
 	c.client.Close()
+	c.logger.Info("connection closed", "url", c.url)
 	if c.customCloseHandler != nil {
 		c.customCloseHandler()
 	}
@@ -1,12 +1,19 @@
 package stmt
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+)
 
 const (
-	SetTagsMessage = 1
-	BindMessage    = 2
+	SetTagsMessage   = 1
+	BindMessage      = 2
+	Stmt2BindMessage = 9
 )
 
+const Stmt2BindProtocolVersion1 = 1
+
 const (
 	STMTConnect      = "conn"
 	STMTInit         = "init"
@@ -19,6 +26,12 @@ const (
 	WSFetch          = "fetch"
 	WSFetchBlock     = "fetch_block"
 	WSFreeResult     = "free_result"
+
+	STMT2Init    = "stmt2_init"
+	STMT2Prepare = "stmt2_prepare"
+	STMT2Exec    = "stmt2_exec"
+	STMT2Result  = "stmt2_result"
+	STMT2Close   = "stmt2_close"
 )
 
 type ConnectReq struct {
@@ -192,3 +205,72 @@ type WSFreeResultRequest struct {
 	ReqID uint64 `json:"req_id"`
 	ID    uint64 `json:"id"`
 }
+
+type Stmt2InitReq struct {
+	ReqID               uint64 `json:"req_id"`
+	SingleStbInsert     bool   `json:"single_stb_insert"`
+	SingleTableBindOnce bool   `json:"single_table_bind_once"`
+}
+
+type Stmt2InitResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Action  string `json:"action"`
+	ReqID   uint64 `json:"req_id"`
+	Timing  int64  `json:"timing"`
+	StmtID  uint64 `json:"stmt_id"`
+}
+
+type Stmt2PrepareReq struct {
+	ReqID     uint64 `json:"req_id"`
+	StmtID    uint64 `json:"stmt_id"`
+	SQL       string `json:"sql"`
+	GetFields bool   `json:"get_fields"`
+}
+
+type Stmt2PrepareResp struct {
+	Code        int                         `json:"code"`
+	Message     string                      `json:"message"`
+	Action      string                      `json:"action"`
+	ReqID       uint64                      `json:"req_id"`
+	Timing      int64                       `json:"timing"`
+	StmtID      uint64                      `json:"stmt_id"`
+	IsInsert    bool                        `json:"is_insert"`
+	Fields      []*stmtCommon.Stmt2AllField `json:"fields"`
+	FieldsCount int                         `json:"fields_count"`
+}
+
+type Stmt2BindResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Action  string `json:"action"`
+	ReqID   uint64 `json:"req_id"`
+	Timing  int64  `json:"timing"`
+	StmtID  uint64 `json:"stmt_id"`
+}
+
+type Stmt2ExecReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type Stmt2ExecResp struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Action   string `json:"action"`
+	ReqID    uint64 `json:"req_id"`
+	Timing   int64  `json:"timing"`
+	StmtID   uint64 `json:"stmt_id"`
+	Affected int    `json:"affected"`
+}
+
+// Stmt2ResultReq asks for the result of an executed stmt2 query, the response has the same layout as UseResultResp.
+type Stmt2ResultReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type Stmt2CloseReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
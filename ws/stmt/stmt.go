@@ -6,6 +6,7 @@ import (
 
 	"github.com/taosdata/driver-go/v3/common/param"
 	"github.com/taosdata/driver-go/v3/common/serializer"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
@@ -105,12 +106,15 @@ func (s *Stmt) SetTags(tags *param.Param, bindType *param.ColumnType) error {
 	return client.HandleResponseError(err, resp.Code, resp.Message)
 }
 
-func (s *Stmt) BindParam(params []*param.Param, bindType *param.ColumnType) error {
+func (s *Stmt) BindParam(params []*param.Param, bindType *param.ColumnType) (err error) {
 	block, err := serializer.SerializeRawBlock(params, bindType)
 	if err != nil {
 		return err
 	}
-	reqID := s.connector.generateReqID()
+	span, reqID := s.connector.startSpan(trace.SpanStmtBind, trace.Bytes(len(block)))
+	defer func() {
+		trace.End(span, err)
+	}()
 	reqData := make([]byte, 24)
 	binary.LittleEndian.PutUint64(reqData, reqID)
 	binary.LittleEndian.PutUint64(reqData[8:], s.id)
@@ -162,8 +166,14 @@ func (s *Stmt) AddBatch() error {
 	return client.HandleResponseError(err, resp.Code, resp.Message)
 }
 
-func (s *Stmt) Exec() error {
-	reqID := s.connector.generateReqID()
+func (s *Stmt) Exec() (err error) {
+	span, reqID := s.connector.startSpan(trace.SpanStmtExec)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(trace.Rows(s.lastAffected))
+		}
+		trace.End(span, err)
+	}()
 	req := &ExecReq{
 		ReqID:  reqID,
 		StmtID: s.id,
@@ -0,0 +1,220 @@
+package stmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/common/trace"
+	"github.com/taosdata/driver-go/v3/ws/client"
+)
+
+var ErrStmt2NotPrepared = errors.New("stmt2 is not prepared")
+
+type Stmt2 struct {
+	connector    *WSConn
+	timezone     *time.Location
+	id           uint64
+	isInsert     *bool
+	fields       []*stmtCommon.Stmt2AllField
+	lastAffected int
+}
+
+func (s *Stmt2) Prepare(sql string) error {
+	reqID := s.connector.generateReqID()
+	req := &Stmt2PrepareReq{
+		ReqID:     reqID,
+		StmtID:    s.id,
+		SQL:       sql,
+		GetFields: true,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return err
+	}
+	action := &client.WSAction{
+		Action: STMT2Prepare,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return err
+	}
+	respBytes, err := s.connector.sendText(reqID, envelope)
+	if err != nil {
+		return err
+	}
+	var resp Stmt2PrepareResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	err = client.HandleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return err
+	}
+	isInsert := resp.IsInsert
+	s.isInsert = &isInsert
+	if isInsert {
+		s.fields = resp.Fields
+	} else {
+		s.fields = nil
+	}
+	return nil
+}
+
+// GetFields returns the fields of the prepared insert statement, a query statement has no fields.
+func (s *Stmt2) GetFields() ([]*stmtCommon.Stmt2AllField, error) {
+	if s.isInsert == nil {
+		return nil, ErrStmt2NotPrepared
+	}
+	return s.fields, nil
+}
+
+// IsInsert reports whether the prepared statement is an insert statement.
+func (s *Stmt2) IsInsert() (bool, error) {
+	if s.isInsert == nil {
+		return false, ErrStmt2NotPrepared
+	}
+	return *s.isInsert, nil
+}
+
+// Bind binds the parameters to the stmt2, see af.Stmt2.Bind for the go type of each DB type.
+func (s *Stmt2) Bind(params []*stmtCommon.TaosStmt2BindData) (err error) {
+	if s.isInsert == nil {
+		return ErrStmt2NotPrepared
+	}
+	data, err := stmtCommon.MarshalStmt2Binary(params, *s.isInsert, s.fields)
+	if err != nil {
+		return err
+	}
+	span, reqID := s.connector.startSpan(trace.SpanStmtBind, trace.Bytes(len(data)))
+	defer func() {
+		trace.End(span, err)
+	}()
+	// req_id(8) + stmt_id(8) + action(8) + version(2) + col_idx(4)
+	reqData := make([]byte, 30)
+	binary.LittleEndian.PutUint64(reqData, reqID)
+	binary.LittleEndian.PutUint64(reqData[8:], s.id)
+	binary.LittleEndian.PutUint64(reqData[16:], Stmt2BindMessage)
+	binary.LittleEndian.PutUint16(reqData[24:], Stmt2BindProtocolVersion1)
+	// col_idx -1 means bind all columns
+	binary.LittleEndian.PutUint32(reqData[26:], math.MaxUint32)
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	envelope.Msg.Grow(30 + len(data))
+	envelope.Msg.Write(reqData)
+	envelope.Msg.Write(data)
+	respBytes, err := s.connector.sendBinary(reqID, envelope)
+	if err != nil {
+		return err
+	}
+	var resp Stmt2BindResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	return client.HandleResponseError(err, resp.Code, resp.Message)
+}
+
+func (s *Stmt2) Exec() (err error) {
+	if s.isInsert == nil {
+		return ErrStmt2NotPrepared
+	}
+	span, reqID := s.connector.startSpan(trace.SpanStmtExec)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(trace.Rows(s.lastAffected))
+		}
+		trace.End(span, err)
+	}()
+	req := &Stmt2ExecReq{
+		ReqID:  reqID,
+		StmtID: s.id,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return err
+	}
+	action := &client.WSAction{
+		Action: STMT2Exec,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return err
+	}
+	respBytes, err := s.connector.sendText(reqID, envelope)
+	if err != nil {
+		return err
+	}
+	var resp Stmt2ExecResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	err = client.HandleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return err
+	}
+	s.lastAffected = resp.Affected
+	return nil
+}
+
+func (s *Stmt2) GetAffectedRows() int {
+	return s.lastAffected
+}
+
+func (s *Stmt2) UseResult() (*Rows, error) {
+	reqID := s.connector.generateReqID()
+	req := &Stmt2ResultReq{
+		ReqID:  reqID,
+		StmtID: s.id,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	action := &client.WSAction{
+		Action: STMT2Result,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := s.connector.sendText(reqID, envelope)
+	if err != nil {
+		return nil, err
+	}
+	var resp UseResultResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	err = client.HandleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return nil, err
+	}
+	return NewRows(s.connector, s.connector.client, &resp, s.timezone), nil
+}
+
+func (s *Stmt2) Close() error {
+	reqID := s.connector.generateReqID()
+	req := &Stmt2CloseReq{
+		ReqID:  reqID,
+		StmtID: s.id,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return err
+	}
+	action := &client.WSAction{
+		Action: STMT2Close,
+		Args:   args,
+	}
+	envelope := client.GlobalEnvelopePool.Get()
+	defer client.GlobalEnvelopePool.Put(envelope)
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		return err
+	}
+	s.connector.sendTextWithoutResp(envelope)
+	return nil
+}
@@ -0,0 +1,141 @@
+package stmt
+
+import (
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+)
+
+func newStmt2TestConnector(t *testing.T, db string) *Connector {
+	config := NewConfig("ws://127.0.0.1:6041", 0)
+	err := config.SetConnectUser("root")
+	require.NoError(t, err)
+	err = config.SetConnectPass("taosdata")
+	require.NoError(t, err)
+	err = config.SetConnectDB(db)
+	require.NoError(t, err)
+	err = config.SetMessageTimeout(common.DefaultMessageTimeout)
+	require.NoError(t, err)
+	config.SetErrorHandler(func(connector *Connector, err error) {
+		t.Log(err)
+	})
+	connector, err := NewConnector(config)
+	require.NoError(t, err)
+	return connector
+}
+
+func TestStmt2(t *testing.T) {
+	db := "test_ws_stmt2"
+	err := doRequest("drop database if exists " + db)
+	require.NoError(t, err)
+	err = doRequest("create database " + db)
+	require.NoError(t, err)
+	defer func() {
+		err = cleanEnv(db)
+		assert.NoError(t, err)
+	}()
+	err = doRequest("create stable " + db + ".stb(ts timestamp, c1 int, c2 nchar(20)) tags(t1 int, t2 binary(20))")
+	require.NoError(t, err)
+	connector := newStmt2TestConnector(t, db)
+	defer func() {
+		err = connector.Close()
+		assert.NoError(t, err)
+	}()
+	stmt2, err := connector.InitStmt2()
+	require.NoError(t, err)
+	_, err = stmt2.GetFields()
+	assert.Equal(t, ErrStmt2NotPrepared, err)
+	err = stmt2.Bind(nil)
+	assert.Equal(t, ErrStmt2NotPrepared, err)
+	err = stmt2.Prepare("insert into ? using stb tags(?,?) values(?,?,?)")
+	require.NoError(t, err)
+	isInsert, err := stmt2.IsInsert()
+	require.NoError(t, err)
+	assert.True(t, isInsert)
+	fields, err := stmt2.GetFields()
+	require.NoError(t, err)
+	require.Equal(t, 6, len(fields))
+	assert.Equal(t, int8(stmtCommon.TAOS_FIELD_TBNAME), fields[0].BindType)
+	now := time.Now().Round(time.Millisecond)
+	params := []*stmtCommon.TaosStmt2BindData{
+		{
+			TableName: "ctb1",
+			Tags:      []driver.Value{int32(1), []byte("tag1")},
+			Cols: [][]driver.Value{
+				{now, now.Add(time.Second)},
+				{int32(1), nil},
+				{"v1", "v2"},
+			},
+		},
+		{
+			TableName: "ctb2",
+			Tags:      []driver.Value{int32(2), []byte("tag2")},
+			Cols: [][]driver.Value{
+				{now},
+				{int32(2)},
+				{nil},
+			},
+		},
+	}
+	err = stmt2.Bind(params)
+	require.NoError(t, err)
+	err = stmt2.Exec()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stmt2.GetAffectedRows())
+	err = stmt2.Close()
+	assert.NoError(t, err)
+
+	stmt2, err = connector.InitStmt2()
+	require.NoError(t, err)
+	defer func() {
+		err = stmt2.Close()
+		assert.NoError(t, err)
+	}()
+	err = stmt2.Prepare("select ts, c1, c2 from stb where t1 = ? order by ts")
+	require.NoError(t, err)
+	isInsert, err = stmt2.IsInsert()
+	require.NoError(t, err)
+	assert.False(t, isInsert)
+	err = stmt2.Bind([]*stmtCommon.TaosStmt2BindData{{Cols: [][]driver.Value{{int32(1)}}}})
+	require.NoError(t, err)
+	err = stmt2.Exec()
+	require.NoError(t, err)
+	rows, err := stmt2.UseResult()
+	require.NoError(t, err)
+	defer func() {
+		err = rows.Close()
+		assert.NoError(t, err)
+	}()
+	assert.Equal(t, []string{"ts", "c1", "c2"}, rows.Columns())
+	var result [][]driver.Value
+	for {
+		values := make([]driver.Value, 3)
+		err = rows.Next(values)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		result = append(result, values)
+	}
+	require.Equal(t, 2, len(result))
+	assert.Equal(t, now.UnixNano()/1e6, result[0][0].(time.Time).UnixNano()/1e6)
+	assert.Equal(t, int32(1), result[0][1])
+	assert.Equal(t, "v1", result[0][2])
+	assert.Nil(t, result[1][1])
+	assert.Equal(t, "v2", result[1][2])
+}
+
+func TestStmt2ConnectorClosed(t *testing.T) {
+	connector := newStmt2TestConnector(t, "")
+	err := connector.Close()
+	require.NoError(t, err)
+	stmt2, err := connector.InitStmt2()
+	assert.Equal(t, ErrConnIsClosed, err)
+	assert.Nil(t, stmt2)
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
@@ -66,17 +68,20 @@ func (c *WSConn) handleTextMessage(message []byte) {
 		return iter.Error == nil
 	})
 	client.JsonI.ReturnIterator(iter)
-	c.listLock.Lock()
-	element := c.findOutChanByID(reqID)
-	if element != nil {
-		element.Value.(*IndexedChan).channel <- message
-		c.sendChanList.Remove(element)
-	}
-	c.listLock.Unlock()
+	c.deliver(reqID, message)
 }
 
 func (c *WSConn) handleBinaryMessage(message []byte) {
+	if len(message) < 16 {
+		c.client.Logger.Warn("drop binary response", "reason", "message too short", "length", len(message))
+		return
+	}
 	reqID := binary.LittleEndian.Uint64(message[8:16])
+	c.deliver(reqID, message)
+}
+
+// deliver passes a response to the waiting request, the late response of a timed out request is dropped.
+func (c *WSConn) deliver(reqID uint64, message []byte) {
 	c.listLock.Lock()
 	element := c.findOutChanByID(reqID)
 	if element != nil {
@@ -84,6 +89,9 @@ func (c *WSConn) handleBinaryMessage(message []byte) {
 		c.sendChanList.Remove(element)
 	}
 	c.listLock.Unlock()
+	if element == nil {
+		c.client.Logger.Debug("drop response", "req_id", reqID)
+	}
 }
 
 func (c *WSConn) findOutChanByID(index uint64) *list.Element {
@@ -111,6 +119,14 @@ func (c *WSConn) generateReqID() uint64 {
 	return uint64(common.GetReqID())
 }
 
+// startSpan starts a span of a request, the req_id of the request is derived from the span.
+func (c *WSConn) startSpan(name string, attrs ...trace.Attribute) (trace.Span, uint64) {
+	ctx, span := trace.Start(context.Background(), name, attrs...)
+	reqID := trace.NewReqID(ctx)
+	span.SetAttributes(trace.ReqID(reqID))
+	return span, uint64(reqID)
+}
+
 func (c *WSConn) sendText(reqID uint64, envelope *client.Envelope) ([]byte, error) {
 	envelope.Type = websocket.TextMessage
 	return c.send(reqID, envelope)
@@ -122,6 +138,8 @@ func (c *WSConn) sendBinary(reqID uint64, envelope *client.Envelope) ([]byte, er
 }
 
 func (c *WSConn) send(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+	c.client.Logger.Debug("stmt request", "req_id", reqID)
+	defer log.Slow(c.client.Logger, "stmt request", reqID, time.Now())
 	channel := &IndexedChan{
 		index:   reqID,
 		channel: make(chan []byte, 1),
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
 )
 
 type config struct {
@@ -29,6 +30,7 @@ type config struct {
 	SessionTimeoutMS     string
 	MaxPollIntervalMS    string
 	OtherOptions         map[string]string
+	Logger               log.Logger
 }
 
 func newConfig(url string, chanLength uint) *config {
@@ -36,6 +38,7 @@ func newConfig(url string, chanLength uint) *config {
 		Url:          url,
 		ChanLength:   chanLength,
 		OtherOptions: make(map[string]string),
+		Logger:       log.GetLogger(),
 	}
 }
 
@@ -18,15 +18,19 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
+	"github.com/taosdata/driver-go/v3/common/metrics"
 	"github.com/taosdata/driver-go/v3/common/parser"
 	"github.com/taosdata/driver-go/v3/common/tdversion"
 	"github.com/taosdata/driver-go/v3/common/tmq"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
 type Consumer struct {
 	client              *client.Client
+	logger              log.Logger
 	requestID           uint64
 	err                 error
 	timezone            *time.Location
@@ -58,6 +62,18 @@ type Consumer struct {
 	writeWait           time.Duration
 	dialer              *websocket.Dialer
 	lastMessageID       uint64
+	offsetStore         tmq.OffsetStore
+	offsetStoreMode     tmq.OffsetStoreMode
+	// vgroups whose offset has been restored from offsetStore
+	restored           map[vgroupKey]struct{}
+	rebalanceCb        RebalanceCb
+	lastAssignment     []tmq.TopicPartition
+	nextRebalanceCheck time.Time
+}
+
+type vgroupKey struct {
+	topic    string
+	vgroupID int32
 }
 
 type IndexedChan struct {
@@ -99,6 +115,7 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 	u.Path = "/rest/tmq"
 	ws, _, err := dialer.Dial(u.String(), nil)
 	if err != nil {
+		config.Logger.Warn("dial failed", "url", u.Host, "error", err)
 		return nil, err
 	}
 	ws.EnableWriteCompression(config.EnableCompression)
@@ -110,6 +127,7 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 
 	consumer := &Consumer{
 		client:              wsClient,
+		logger:              config.Logger,
 		requestID:           0,
 		sendChanList:        list.New(),
 		messageTimeout:      config.MessageTimeout,
@@ -135,6 +153,7 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 		timezone:            config.Timezone,
 	}
 	consumer.initClient(consumer.client)
+	consumer.logger.Info("consumer opened", "url", u.Host, "group_id", config.GroupID, "client_id", config.ClientID)
 	return consumer, nil
 }
 
@@ -145,20 +164,24 @@ func (c *Consumer) initClient(client *client.Client) {
 	client.BinaryMessageHandler = c.handleBinaryMessage
 	client.TextMessageHandler = c.handleTextMessage
 	client.ErrorHandler = c.handleError
+	client.Logger = c.logger
 	go client.WritePump()
 	go client.ReadPump()
 }
 
 func (c *Consumer) reconnect() error {
 	reconnected := false
+	c.logger.Warn("reconnecting", "group_id", c.groupID, "client_id", c.clientID)
 	for i := 0; i < c.reconnectRetryCount; i++ {
 		time.Sleep(time.Duration(c.reconnectIntervalMs) * time.Millisecond)
 		conn, _, err := c.dialer.Dial(c.url, nil)
 		if err != nil {
+			c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			continue
 		}
 		conn.EnableWriteCompression(c.dialer.EnableCompression)
 		if err = tdversion.WSCheckVersion(conn); err != nil {
+			c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 			_ = conn.Close()
 			continue
 		}
@@ -171,14 +194,18 @@ func (c *Consumer) reconnect() error {
 		if len(c.topics) > 0 {
 			err = c.doSubscribe(c.topics, false)
 			if err != nil {
+				c.logger.Warn("reconnect attempt failed", "attempt", i+1, "error", err)
 				c.client.Close()
 				continue
 			}
 		}
+		c.logger.Info("reconnected", "attempts", i+1)
+		metrics.GetMetrics().AddReconnect()
 		reconnected = true
 		break
 	}
 	if !reconnected {
+		c.logger.Error("reconnect failed", "group_id", c.groupID, "client_id", c.clientID)
 		return errors.New("reconnect failed")
 	}
 	return nil
@@ -205,6 +232,7 @@ var excludeConfig = map[string]struct{}{
 	"session.timeout.ms":           {},
 	"max.poll.interval.ms":         {},
 	"timezone":                     {},
+	tmq.LoggerKey:                  {},
 }
 
 func configMapToConfig(m tmq.ConfigMap) (*config, error) {
@@ -292,7 +320,12 @@ func configMapToConfig(m tmq.ConfigMap) (*config, error) {
 	if err != nil {
 		return nil, err
 	}
+	logger, err := m.GetLogger()
+	if err != nil {
+		return nil, err
+	}
 	config := newConfig(url.(string), chanLen.(uint))
+	config.Logger = logger
 	err = config.setMessageTimeout(messageTimeout.(time.Duration))
 	if err != nil {
 		return nil, err
@@ -347,17 +380,20 @@ func (c *Consumer) handleTextMessage(message []byte) {
 		return iter.Error == nil
 	})
 	client.JsonI.ReturnIterator(iter)
-	c.listLock.Lock()
-	element := c.findOutChanByID(reqID)
-	if element != nil {
-		element.Value.(*IndexedChan).channel <- message
-		c.sendChanList.Remove(element)
-	}
-	c.listLock.Unlock()
+	c.deliver(reqID, message)
 }
 
 func (c *Consumer) handleBinaryMessage(message []byte) {
+	if len(message) < 16 {
+		c.logger.Warn("drop binary response", "reason", "message too short", "length", len(message))
+		return
+	}
 	reqID := binary.LittleEndian.Uint64(message[8:16])
+	c.deliver(reqID, message)
+}
+
+// deliver passes a response to the waiting request, the late response of a timed out request is dropped.
+func (c *Consumer) deliver(reqID uint64, message []byte) {
 	c.listLock.Lock()
 	element := c.findOutChanByID(reqID)
 	if element != nil {
@@ -365,9 +401,13 @@ func (c *Consumer) handleBinaryMessage(message []byte) {
 		c.sendChanList.Remove(element)
 	}
 	c.listLock.Unlock()
+	if element == nil {
+		c.logger.Debug("drop response", "req_id", reqID)
+	}
 }
 
 func (c *Consumer) handleError(err error) {
+	c.logger.Warn("websocket error", "group_id", c.groupID, "client_id", c.clientID, "error", err)
 	if !c.autoReconnect {
 		c.err = &WSError{err: err}
 	}
@@ -377,11 +417,25 @@ func (c *Consumer) generateReqID() uint64 {
 	return atomic.AddUint64(&c.requestID, 1)
 }
 
+// startSpan starts a span of a request, the req_id of the request is derived from the span if the tracer gives it an id.
+func (c *Consumer) startSpan(ctx context.Context, name string, attrs ...trace.Attribute) (trace.Span, uint64) {
+	ctx, span := trace.Start(ctx, name, attrs...)
+	var reqID uint64
+	if spanID, ok := trace.GetTracer().SpanID(ctx); ok {
+		reqID = uint64(trace.ReqIDFromSpanID(spanID))
+	} else {
+		reqID = c.generateReqID()
+	}
+	span.SetAttributes(trace.ReqID(int64(reqID)))
+	return span, reqID
+}
+
 // Close consumer. This function can be called multiple times
 func (c *Consumer) Close() error {
 	c.closeOnce.Do(func() {
 		close(c.closeChan)
 		c.client.Close()
+		c.logger.Info("consumer closed", "group_id", c.groupID, "client_id", c.clientID)
 	})
 	return nil
 }
@@ -428,10 +482,21 @@ const (
 	TMQPosition           = "position"
 )
 
+const rawDataOffset = 38
+
 //revive:disable-next-line
 var ClosedErr = errors.New("connection closed")
 
 func (c *Consumer) sendText(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+	return c.sendTextContext(context.Background(), reqID, envelope)
+}
+
+// sendTextContext sends a request and waits for its response until the message timeout or ctx is done,
+// a response that arrives after ctx is done is dropped.
+func (c *Consumer) sendTextContext(ctx context.Context, reqID uint64, envelope *client.Envelope) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	channel := &IndexedChan{
 		index:   reqID,
 		channel: make(chan []byte, 1),
@@ -452,29 +517,43 @@ func (c *Consumer) sendText(reqID uint64, envelope *client.Envelope) ([]byte, er
 		c.listLock.Unlock()
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), c.messageTimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.messageTimeout)
 	defer cancel()
 	select {
 	case <-c.closeChan:
 		return nil, ClosedErr
 	case resp := <-channel.channel:
 		return resp, nil
-	case <-ctx.Done():
+	case <-timeoutCtx.Done():
 		c.listLock.Lock()
 		c.sendChanList.Remove(element)
 		c.listLock.Unlock()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("message timeout :%s", envelope.Msg.String())
 	}
 }
 
+// RebalanceCb is called with tmq.RevokedPartitions and tmq.AssignedPartitions when the vgroups assigned to the consumer change.
+// The assignment is compared by Poll at most once per second, a returned error is returned by Poll as a tmq.Error.
 type RebalanceCb func(*Consumer, tmq.Event) error
 
+const rebalanceCheckInterval = time.Second
+
 func (c *Consumer) Subscribe(topic string, rebalanceCb RebalanceCb) error {
 	return c.SubscribeTopics([]string{topic}, rebalanceCb)
 }
 
 func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) error {
-	return c.doSubscribe(topics, c.autoReconnect)
+	err := c.doSubscribe(topics, c.autoReconnect)
+	if err != nil {
+		return err
+	}
+	c.rebalanceCb = rebalanceCb
+	c.lastAssignment = nil
+	c.nextRebalanceCheck = time.Time{}
+	return nil
 }
 
 func (c *Consumer) doSubscribe(topics []string, reconnect bool) error {
@@ -513,6 +592,7 @@ func (c *Consumer) doSubscribe(topics []string, reconnect bool) error {
 	if err != nil {
 		return err
 	}
+	start := time.Now()
 	respBytes, err := c.sendText(reqID, envelope)
 	if err != nil {
 		if !reconnect {
@@ -532,6 +612,7 @@ func (c *Consumer) doSubscribe(topics []string, reconnect bool) error {
 			return err
 		}
 	}
+	log.Slow(c.logger, "subscribe", reqID, start)
 	var resp SubscribeResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -540,25 +621,144 @@ func (c *Consumer) doSubscribe(topics []string, reconnect bool) error {
 	}
 	c.topics = make([]string, len(topics))
 	copy(c.topics, topics)
+	if c.offsetStore != nil {
+		// the subscription of a new session starts from the offsets of the server
+		c.restored = make(map[vgroupKey]struct{})
+		return c.restoreAssignment()
+	}
 	return nil
 }
 
-// Poll messages
-func (c *Consumer) Poll(timeoutMs int) tmq.Event {
+// SetOffsetStore saves the offsets committed by Commit and CommitOffsets in store, in addition to the server
+// with tmq.StoreAndCommit or instead of it with tmq.StoreOnly. The offsets of the vgroups assigned to the consumer
+// are restored from store on subscribe and when a vgroup is assigned later. It must be called before Subscribe.
+func (c *Consumer) SetOffsetStore(store tmq.OffsetStore, mode tmq.OffsetStoreMode) {
+	c.offsetStore = store
+	c.offsetStoreMode = mode
+	c.restored = make(map[vgroupKey]struct{})
+}
+
+func (c *Consumer) restoreAssignment() error {
+	partitions, err := c.Assignment()
+	if err != nil {
+		return err
+	}
+	_, err = tmq.RestoreOffsets(c, c.offsetStore, c.groupID, partitions)
+	if err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}] = struct{}{}
+	}
+	return nil
+}
+
+// restoreVgroup restores the offset of a vgroup polled for the first time, it reports whether the consumer
+// has been moved to another offset and the polled message must be dropped.
+func (c *Consumer) restoreVgroup(topic string, vgID int32, offset tmq.Offset) (bool, error) {
+	key := vgroupKey{topic: topic, vgroupID: vgID}
+	if _, exist := c.restored[key]; exist {
+		return false, nil
+	}
+	restored, err := tmq.RestoreOffsets(c, c.offsetStore, c.groupID, []tmq.TopicPartition{{Topic: &topic, Partition: vgID}})
+	if err != nil {
+		return false, err
+	}
+	c.restored[key] = struct{}{}
+	return len(restored) == 1 && restored[0].Offset != offset, nil
+}
+
+// checkRebalance compares the assignment with the last one and calls the rebalance callback with the changes.
+func (c *Consumer) checkRebalance() error {
+	if c.rebalanceCb == nil || time.Now().Before(c.nextRebalanceCheck) {
+		return nil
+	}
+	c.nextRebalanceCheck = time.Now().Add(rebalanceCheckInterval)
+	current, err := c.Assignment()
+	if err != nil {
+		return err
+	}
+	assigned, revoked := tmq.DiffAssignment(c.lastAssignment, current)
+	c.lastAssignment = current
+	if len(revoked) != 0 {
+		if c.offsetStore != nil {
+			for _, partition := range revoked {
+				delete(c.restored, vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition})
+			}
+		}
+		if err = c.rebalanceCb(c, tmq.RevokedPartitions{Partitions: revoked}); err != nil {
+			return err
+		}
+	}
+	if len(assigned) != 0 {
+		if c.offsetStore != nil {
+			var unrestored []tmq.TopicPartition
+			for _, partition := range assigned {
+				if _, exist := c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}]; !exist {
+					unrestored = append(unrestored, partition)
+				}
+			}
+			if _, err = tmq.RestoreOffsets(c, c.offsetStore, c.groupID, unrestored); err != nil {
+				return err
+			}
+			for _, partition := range unrestored {
+				c.restored[vgroupKey{topic: *partition.Topic, vgroupID: partition.Partition}] = struct{}{}
+			}
+		}
+		if err = c.rebalanceCb(c, tmq.AssignedPartitions{Partitions: assigned}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeAssignment calls the rebalance callback with the whole assignment after unsubscribe.
+func (c *Consumer) revokeAssignment() error {
+	revoked := c.lastAssignment
+	c.lastAssignment = nil
+	if c.offsetStore != nil {
+		c.restored = make(map[vgroupKey]struct{})
+	}
+	if c.rebalanceCb == nil || len(revoked) == 0 {
+		return nil
+	}
+	return c.rebalanceCb(c, tmq.RevokedPartitions{Partitions: revoked})
+}
+
+// poll requests the next message, the message is fetched with its MessageID
+func (c *Consumer) poll(timeoutMs int) (result *PollResp, err error) {
 	if c.err != nil {
-		return tmq.NewTMQErrorWithErr(c.err)
+		return nil, c.err
+	}
+	if err := c.checkRebalance(); err != nil {
+		return nil, err
 	}
 	if c.autoCommit {
 		if c.nextAutoCommitTime.IsZero() {
 			c.nextAutoCommitTime = time.Now().Add(c.autoCommitInterval)
 		} else {
 			if time.Now().After(c.nextAutoCommitTime) {
-				_ = c.doCommit()
+				if c.offsetStore != nil {
+					_, _ = c.Commit()
+				} else {
+					_ = c.doCommit(context.Background())
+				}
 				c.nextAutoCommitTime = time.Now().Add(c.autoCommitInterval)
 			}
 		}
 	}
-	reqID := c.generateReqID()
+	start := time.Now()
+	span, reqID := c.startSpan(context.Background(), trace.SpanTMQPoll)
+	defer func() {
+		metrics.GetMetrics().ObserveRequest(metrics.KindTMQPoll, time.Since(start), err)
+		if result != nil && result.HaveMessage {
+			span.SetAttributes(
+				trace.Attribute{Key: trace.AttrTopic, Value: result.Topic},
+				trace.Attribute{Key: trace.AttrVgroupID, Value: result.VgroupID},
+			)
+		}
+		trace.End(span, err)
+	}()
 	req := &PollReq{
 		ReqID:        reqID,
 		BlockingTime: int64(timeoutMs),
@@ -566,7 +766,7 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 	}
 	args, err := client.JsonI.Marshal(req)
 	if err != nil {
-		return tmq.NewTMQErrorWithErr(err)
+		return nil, err
 	}
 	action := &client.WSAction{
 		Action: TMQPoll,
@@ -576,37 +776,61 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 	defer client.GlobalEnvelopePool.Put(envelope)
 	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
 	if err != nil {
-		return tmq.NewTMQErrorWithErr(err)
+		return nil, err
 	}
+	c.logger.Debug("poll", "req_id", reqID, "message_id", c.lastMessageID)
 	respBytes, err := c.sendText(reqID, envelope)
 	if err != nil {
 		if !c.autoReconnect {
-			return tmq.NewTMQErrorWithErr(err)
+			return nil, err
 		}
 		var opError *net.OpError
 		if errors.Is(err, ClosedErr) || errors.Is(err, client.ClosedError) || errors.As(err, &opError) {
 			err = c.reconnect()
 			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
+				return nil, err
 			}
 			respBytes, err = c.sendText(reqID, envelope)
 			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
+				return nil, err
 			}
 		} else {
-			return tmq.NewTMQErrorWithErr(err)
+			return nil, err
 		}
 	}
+	// the server holds the poll for up to the blocking time
+	log.Slow(c.logger, "poll", reqID, start.Add(time.Duration(timeoutMs)*time.Millisecond))
 	var resp PollResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	if err != nil {
-		return tmq.NewTMQErrorWithErr(err)
+		c.logger.Error("protocol error", "req_id", reqID, "error", err)
+		return nil, err
 	}
 	if resp.Code != 0 {
-		return tmq.NewTMQErrorWithErr(taosErrors.NewError(resp.Code, resp.Message))
+		return nil, taosErrors.NewError(resp.Code, resp.Message)
 	}
 	if resp.HaveMessage {
 		c.lastMessageID = resp.MessageID
+		if c.offsetStore != nil {
+			moved, err := c.restoreVgroup(resp.Topic, resp.VgroupID, tmq.Offset(resp.Offset))
+			if err != nil {
+				return nil, err
+			}
+			if moved {
+				resp.HaveMessage = false
+			}
+		}
+	}
+	return &resp, nil
+}
+
+// Poll messages
+func (c *Consumer) Poll(timeoutMs int) tmq.Event {
+	resp, err := c.poll(timeoutMs)
+	if err != nil {
+		return tmq.NewTMQErrorWithErr(err)
+	}
+	if resp.HaveMessage {
 		switch resp.MessageType {
 		case common.TMQ_RES_DATA:
 			result := &tmq.DataMessage{}
@@ -674,6 +898,60 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 	}
 }
 
+// contextPollTimeout is the blocking time of each poll request of PollContext. A polled message can not be
+// given back to the server, so the poll request in flight is not aborted when ctx is done.
+const contextPollTimeout = 100 * time.Millisecond
+
+// PollContext polls until a message or an error is received, it returns nil when ctx is done.
+func (c *Consumer) PollContext(ctx context.Context) tmq.Event {
+	for ctx.Err() == nil {
+		timeout := contextPollTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining < time.Millisecond {
+				// a poll of 0 ms waits without limit
+				return nil
+			}
+			if remaining < timeout {
+				timeout = remaining
+			}
+		}
+		if event := c.Poll(int(timeout / time.Millisecond)); event != nil {
+			return event
+		}
+	}
+	return nil
+}
+
+// PollRaw polls a message without parsing it, the returned event is nil, a tmq.Error or a *tmq.RawMessage.
+// The raw payload can be written to another cluster with the WriteRaw method of a taosWS connection.
+func (c *Consumer) PollRaw(timeoutMs int) tmq.Event {
+	resp, err := c.poll(timeoutMs)
+	if err != nil {
+		return tmq.NewTMQErrorWithErr(err)
+	}
+	if !resp.HaveMessage {
+		return nil
+	}
+	raw, err := c.fetchRaw(resp.MessageID)
+	if err != nil {
+		return tmq.NewTMQErrorWithErr(err)
+	}
+	result := &tmq.RawMessage{}
+	result.SetDbName(resp.Database)
+	result.SetTopic(resp.Topic)
+	result.SetOffset(tmq.Offset(resp.Offset))
+	result.SetMessageType(resp.MessageType)
+	result.SetRaw(raw)
+	topic := resp.Topic
+	result.TopicPartition = tmq.TopicPartition{
+		Topic:     &topic,
+		Partition: resp.VgroupID,
+		Offset:    tmq.Offset(resp.Offset),
+	}
+	return result
+}
+
 func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
 	reqID := c.generateReqID()
 	req := &FetchJsonMetaReq{
@@ -694,10 +972,12 @@ func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 	respBytes, err := c.sendText(reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
+	log.Slow(c.logger, "fetch json meta", reqID, start)
 	var resp FetchJsonMetaResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -712,7 +992,10 @@ func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
 	return &meta, nil
 }
 
-func (c *Consumer) fetch(messageID uint64) ([]*tmq.Data, error) {
+// fetchRaw fetches the raw data of a message, the response is
+// timing (uint64), req_id (uint64), message_id (uint64), message type (uint64),
+// raw data length (uint32), meta type (uint16) and the raw data.
+func (c *Consumer) fetchRaw(messageID uint64) (*tmq.RawData, error) {
 	reqID := c.generateReqID()
 	req := &TMQFetchRawMetaReq{
 		ReqID:     reqID,
@@ -732,15 +1015,43 @@ func (c *Consumer) fetch(messageID uint64) ([]*tmq.Data, error) {
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 	respBytes, err := c.sendText(reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
-	blockInfo, err := c.dataParser.Parse(unsafe.Pointer(&respBytes[38]))
+	log.Slow(c.logger, "fetch raw", reqID, start)
+	return parseRawData(respBytes)
+}
+
+func parseRawData(respBytes []byte) (*tmq.RawData, error) {
+	if len(respBytes) < rawDataOffset {
+		return nil, taosErrors.NewError(0xffff, "invalid fetch raw response")
+	}
+	length := int(binary.LittleEndian.Uint32(respBytes[32:]))
+	if len(respBytes) < rawDataOffset+length {
+		return nil, taosErrors.NewError(0xffff, "invalid fetch raw response")
+	}
+	return &tmq.RawData{
+		MetaType: binary.LittleEndian.Uint16(respBytes[36:]),
+		Data:     respBytes[rawDataOffset : rawDataOffset+length],
+	}, nil
+}
+
+func (c *Consumer) fetch(messageID uint64) ([]*tmq.Data, error) {
+	raw, err := c.fetchRaw(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw.Data) == 0 {
+		return nil, nil
+	}
+	blockInfo, err := c.dataParser.Parse(unsafe.Pointer(&raw.Data[0]))
 	if err != nil {
 		return nil, err
 	}
 	tmqData := make([]*tmq.Data, len(blockInfo))
+	rows := 0
 	for i := 0; i < len(blockInfo); i++ {
 		var data [][]driver.Value
 		if c.timezone == nil {
@@ -751,11 +1062,14 @@ func (c *Consumer) fetch(messageID uint64) ([]*tmq.Data, error) {
 		if err != nil {
 			return nil, err
 		}
+		rows += len(data)
 		tmqData[i] = &tmq.Data{
 			TableName: blockInfo[i].TableName,
 			Data:      data,
+			Fields:    parser.ParseTMQFields(blockInfo[i]),
 		}
 	}
+	metrics.GetMetrics().AddFetched(rows, len(raw.Data))
 	return tmqData, nil
 }
 
@@ -764,22 +1078,44 @@ func (c *Consumer) FormatTime(ts int64, precision int) driver.Value {
 }
 
 func (c *Consumer) Commit() ([]tmq.TopicPartition, error) {
-	err := c.doCommit()
+	return c.CommitContext(context.Background())
+}
+
+// CommitContext is Commit that returns ctx.Err() when ctx is done before the responses,
+// the request in flight may still be executed by the server.
+func (c *Consumer) CommitContext(ctx context.Context) ([]tmq.TopicPartition, error) {
+	if c.offsetStore != nil {
+		partitions, err := c.assignment(ctx)
+		if err != nil {
+			return nil, err
+		}
+		positions, err := c.position(ctx, partitions)
+		if err != nil {
+			return nil, err
+		}
+		return c.commitOffsets(ctx, positions)
+	}
+	err := c.doCommit(ctx)
 	if err != nil {
 		return nil, err
 	}
-	partitions, err := c.Assignment()
+	partitions, err := c.assignment(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return c.Committed(partitions, 0)
+	return c.committed(ctx, partitions)
 }
 
-func (c *Consumer) doCommit() error {
+func (c *Consumer) doCommit(ctx context.Context) (err error) {
 	if c.err != nil {
 		return c.err
 	}
-	reqID := c.generateReqID()
+	start := time.Now()
+	span, reqID := c.startSpan(ctx, trace.SpanTMQCommit)
+	defer func() {
+		metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), err)
+		trace.End(span, err)
+	}()
 	req := &CommitReq{
 		ReqID:     reqID,
 		MessageID: 0,
@@ -798,10 +1134,12 @@ func (c *Consumer) doCommit() error {
 	if err != nil {
 		return err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	c.logger.Debug("commit", "req_id", reqID)
+	respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 	if err != nil {
 		return err
 	}
+	log.Slow(c.logger, "commit", reqID, start)
 	var resp CommitResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	return client.HandleResponseError(err, resp.Code, resp.Message)
@@ -829,16 +1167,26 @@ func (c *Consumer) Unsubscribe() error {
 	if err != nil {
 		return err
 	}
+	start := time.Now()
 	respBytes, err := c.sendText(reqID, envelope)
 	if err != nil {
 		return err
 	}
+	log.Slow(c.logger, "unsubscribe", reqID, start)
 	var resp CommitResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
-	return client.HandleResponseError(err, resp.Code, resp.Message)
+	err = client.HandleResponseError(err, resp.Code, resp.Message)
+	if err != nil {
+		return err
+	}
+	return c.revokeAssignment()
 }
 
 func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
+	return c.assignment(context.Background())
+}
+
+func (c *Consumer) assignment(ctx context.Context) (partitions []tmq.TopicPartition, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
@@ -862,10 +1210,12 @@ func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
 		if err != nil {
 			return nil, err
 		}
-		respBytes, err := c.sendText(reqID, envelope)
+		start := time.Now()
+		respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 		if err != nil {
 			return nil, err
 		}
+		log.Slow(c.logger, "assignment", reqID, start)
 		var resp AssignmentResp
 		err = client.JsonI.Unmarshal(respBytes, &resp)
 		err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -886,6 +1236,15 @@ func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
 }
 
 func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) error {
+	return c.seek(context.Background(), partition)
+}
+
+// SeekContext is Seek that returns ctx.Err() when ctx is done before the response.
+func (c *Consumer) SeekContext(ctx context.Context, partition tmq.TopicPartition) error {
+	return c.seek(ctx, partition)
+}
+
+func (c *Consumer) seek(ctx context.Context, partition tmq.TopicPartition) error {
 	if c.err != nil {
 		return c.err
 	}
@@ -910,16 +1269,25 @@ func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) erro
 	if err != nil {
 		return err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	start := time.Now()
+	respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 	if err != nil {
 		return err
 	}
+	log.Slow(c.logger, "seek", reqID, start)
 	var resp OffsetSeekResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	return client.HandleResponseError(err, resp.Code, resp.Message)
 }
 
 func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (offsets []tmq.TopicPartition, err error) {
+	return c.committed(context.Background(), partitions)
+}
+
+func (c *Consumer) committed(ctx context.Context, partitions []tmq.TopicPartition) (offsets []tmq.TopicPartition, err error) {
+	if c.offsetStore != nil && c.offsetStoreMode == tmq.StoreOnly {
+		return c.offsetStore.Load(c.groupID, partitions)
+	}
 	offsets = make([]tmq.TopicPartition, len(partitions))
 	reqID := c.generateReqID()
 	req := &CommittedReq{
@@ -946,10 +1314,12 @@ func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (of
 	if err != nil {
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	start := time.Now()
+	respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
+	log.Slow(c.logger, "committed", reqID, start)
 	var resp CommittedResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -967,13 +1337,38 @@ func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (of
 }
 
 func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicPartition, error) {
+	return c.commitOffsets(context.Background(), offsets)
+}
+
+func (c *Consumer) commitOffsets(ctx context.Context, offsets []tmq.TopicPartition) (committed []tmq.TopicPartition, err error) {
 	if c.err != nil {
 		return nil, c.err
 	}
+	// every vgroup is committed with a request of its own, the req_ids of the requests are recorded on the span
+	start := time.Now()
+	span, _ := c.startSpan(ctx, trace.SpanTMQCommit)
+	reqIDs := make([]int64, 0, len(offsets))
+	defer func() {
+		if len(reqIDs) > 0 {
+			span.SetAttributes(trace.ReqIDs(reqIDs))
+		}
+		metrics.GetMetrics().ObserveRequest(metrics.KindTMQCommit, time.Since(start), err)
+		trace.End(span, err)
+	}()
+	if c.offsetStore != nil {
+		err := c.offsetStore.Store(c.groupID, offsets)
+		if err != nil {
+			return nil, err
+		}
+		if c.offsetStoreMode == tmq.StoreOnly {
+			return c.committed(ctx, offsets)
+		}
+	}
 	envelope := client.GlobalEnvelopePool.Get()
 	defer client.GlobalEnvelopePool.Put(envelope)
 	for i := 0; i < len(offsets); i++ {
 		reqID := c.generateReqID()
+		reqIDs = append(reqIDs, int64(reqID))
 		req := &CommitOffsetReq{
 			ReqID:    reqID,
 			Topic:    *offsets[i].Topic,
@@ -993,10 +1388,12 @@ func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicParti
 		if err != nil {
 			return nil, err
 		}
-		respBytes, err := c.sendText(reqID, envelope)
+		reqStart := time.Now()
+		respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 		if err != nil {
 			return nil, err
 		}
+		log.Slow(c.logger, "commit offset", reqID, reqStart)
 		var resp CommitOffsetResp
 		err = client.JsonI.Unmarshal(respBytes, &resp)
 		err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -1004,10 +1401,14 @@ func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicParti
 			return nil, err
 		}
 	}
-	return c.Committed(offsets, 0)
+	return c.committed(ctx, offsets)
 }
 
 func (c *Consumer) Position(partitions []tmq.TopicPartition) (offsets []tmq.TopicPartition, err error) {
+	return c.position(context.Background(), partitions)
+}
+
+func (c *Consumer) position(ctx context.Context, partitions []tmq.TopicPartition) (offsets []tmq.TopicPartition, err error) {
 	offsets = make([]tmq.TopicPartition, len(partitions))
 	reqID := c.generateReqID()
 	req := &PositionReq{
@@ -1034,10 +1435,12 @@ func (c *Consumer) Position(partitions []tmq.TopicPartition) (offsets []tmq.Topi
 	if err != nil {
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	start := time.Now()
+	respBytes, err := c.sendTextContext(ctx, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
+	log.Slow(c.logger, "position", reqID, start)
 	var resp PositionResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
 	err = client.HandleResponseError(err, resp.Code, resp.Message)
@@ -1,6 +1,7 @@
 package tmq
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -17,9 +19,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/log"
 	"github.com/taosdata/driver-go/v3/common/testtool"
 	"github.com/taosdata/driver-go/v3/common/tmq"
+	"github.com/taosdata/driver-go/v3/common/trace"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/testutil/mockadapter"
 	"github.com/taosdata/driver-go/v3/ws/client"
 )
 
@@ -426,6 +431,353 @@ func TestSeek(t *testing.T) {
 	assert.GreaterOrEqual(t, partitions[0].Offset, messageOffset)
 }
 
+func prepareOffsetStoreEnv() error {
+	steps := []string{
+		"drop topic if exists test_ws_tmq_offset_store_topic",
+		"drop database if exists test_ws_tmq_offset_store",
+		"create database test_ws_tmq_offset_store vgroups 1 WAL_RETENTION_PERIOD 86400",
+		"create topic test_ws_tmq_offset_store_topic as database test_ws_tmq_offset_store",
+		"create table test_ws_tmq_offset_store.t1(ts timestamp,v int)",
+		"insert into test_ws_tmq_offset_store.t1 values (now,1)",
+	}
+	for _, step := range steps {
+		if err := doRequest(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cleanOffsetStoreEnv() error {
+	steps := []string{
+		"drop topic if exists test_ws_tmq_offset_store_topic",
+		"drop database if exists test_ws_tmq_offset_store",
+	}
+	var err error
+	for i := 0; i < 10; i++ {
+		time.Sleep(2 * time.Second)
+		err = doClean(steps)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func TestOffsetStore(t *testing.T) {
+	err := prepareOffsetStoreEnv()
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, cleanOffsetStoreEnv())
+	}()
+	store := tmq.NewMemoryOffsetStore()
+	consume := func(store tmq.OffsetStore) (int, []tmq.TopicPartition) {
+		consumer, err := NewConsumer(&tmq.ConfigMap{
+			"ws.url":             "ws://127.0.0.1:6041",
+			"td.connect.user":    "root",
+			"td.connect.pass":    "taosdata",
+			"group.id":           "test_offset_store",
+			"client.id":          "test_consumer",
+			"auto.offset.reset":  "earliest",
+			"enable.auto.commit": "false",
+		})
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, consumer.Close())
+		}()
+		if store != nil {
+			consumer.SetOffsetStore(store, tmq.StoreOnly)
+		}
+		require.NoError(t, consumer.Subscribe("test_ws_tmq_offset_store_topic", nil))
+		messages := 0
+		for i := 0; i < 5; i++ {
+			event := consumer.Poll(500)
+			if _, ok := event.(*tmq.DataMessage); ok {
+				messages += 1
+			}
+		}
+		committed, err := consumer.Commit()
+		require.NoError(t, err)
+		return messages, committed
+	}
+	messages, committed := consume(store)
+	assert.Equal(t, 1, len(committed))
+	assert.Equal(t, 1, messages)
+	assert.True(t, committed[0].Offset > 0)
+	stored, err := store.Load("test_offset_store", committed)
+	require.NoError(t, err)
+	assert.Equal(t, committed, stored)
+
+	// restored from the store
+	messages, _ = consume(store)
+	assert.Equal(t, 0, messages)
+	// nothing has been committed to the server
+	messages, _ = consume(nil)
+	assert.Equal(t, 1, messages)
+}
+
+func TestRebalanceCallback(t *testing.T) {
+	steps := []string{
+		"drop topic if exists test_ws_tmq_rebalance_topic",
+		"drop database if exists test_ws_tmq_rebalance",
+		"create database test_ws_tmq_rebalance vgroups 2 WAL_RETENTION_PERIOD 86400",
+		"create topic test_ws_tmq_rebalance_topic as database test_ws_tmq_rebalance",
+	}
+	for _, step := range steps {
+		require.NoError(t, doRequest(step))
+	}
+	defer func() {
+		assert.NoError(t, doClean([]string{
+			"drop topic if exists test_ws_tmq_rebalance_topic",
+			"drop database if exists test_ws_tmq_rebalance",
+		}))
+	}()
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":             "ws://127.0.0.1:6041",
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test_rebalance",
+		"client.id":          "test_consumer",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": "false",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	var events []tmq.Event
+	err = consumer.Subscribe("test_ws_tmq_rebalance_topic", func(c *Consumer, event tmq.Event) error {
+		assert.Equal(t, consumer, c)
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	event := consumer.Poll(100)
+	assert.Nil(t, event)
+	require.Equal(t, 1, len(events))
+	assigned, ok := events[0].(tmq.AssignedPartitions)
+	require.True(t, ok)
+	assert.Equal(t, 2, len(assigned.Partitions))
+	// checked at most once per second
+	consumer.Poll(100)
+	assert.Equal(t, 1, len(events))
+
+	require.NoError(t, consumer.Unsubscribe())
+	require.Equal(t, 2, len(events))
+	revoked, ok := events[1].(tmq.RevokedPartitions)
+	require.True(t, ok)
+	assert.Equal(t, assigned.Partitions, revoked.Partitions)
+}
+
+func TestContextAPI(t *testing.T) {
+	steps := []string{
+		"drop topic if exists test_ws_tmq_context_topic",
+		"drop database if exists test_ws_tmq_context",
+		"create database test_ws_tmq_context vgroups 1 WAL_RETENTION_PERIOD 86400",
+		"create topic test_ws_tmq_context_topic as database test_ws_tmq_context",
+		"create table test_ws_tmq_context.t1(ts timestamp,v int)",
+		"insert into test_ws_tmq_context.t1 values (now,1)",
+	}
+	for _, step := range steps {
+		require.NoError(t, doRequest(step))
+	}
+	defer func() {
+		assert.NoError(t, doClean([]string{
+			"drop topic if exists test_ws_tmq_context_topic",
+			"drop database if exists test_ws_tmq_context",
+		}))
+	}()
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":             "ws://127.0.0.1:6041",
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test_context",
+		"client.id":          "test_consumer",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": "false",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	require.NoError(t, consumer.Subscribe("test_ws_tmq_context_topic", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	event := consumer.PollContext(ctx)
+	cancel()
+	message, ok := event.(*tmq.DataMessage)
+	require.True(t, ok, "unexpected event %v", event)
+	committed, err := consumer.CommitContext(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(committed))
+	assert.True(t, committed[0].Offset > message.Offset())
+
+	start := time.Now()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*300)
+	event = consumer.PollContext(ctx)
+	cancel()
+	assert.Nil(t, event)
+	assert.True(t, time.Since(start) < time.Second)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = consumer.CommitContext(canceled)
+	assert.Equal(t, context.Canceled, err)
+	err = consumer.SeekContext(canceled, tmq.TopicPartition{Topic: message.TopicPartition.Topic, Partition: message.TopicPartition.Partition})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, consumer.PollContext(canceled))
+}
+
+func TestPollContextDeadline(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":          s.WSURL(),
+		"td.connect.user": "root",
+		"td.connect.pass": "taosdata",
+		"group.id":        "test_deadline",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	require.NoError(t, consumer.Subscribe("test_deadline_topic", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*250)
+	defer cancel()
+	assert.Nil(t, consumer.PollContext(ctx))
+	polls := s.Requests(mockadapter.ActionTMQPoll)
+	require.NotEmpty(t, polls)
+	for _, poll := range polls {
+		var args struct {
+			BlockingTime int64 `json:"blocking_time"`
+		}
+		require.NoError(t, jsoniter.Unmarshal(poll.Args, &args))
+		assert.True(t, args.BlockingTime > 0, "poll of %d ms", args.BlockingTime)
+	}
+}
+
+type slowLogger struct {
+	lock    sync.Mutex
+	actions []string
+}
+
+func (l *slowLogger) Debug(_ string, _ ...interface{}) {}
+func (l *slowLogger) Info(_ string, _ ...interface{})  {}
+func (l *slowLogger) Error(_ string, _ ...interface{}) {}
+
+func (l *slowLogger) Warn(msg string, keyvals ...interface{}) {
+	if msg != "slow request" {
+		return
+	}
+	l.lock.Lock()
+	l.actions = append(l.actions, keyvals[1].(string))
+	l.lock.Unlock()
+}
+
+func (l *slowLogger) getActions() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string(nil), l.actions...)
+}
+
+func TestSlowRequest(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	logger := &slowLogger{}
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":          s.WSURL(),
+		"td.connect.user": "root",
+		"td.connect.pass": "taosdata",
+		"group.id":        "test_slow",
+		tmq.LoggerKey:     logger,
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	log.SetSlowThreshold(time.Nanosecond)
+	defer log.SetSlowThreshold(0)
+	require.NoError(t, consumer.Subscribe("test_slow_topic", nil))
+	_, err = consumer.Commit()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subscribe", "commit", "assignment", "committed"}, logger.getActions())
+
+	// the blocking time of a poll is not slow
+	log.SetSlowThreshold(time.Millisecond * 50)
+	assert.Nil(t, consumer.Poll(200))
+	assert.Equal(t, []string{"subscribe", "commit", "assignment", "committed"}, logger.getActions())
+}
+
+type recordSpan struct {
+	name  string
+	attrs map[string]interface{}
+}
+
+func (s *recordSpan) SetAttributes(attrs ...trace.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) End(error) {}
+
+type recordTracer struct {
+	lock  sync.Mutex
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (t *recordTracer) SpanID(context.Context) ([8]byte, bool) {
+	return [8]byte{}, false
+}
+
+func TestCommitOffsetsReqID(t *testing.T) {
+	s := mockadapter.New()
+	defer s.Close()
+	topic := "test_commit_offsets_topic"
+	s.Produce(&mockadapter.Message{Topic: topic, VgroupID: 2}, &mockadapter.Message{Topic: topic, VgroupID: 3})
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":          s.WSURL(),
+		"td.connect.user": "root",
+		"td.connect.pass": "taosdata",
+		"group.id":        "test_commit_offsets",
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, consumer.Close())
+	}()
+	require.NoError(t, consumer.Subscribe(topic, nil))
+	tracer := &recordTracer{}
+	trace.SetTracer(tracer, nil)
+	defer trace.SetTracer(nil, nil)
+	_, err = consumer.CommitOffsets([]tmq.TopicPartition{
+		{Topic: &topic, Partition: 2, Offset: 1},
+		{Topic: &topic, Partition: 3, Offset: 1},
+	})
+	require.NoError(t, err)
+	requests := s.Requests(mockadapter.ActionTMQCommitOffset)
+	require.Equal(t, 2, len(requests))
+	assert.NotEqual(t, requests[0].ReqID, requests[1].ReqID)
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	var commits []*recordSpan
+	for _, span := range tracer.spans {
+		if span.name == trace.SpanTMQCommit {
+			commits = append(commits, span)
+		}
+	}
+	require.Equal(t, 1, len(commits))
+	assert.Equal(t, []int64{int64(requests[0].ReqID), int64(requests[1].ReqID)}, commits[0].attrs[trace.AttrReqIDs])
+}
+
 func prepareAutocommitEnv() error {
 	var err error
 	steps := []string{
@@ -1472,3 +1824,18 @@ func TestTimezone(t *testing.T) {
 	}
 	t.Error("no message got")
 }
+
+func TestParseRawData(t *testing.T) {
+	resp := make([]byte, rawDataOffset+3)
+	resp[32] = 3
+	resp[36] = 2
+	copy(resp[rawDataOffset:], []byte{1, 2, 3})
+	raw, err := parseRawData(resp)
+	require.NoError(t, err)
+	assert.Equal(t, &tmq.RawData{MetaType: 2, Data: []byte{1, 2, 3}}, raw)
+
+	_, err = parseRawData(resp[:rawDataOffset+2])
+	assert.Error(t, err)
+	_, err = parseRawData(resp[:10])
+	assert.Error(t, err)
+}